@@ -0,0 +1,259 @@
+package commonservice
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	bindTagName     = "mapstructure"
+	bindTagDefault  = "default"
+	bindTagValidate = "validate"
+)
+
+var (
+	durationType = reflect.TypeOf(time.Duration(0))
+	timeType     = reflect.TypeOf(time.Time{})
+)
+
+// Bind creates a structure of type T filled with the configuration values found under the given prefix.
+// Each exported field is mapped to a key using its mapstructure tag (lower case field name when missing, "-" to ignore the field).
+// As keys can be configured with dot (see database.ConfigureDbDefaultForKey) or dash (see database.ConfigureDbDefault) separators,
+// prefix.key is looked up first, then prefix-key. Nested structures are bound with their own key as prefix unless tagged with ",squash".
+// When a key is not set, the value of the default tag is used.
+// The validate tag declares constraints: required, min=x and max=x (value of numbers and durations, length of strings and slices).
+// All the failing fields are reported in the returned error
+func Bind[T any](c Configuration, prefix string) (T, error) {
+	var res T
+	var value = reflect.ValueOf(&res).Elem()
+	if value.Kind() != reflect.Struct {
+		return res, fmt.Errorf("can't bind configuration to type %s: only structures are supported", value.Type())
+	}
+
+	var prefixes []string
+	if prefix != "" {
+		prefixes = []string{prefix}
+	}
+	var errs []error
+	bindStruct(c, prefixes, value, &errs)
+	return res, errors.Join(errs...)
+}
+
+func bindStruct(c Configuration, prefixes []string, value reflect.Value, errs *[]error) {
+	var valueType = value.Type()
+	for i := 0; i < valueType.NumField(); i++ {
+		var field = valueType.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		var name, options, _ = strings.Cut(field.Tag.Get(bindTagName), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+
+		if field.Type.Kind() == reflect.Struct && field.Type != timeType {
+			if options == "squash" {
+				bindStruct(c, prefixes, value.Field(i), errs)
+			} else {
+				bindStruct(c, bindKeys(prefixes, name), value.Field(i), errs)
+			}
+			continue
+		}
+		if err := bindField(c, bindKeys(prefixes, name), field, value.Field(i)); err != nil {
+			*errs = append(*errs, err)
+		}
+	}
+}
+
+// bindKeys returns the candidate keys of a value for every candidate prefix
+func bindKeys(prefixes []string, name string) []string {
+	if len(prefixes) == 0 {
+		return []string{name}
+	}
+	var res []string
+	for _, separator := range []string{".", "-"} {
+		for _, prefix := range prefixes {
+			res = append(res, prefix+separator+name)
+		}
+	}
+	return res
+}
+
+func bindField(c Configuration, keys []string, field reflect.StructField, value reflect.Value) error {
+	var displayKey = strings.Join(keys, " or ")
+	var constraints = parseBindConstraints(field.Tag.Get(bindTagValidate))
+
+	var key, found = findBindKey(c, keys)
+	var err error
+	if found {
+		err = setFromConfiguration(c, key, value)
+	} else if defaultValue, ok := field.Tag.Lookup(bindTagDefault); ok {
+		err = setFromString(defaultValue, value)
+	} else if _, required := constraints["required"]; required {
+		return fmt.Errorf("missing required configuration key %s", displayKey)
+	} else {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("invalid configuration key %s: %w", displayKey, err)
+	}
+	if err = checkBindConstraints(constraints, value); err != nil {
+		return fmt.Errorf("invalid configuration key %s: %w", displayKey, err)
+	}
+	return nil
+}
+
+func findBindKey(c Configuration, keys []string) (string, bool) {
+	for _, key := range keys {
+		if c.Get(key) != nil {
+			return key, true
+		}
+	}
+	return "", false
+}
+
+func parseBindConstraints(tag string) map[string]string {
+	var res = map[string]string{}
+	for constraint := range strings.SplitSeq(tag, ",") {
+		if constraint = strings.TrimSpace(constraint); constraint != "" {
+			var name, param, _ = strings.Cut(constraint, "=")
+			res[name] = param
+		}
+	}
+	return res
+}
+
+func setFromConfiguration(c Configuration, key string, value reflect.Value) error {
+	switch {
+	case value.Type() == durationType:
+		value.SetInt(int64(c.GetDuration(key)))
+	case value.Type() == timeType:
+		value.Set(reflect.ValueOf(c.GetTime(key)))
+	case value.Kind() == reflect.String:
+		value.SetString(c.GetString(key))
+	case value.Kind() == reflect.Bool:
+		value.SetBool(c.GetBool(key))
+	case value.CanInt():
+		var i = c.GetInt64(key)
+		if value.OverflowInt(i) {
+			return fmt.Errorf("value %d overflows %s", i, value.Type())
+		}
+		value.SetInt(i)
+	case value.CanUint():
+		var i = c.GetInt64(key)
+		if i < 0 || value.OverflowUint(uint64(i)) {
+			return fmt.Errorf("value %d overflows %s", i, value.Type())
+		}
+		value.SetUint(uint64(i))
+	case value.CanFloat():
+		value.SetFloat(c.GetFloat64(key))
+	case value.Kind() == reflect.Slice && value.Type().Elem().Kind() == reflect.String:
+		value.Set(reflect.ValueOf(c.GetStringSlice(key)).Convert(value.Type()))
+	default:
+		return fmt.Errorf("unsupported type %s", value.Type())
+	}
+	return nil
+}
+
+func setFromString(input string, value reflect.Value) error {
+	switch {
+	case value.Type() == durationType:
+		var d, err = time.ParseDuration(input)
+		if err != nil {
+			return err
+		}
+		value.SetInt(int64(d))
+	case value.Type() == timeType:
+		var t, err = time.Parse(time.RFC3339, input)
+		if err != nil {
+			return err
+		}
+		value.Set(reflect.ValueOf(t))
+	case value.Kind() == reflect.String:
+		value.SetString(input)
+	case value.Kind() == reflect.Bool:
+		var b, err = strconv.ParseBool(input)
+		if err != nil {
+			return err
+		}
+		value.SetBool(b)
+	case value.CanInt():
+		var i, err = strconv.ParseInt(input, 10, value.Type().Bits())
+		if err != nil {
+			return err
+		}
+		value.SetInt(i)
+	case value.CanUint():
+		var i, err = strconv.ParseUint(input, 10, value.Type().Bits())
+		if err != nil {
+			return err
+		}
+		value.SetUint(i)
+	case value.CanFloat():
+		var f, err = strconv.ParseFloat(input, value.Type().Bits())
+		if err != nil {
+			return err
+		}
+		value.SetFloat(f)
+	case value.Kind() == reflect.Slice && value.Type().Elem().Kind() == reflect.String:
+		var values = []string{}
+		for item := range strings.SplitSeq(input, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				values = append(values, item)
+			}
+		}
+		value.Set(reflect.ValueOf(values).Convert(value.Type()))
+	default:
+		return fmt.Errorf("unsupported type %s", value.Type())
+	}
+	return nil
+}
+
+func checkBindConstraints(constraints map[string]string, value reflect.Value) error {
+	for _, name := range []string{"min", "max"} {
+		var param, ok = constraints[name]
+		if !ok {
+			continue
+		}
+		var actual, limit, err = bindMeasures(value, param)
+		if err != nil {
+			return fmt.Errorf("invalid %s constraint: %w", name, err)
+		}
+		if name == "min" && actual < limit {
+			return fmt.Errorf("value should be at least %s", param)
+		}
+		if name == "max" && actual > limit {
+			return fmt.Errorf("value should be at most %s", param)
+		}
+	}
+	return nil
+}
+
+// bindMeasures returns the measure of a value (number, duration or length) and the parsed limit it should be compared to
+func bindMeasures(value reflect.Value, param string) (float64, float64, error) {
+	switch {
+	case value.Type() == durationType:
+		var limit, err = time.ParseDuration(param)
+		return float64(value.Int()), float64(limit), err
+	case value.Kind() == reflect.String || value.Kind() == reflect.Slice:
+		var limit, err = strconv.Atoi(param)
+		return float64(value.Len()), float64(limit), err
+	case value.CanInt():
+		var limit, err = strconv.ParseFloat(param, 64)
+		return float64(value.Int()), limit, err
+	case value.CanUint():
+		var limit, err = strconv.ParseFloat(param, 64)
+		return float64(value.Uint()), limit, err
+	case value.CanFloat():
+		var limit, err = strconv.ParseFloat(param, 64)
+		return value.Float(), limit, err
+	}
+	return 0, 0, fmt.Errorf("not supported by type %s", value.Type())
+}
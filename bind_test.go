@@ -0,0 +1,129 @@
+package commonservice
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// mapConfiguration is a minimal Configuration where typed getters expect values of the right type
+type mapConfiguration map[string]any
+
+func (c mapConfiguration) SetDefault(key string, value any) { c[key] = value }
+func (c mapConfiguration) BindEnv(input ...string) error    { return nil }
+func (c mapConfiguration) Set(key string, value any)        { c[key] = value }
+func (c mapConfiguration) Get(key string) any               { return c[key] }
+func (c mapConfiguration) GetString(key string) string      { v, _ := c[key].(string); return v }
+func (c mapConfiguration) GetStringSlice(key string) []string {
+	v, _ := c[key].([]string)
+	return v
+}
+func (c mapConfiguration) GetBool(key string) bool       { v, _ := c[key].(bool); return v }
+func (c mapConfiguration) GetInt(key string) int         { v, _ := c[key].(int); return v }
+func (c mapConfiguration) GetInt32(key string) int32     { return int32(c.GetInt(key)) }
+func (c mapConfiguration) GetInt64(key string) int64     { return int64(c.GetInt(key)) }
+func (c mapConfiguration) GetFloat64(key string) float64 { v, _ := c[key].(float64); return v }
+func (c mapConfiguration) GetTime(key string) time.Time  { v, _ := c[key].(time.Time); return v }
+func (c mapConfiguration) GetDuration(key string) time.Duration {
+	v, _ := c[key].(time.Duration)
+	return v
+}
+
+type bindTLS struct {
+	Enabled bool   `mapstructure:"enabled" default:"true"`
+	CAFile  string `mapstructure:"ca-file"`
+}
+
+type bindCommon struct {
+	Debug bool `mapstructure:"debug"`
+}
+
+type bindTarget struct {
+	HostPort  string        `mapstructure:"host-port" validate:"required"`
+	Username  string        `mapstructure:"username" validate:"max=8"`
+	MaxConns  int           `mapstructure:"max-open-conns" default:"10" validate:"min=1,max=100"`
+	Ratio     float64       `mapstructure:"ratio" default:"0.5"`
+	Retries   uint8         `mapstructure:"retries"`
+	Timeout   time.Duration `mapstructure:"timeout" default:"2s" validate:"max=1m"`
+	Origins   []string      `mapstructure:"allowed-origins" default:"a, b"`
+	NotBefore time.Time     `mapstructure:"not-before"`
+	TLS       bindTLS       `mapstructure:"tls"`
+	Common    bindCommon    `mapstructure:",squash"`
+	Name      string
+	Ignored   string `mapstructure:"-" default:"ignored"`
+	internal  string
+}
+
+func TestBind(t *testing.T) {
+	var notBefore = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("Not a structure", func(t *testing.T) {
+		var _, err = Bind[string](mapConfiguration{}, "db")
+		assert.NotNil(t, err)
+	})
+	t.Run("Dash separator and defaults", func(t *testing.T) {
+		var conf = mapConfiguration{"db-host-port": "localhost:3306", "db-tls-ca-file": "ca.pem", "db-debug": true, "db-name": "name"}
+		var res, err = Bind[bindTarget](conf, "db")
+		assert.Nil(t, err)
+		assert.Equal(t, bindTarget{
+			HostPort: "localhost:3306",
+			MaxConns: 10,
+			Ratio:    0.5,
+			Timeout:  2 * time.Second,
+			Origins:  []string{"a", "b"},
+			TLS:      bindTLS{Enabled: true, CAFile: "ca.pem"},
+			Common:   bindCommon{Debug: true},
+			Name:     "name",
+		}, res)
+	})
+	t.Run("Dot separator", func(t *testing.T) {
+		var conf = mapConfiguration{
+			"db.host-port":       "localhost:3306",
+			"db.max-open-conns":  20,
+			"db.retries":         3,
+			"db.timeout":         time.Second,
+			"db.allowed-origins": []string{"c"},
+			"db.not-before":      notBefore,
+			"db.tls.enabled":     false,
+		}
+		var res, err = Bind[bindTarget](conf, "db")
+		assert.Nil(t, err)
+		assert.Equal(t, 20, res.MaxConns)
+		assert.Equal(t, uint8(3), res.Retries)
+		assert.Equal(t, time.Second, res.Timeout)
+		assert.Equal(t, []string{"c"}, res.Origins)
+		assert.Equal(t, notBefore, res.NotBefore)
+		assert.False(t, res.TLS.Enabled)
+	})
+	t.Run("No prefix", func(t *testing.T) {
+		var res, err = Bind[bindTarget](mapConfiguration{"host-port": "localhost:3306"}, "")
+		assert.Nil(t, err)
+		assert.Equal(t, "localhost:3306", res.HostPort)
+	})
+	t.Run("Validation errors are aggregated", func(t *testing.T) {
+		var conf = mapConfiguration{"db-username": "very-long-username", "db-max-open-conns": 0, "db-retries": 300, "db-timeout": time.Hour}
+		var _, err = Bind[bindTarget](conf, "db")
+		assert.NotNil(t, err)
+		var msg = err.Error()
+		assert.Contains(t, msg, "missing required configuration key db.host-port or db-host-port")
+		assert.Contains(t, msg, "db.username or db-username: value should be at most 8")
+		assert.Contains(t, msg, "db.max-open-conns or db-max-open-conns: value should be at least 1")
+		assert.Contains(t, msg, "db.retries or db-retries: value 300 overflows uint8")
+		assert.Contains(t, msg, "db.timeout or db-timeout: value should be at most 1m")
+	})
+	t.Run("Invalid default value", func(t *testing.T) {
+		type invalidDefault struct {
+			Value int `mapstructure:"value" default:"abc"`
+		}
+		var _, err = Bind[invalidDefault](mapConfiguration{}, "")
+		assert.NotNil(t, err)
+	})
+	t.Run("Unsupported type", func(t *testing.T) {
+		type unsupported struct {
+			Value map[string]string `mapstructure:"value"`
+		}
+		var _, err = Bind[unsupported](mapConfiguration{"value": map[string]string{}}, "")
+		assert.NotNil(t, err)
+	})
+}
@@ -0,0 +1,343 @@
+package commonservice
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Supported configuration file formats
+const (
+	ConfigurationFormatYAML = "yaml"
+	ConfigurationFormatJSON = "json"
+)
+
+// BasicConfiguration is a Configuration implementation without external dependency.
+// Values are read, by order of precedence, from Set, from environment variables declared with BindEnv, from the loaded files
+// and finally from SetDefault. Keys are case insensitive and nested file values are flattened using a dot separator
+type BasicConfiguration struct {
+	mutex     sync.RWMutex
+	overrides map[string]any
+	envs      map[string][]string
+	values    map[string]any
+	defaults  map[string]any
+	lookupEnv func(string) (string, bool)
+}
+
+// NewBasicConfiguration creates an empty configuration
+func NewBasicConfiguration() *BasicConfiguration {
+	return &BasicConfiguration{
+		overrides: map[string]any{},
+		envs:      map[string][]string{},
+		values:    map[string]any{},
+		defaults:  map[string]any{},
+		lookupEnv: os.LookupEnv,
+	}
+}
+
+// ReadFile loads a YAML or JSON file. The format is chosen according to the file extension.
+// Values of the file are merged with the ones of the previously loaded files
+func (c *BasicConfiguration) ReadFile(path string) error {
+	var format = ConfigurationFormatYAML
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		format = ConfigurationFormatJSON
+	}
+	var content, err = os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return c.ReadConfig(bytes.NewReader(content), format)
+}
+
+// ReadConfig loads values from a reader using the given format
+func (c *BasicConfiguration) ReadConfig(reader io.Reader, format string) error {
+	var content = map[string]any{}
+	var err error
+	switch format {
+	case ConfigurationFormatYAML:
+		err = yaml.NewDecoder(reader).Decode(&content)
+		if errors.Is(err, io.EOF) {
+			err = nil
+		}
+	case ConfigurationFormatJSON:
+		err = json.NewDecoder(reader).Decode(&content)
+	default:
+		err = fmt.Errorf("unsupported configuration format %s", format)
+	}
+	if err != nil {
+		return err
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	flattenConfiguration("", content, c.values)
+	return nil
+}
+
+func flattenConfiguration(prefix string, content map[string]any, dest map[string]any) {
+	for key, value := range content {
+		key = strings.ToLower(prefix + key)
+		if nested, ok := value.(map[string]any); ok {
+			flattenConfiguration(key+".", nested, dest)
+		} else {
+			dest[key] = value
+		}
+	}
+}
+
+// SetDefault sets the value used when a key is not provided by any other source
+func (c *BasicConfiguration) SetDefault(key string, value any) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.defaults[strings.ToLower(key)] = value
+}
+
+// BindEnv binds a key to environment variables. The first input is the key, the following ones are the names of the
+// environment variables. When no name is provided, the upper cased key is used
+func (c *BasicConfiguration) BindEnv(input ...string) error {
+	if len(input) == 0 {
+		return errors.New("missing key to bind to")
+	}
+	var key = strings.ToLower(input[0])
+	var names = input[1:]
+	if len(names) == 0 {
+		names = []string{strings.ToUpper(key)}
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.envs[key] = names
+	return nil
+}
+
+// Set sets a value which overrides any other source
+func (c *BasicConfiguration) Set(key string, value any) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.overrides[strings.ToLower(key)] = value
+}
+
+// Get returns the value of a key or nil when the key is not set
+func (c *BasicConfiguration) Get(key string) any {
+	key = strings.ToLower(key)
+
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	if value, ok := c.overrides[key]; ok {
+		return value
+	}
+	for _, name := range c.envs[key] {
+		if value, ok := c.lookupEnv(name); ok {
+			return value
+		}
+	}
+	if value, ok := c.values[key]; ok {
+		return value
+	}
+	return c.defaults[key]
+}
+
+// UnknownKeys returns the sorted keys of the loaded files which are neither declared with SetDefault, BindEnv nor Set.
+// It helps detecting typos in configuration files
+func (c *BasicConfiguration) UnknownKeys() []string {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	var res = []string{}
+	for key := range c.values {
+		if _, ok := c.defaults[key]; ok {
+			continue
+		}
+		if _, ok := c.envs[key]; ok {
+			continue
+		}
+		if _, ok := c.overrides[key]; ok {
+			continue
+		}
+		res = append(res, key)
+	}
+	sort.Strings(res)
+	return res
+}
+
+// GetString returns the value of a key as a string
+func (c *BasicConfiguration) GetString(key string) string {
+	return configurationString(c.Get(key))
+}
+
+// GetStringSlice returns the value of a key as a slice of strings. A string value is split on white spaces
+func (c *BasicConfiguration) GetStringSlice(key string) []string {
+	return configurationStringSlice(c.Get(key))
+}
+
+// GetBool returns the value of a key as a boolean
+func (c *BasicConfiguration) GetBool(key string) bool {
+	return configurationBool(c.Get(key))
+}
+
+// GetInt returns the value of a key as an int
+func (c *BasicConfiguration) GetInt(key string) int {
+	return int(c.GetInt64(key))
+}
+
+// GetInt32 returns the value of a key as an int32
+func (c *BasicConfiguration) GetInt32(key string) int32 {
+	return int32(c.GetInt64(key))
+}
+
+// GetInt64 returns the value of a key as an int64
+func (c *BasicConfiguration) GetInt64(key string) int64 {
+	return configurationInt64(c.Get(key))
+}
+
+// GetFloat64 returns the value of a key as a float64
+func (c *BasicConfiguration) GetFloat64(key string) float64 {
+	return configurationFloat64(c.Get(key))
+}
+
+// GetTime returns the value of a key as a time. Strings are parsed using RFC3339 or the 2006-01-02 layout
+func (c *BasicConfiguration) GetTime(key string) time.Time {
+	return configurationTime(c.Get(key))
+}
+
+// GetDuration returns the value of a key as a duration. Strings are parsed with time.ParseDuration, numbers are nanoseconds
+func (c *BasicConfiguration) GetDuration(key string) time.Duration {
+	return configurationDuration(c.Get(key))
+}
+
+// configurationString converts a configuration value to a string
+func configurationString(value any) string {
+	switch value := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return value
+	case time.Time:
+		return value.Format(time.RFC3339)
+	default:
+		return fmt.Sprint(value)
+	}
+}
+
+// configurationStringSlice converts a configuration value to a slice of strings. A string value is split on white spaces
+func configurationStringSlice(value any) []string {
+	switch value := value.(type) {
+	case []string:
+		return value
+	case []any:
+		var res = make([]string, 0, len(value))
+		for _, item := range value {
+			res = append(res, fmt.Sprint(item))
+		}
+		return res
+	case string:
+		return strings.Fields(value)
+	}
+	return []string{}
+}
+
+// configurationBool converts a configuration value to a boolean
+func configurationBool(value any) bool {
+	switch value := value.(type) {
+	case bool:
+		return value
+	case string:
+		var res, _ = strconv.ParseBool(value)
+		return res
+	case int:
+		return value != 0
+	}
+	return false
+}
+
+// configurationInt64 converts a configuration value to an int64
+func configurationInt64(value any) int64 {
+	switch value := value.(type) {
+	case int:
+		return int64(value)
+	case int32:
+		return int64(value)
+	case int64:
+		return value
+	case uint64:
+		return int64(value)
+	case float64:
+		return int64(value)
+	case bool:
+		if value {
+			return 1
+		}
+	case string:
+		var res, err = strconv.ParseInt(strings.TrimSpace(value), 0, 64)
+		if err != nil {
+			var f, _ = strconv.ParseFloat(strings.TrimSpace(value), 64)
+			return int64(f)
+		}
+		return res
+	case time.Duration:
+		return int64(value)
+	}
+	return 0
+}
+
+// configurationFloat64 converts a configuration value to a float64
+func configurationFloat64(input any) float64 {
+	switch value := input.(type) {
+	case float64:
+		return value
+	case float32:
+		return float64(value)
+	case string:
+		var res, _ = strconv.ParseFloat(strings.TrimSpace(value), 64)
+		return res
+	case nil:
+		return 0
+	}
+	return float64(configurationInt64(input))
+}
+
+// configurationTime converts a configuration value to a time. Strings are parsed using RFC3339 or the 2006-01-02 layout
+func configurationTime(value any) time.Time {
+	switch value := value.(type) {
+	case time.Time:
+		return value
+	case string:
+		for _, layout := range []string{time.RFC3339Nano, "2006-01-02"} {
+			if res, err := time.Parse(layout, strings.TrimSpace(value)); err == nil {
+				return res
+			}
+		}
+	case int:
+		return time.Unix(int64(value), 0)
+	case int64:
+		return time.Unix(value, 0)
+	}
+	return time.Time{}
+}
+
+// configurationDuration converts a configuration value to a duration. Strings are parsed with time.ParseDuration, numbers are nanoseconds
+func configurationDuration(input any) time.Duration {
+	switch value := input.(type) {
+	case time.Duration:
+		return value
+	case string:
+		value = strings.TrimSpace(value)
+		if res, err := time.ParseDuration(value); err == nil {
+			return res
+		}
+		var res, _ = strconv.ParseInt(value, 10, 64)
+		return time.Duration(res)
+	}
+	return time.Duration(configurationInt64(input))
+}
@@ -0,0 +1,235 @@
+package configuration
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	cerrors "github.com/cloudtrust/common-service/v2/errors"
+	"github.com/cloudtrust/common-service/v2/log"
+	"gopkg.in/yaml.v3"
+)
+
+// AuthorizationsReader is a source of authorizations. It is implemented by ConfigurationReaderDBModule, AuthorizationFileReader
+// and CompositeAuthorizationReader
+type AuthorizationsReader interface {
+	GetAuthorizations(ctx context.Context) ([]Authorization, error)
+}
+
+// AuthorizationsFile is the content of an authorizations file
+type AuthorizationsFile struct {
+	Authorizations []Authorization `json:"authorizations"`
+}
+
+// UnmarshalAuthorizations deserializes an authorizations file from the given format (BundleFormatJSON or BundleFormatYAML)
+// and checks its entries. YAML documents use the same field names than JSON ones
+func UnmarshalAuthorizations(data []byte, format string) ([]Authorization, error) {
+	switch format {
+	case BundleFormatJSON:
+	case BundleFormatYAML:
+		var generic any
+		if err := yaml.Unmarshal(data, &generic); err != nil {
+			return nil, err
+		}
+		var err error
+		if data, err = json.Marshal(generic); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, cerrors.CreateBadRequestError(cerrors.MsgErrInvalidParam + ".format")
+	}
+	var file AuthorizationsFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, err
+	}
+	for _, authz := range file.Authorizations {
+		if authz.RealmID == nil {
+			return nil, cerrors.CreateMissingParameterError("authorizations.realm_id")
+		}
+		if authz.GroupName == nil {
+			return nil, cerrors.CreateMissingParameterError("authorizations.group_id")
+		}
+		if authz.Action == nil {
+			return nil, cerrors.CreateMissingParameterError("authorizations.action")
+		}
+		if authz.TargetRealmID == nil && authz.TargetGroupName != nil {
+			return nil, cerrors.CreateMissingParameterError("authorizations.target_realm_id")
+		}
+		if authz.Conditions != nil {
+			if err := authz.Conditions.Validate(); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return file.Authorizations, nil
+}
+
+// AuthorizationFileReader reads authorizations from a YAML or JSON file. The file is read on each call so that reloading the
+// authorizations takes its changes into account
+type AuthorizationFileReader struct {
+	path      string
+	authScope map[string]bool
+	logger    log.Logger
+}
+
+// NewAuthorizationFileReader returns an AuthorizationFileReader. The format is deduced from the extension of the file: .json
+// for JSON, YAML otherwise. As for NewConfigurationReaderDBModule, actions can restrict the loaded authorizations
+func NewAuthorizationFileReader(path string, logger log.Logger, actions ...[]string) *AuthorizationFileReader {
+	var authScope map[string]bool
+	if len(actions) > 0 {
+		authScope = make(map[string]bool)
+		for _, actionSet := range actions {
+			for _, filter := range actionSet {
+				authScope[filter] = true
+			}
+		}
+	}
+	return &AuthorizationFileReader{
+		path:      path,
+		authScope: authScope,
+		logger:    logger,
+	}
+}
+
+// GetAuthorizations returns the authorizations of the file
+func (r *AuthorizationFileReader) GetAuthorizations(ctx context.Context) ([]Authorization, error) {
+	var data, err = os.ReadFile(r.path)
+	if err != nil {
+		r.logger.Warn(ctx, "msg", "Can't read authorizations file", "path", r.path, "err", err.Error())
+		return nil, err
+	}
+	var format = BundleFormatYAML
+	if strings.EqualFold(filepath.Ext(r.path), ".json") {
+		format = BundleFormatJSON
+	}
+	authorizations, err := UnmarshalAuthorizations(data, format)
+	if err != nil {
+		r.logger.Warn(ctx, "msg", "Invalid authorizations file", "path", r.path, "err", err.Error())
+		return nil, err
+	}
+
+	var res = make([]Authorization, 0, len(authorizations))
+	for _, authz := range authorizations {
+		if r.authScope == nil || r.authScope[*authz.Action] {
+			res = append(res, authz)
+		}
+	}
+	return res, nil
+}
+
+// AuthorizationSource is a named source of authorizations used by a CompositeAuthorizationReader
+type AuthorizationSource struct {
+	Name   string
+	Reader AuthorizationsReader
+}
+
+// AuthorizationConflict is an authorization both allowed and denied by the sources of a CompositeAuthorizationReader
+type AuthorizationConflict struct {
+	Authorization Authorization `json:"authorization"`
+	AllowedBy     []string      `json:"allowed_by"`
+	DeniedBy      []string      `json:"denied_by"`
+}
+
+// CompositeAuthorizationReader merges the authorizations of several sources, for instance baseline authorizations shipped
+// with the service in a file and the authorizations of the database
+type CompositeAuthorizationReader struct {
+	sources   []AuthorizationSource
+	logger    log.Logger
+	mutex     sync.Mutex
+	conflicts []AuthorizationConflict
+}
+
+// NewCompositeAuthorizationReader returns a CompositeAuthorizationReader reading the given sources in order
+func NewCompositeAuthorizationReader(logger log.Logger, sources ...AuthorizationSource) *CompositeAuthorizationReader {
+	return &CompositeAuthorizationReader{
+		sources: sources,
+		logger:  logger,
+	}
+}
+
+// GetAuthorizations returns the authorizations of all the sources. Entries provided by several sources are returned once.
+// An entry both allowed and denied is reported as a conflict and both entries are returned: the deny entry takes precedence.
+// If a source fails, an error is returned so that the last loaded authorizations are kept by the caller
+func (c *CompositeAuthorizationReader) GetAuthorizations(ctx context.Context) ([]Authorization, error) {
+	var res = make([]Authorization, 0)
+	var sourcesByKey = map[string][]string{}
+	var conflictKeys = map[string]bool{}
+	for _, source := range c.sources {
+		authorizations, err := source.Reader.GetAuthorizations(ctx)
+		if err != nil {
+			c.logger.Warn(ctx, "msg", "Can't get authorizations from source", "source", source.Name, "err", err.Error())
+			return nil, fmt.Errorf("authorizations source %s: %w", source.Name, err)
+		}
+		for _, authz := range authorizations {
+			var key = authorizationKey(authz)
+			if _, ok := sourcesByKey[key]; !ok {
+				res = append(res, authz)
+				var opposite = authz
+				opposite.Deny = !authz.Deny
+				if _, conflict := sourcesByKey[authorizationKey(opposite)]; conflict {
+					conflictKeys[authorizationKey(allowEntry(authz))] = true
+				}
+			}
+			sourcesByKey[key] = appendSource(sourcesByKey[key], source.Name)
+		}
+	}
+
+	var conflicts = make([]AuthorizationConflict, 0, len(conflictKeys))
+	for _, authz := range res {
+		if authz.Deny || !conflictKeys[authorizationKey(authz)] {
+			continue
+		}
+		var denied = authz
+		denied.Deny = true
+		var conflict = AuthorizationConflict{
+			Authorization: authz,
+			AllowedBy:     sourcesByKey[authorizationKey(authz)],
+			DeniedBy:      sourcesByKey[authorizationKey(denied)],
+		}
+		c.logger.Warn(ctx, "msg", "Authorization both allowed and denied", "authorization", authorizationKeyForLog(authz),
+			"allowedBy", strings.Join(conflict.AllowedBy, ","), "deniedBy", strings.Join(conflict.DeniedBy, ","))
+		conflicts = append(conflicts, conflict)
+	}
+
+	c.mutex.Lock()
+	c.conflicts = conflicts
+	c.mutex.Unlock()
+
+	return res, nil
+}
+
+// Conflicts returns the conflicts detected by the last successful call to GetAuthorizations
+func (c *CompositeAuthorizationReader) Conflicts() []AuthorizationConflict {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.conflicts
+}
+
+func allowEntry(authz Authorization) Authorization {
+	authz.Deny = false
+	return authz
+}
+
+func appendSource(sources []string, source string) []string {
+	if slices.Contains(sources, source) {
+		return sources
+	}
+	return append(sources, source)
+}
+
+func authorizationKeyForLog(authz Authorization) string {
+	var parts = []string{}
+	for _, value := range []*string{authz.RealmID, authz.GroupName, authz.Action, authz.TargetRealmID, authz.TargetGroupName} {
+		if value == nil {
+			parts = append(parts, "-")
+		} else {
+			parts = append(parts, *value)
+		}
+	}
+	return strings.Join(parts, "/")
+}
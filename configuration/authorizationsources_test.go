@@ -0,0 +1,132 @@
+package configuration
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cloudtrust/common-service/v2/log"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	baselineAuthorizationsYAML = `
+authorizations:
+  - realm_id: master
+    group_id: technical
+    action: GetRealm
+    target_realm_id: "*"
+  - realm_id: master
+    group_id: technical
+    action: GetUser
+    target_realm_id: master
+    deny: true
+`
+	dbAuthorizationsJSON = `{"authorizations": [
+		{"realm_id": "master", "group_id": "technical", "action": "GetRealm", "target_realm_id": "*"},
+		{"realm_id": "master", "group_id": "technical", "action": "GetUser", "target_realm_id": "master"},
+		{"realm_id": "master", "group_id": "admin", "action": "CreateUser", "target_realm_id": "customer", "target_group_name": "users"}
+	]}`
+)
+
+func writeAuthorizationsFile(t *testing.T, name string, content string) string {
+	var path = filepath.Join(t.TempDir(), name)
+	assert.Nil(t, os.WriteFile(path, []byte(content), 0600))
+	return path
+}
+
+func TestUnmarshalAuthorizations(t *testing.T) {
+	t.Run("Invalid format", func(t *testing.T) {
+		var _, err = UnmarshalAuthorizations([]byte(dbAuthorizationsJSON), "xml")
+		assert.NotNil(t, err)
+	})
+	t.Run("Invalid YAML", func(t *testing.T) {
+		var _, err = UnmarshalAuthorizations([]byte("authorizations: ["), BundleFormatYAML)
+		assert.NotNil(t, err)
+	})
+	t.Run("Missing mandatory values", func(t *testing.T) {
+		for _, content := range []string{
+			`{"authorizations": [{"group_id": "g", "action": "a"}]}`,
+			`{"authorizations": [{"realm_id": "r", "action": "a"}]}`,
+			`{"authorizations": [{"realm_id": "r", "group_id": "g"}]}`,
+			`{"authorizations": [{"realm_id": "r", "group_id": "g", "action": "a", "target_group_name": "t"}]}`,
+		} {
+			var _, err = UnmarshalAuthorizations([]byte(content), BundleFormatJSON)
+			assert.NotNil(t, err, content)
+		}
+	})
+	t.Run("Success", func(t *testing.T) {
+		var res, err = UnmarshalAuthorizations([]byte(baselineAuthorizationsYAML), BundleFormatYAML)
+		assert.Nil(t, err)
+		assert.Len(t, res, 2)
+		assert.Equal(t, "technical", *res[0].GroupName)
+		assert.Equal(t, "*", *res[0].TargetRealmID)
+		assert.Nil(t, res[0].TargetGroupName)
+		assert.True(t, res[1].Deny)
+	})
+}
+
+func TestAuthorizationFileReader(t *testing.T) {
+	var ctx = context.TODO()
+	var logger = log.NewNopLogger()
+
+	t.Run("Missing file", func(t *testing.T) {
+		var _, err = NewAuthorizationFileReader(filepath.Join(t.TempDir(), "missing.yaml"), logger).GetAuthorizations(ctx)
+		assert.NotNil(t, err)
+	})
+	t.Run("Invalid file", func(t *testing.T) {
+		var path = writeAuthorizationsFile(t, "authz.json", baselineAuthorizationsYAML)
+		var _, err = NewAuthorizationFileReader(path, logger).GetAuthorizations(ctx)
+		assert.NotNil(t, err)
+	})
+	t.Run("JSON file", func(t *testing.T) {
+		var path = writeAuthorizationsFile(t, "authz.JSON", dbAuthorizationsJSON)
+		var res, err = NewAuthorizationFileReader(path, logger).GetAuthorizations(ctx)
+		assert.Nil(t, err)
+		assert.Len(t, res, 3)
+	})
+	t.Run("Actions scope", func(t *testing.T) {
+		var path = writeAuthorizationsFile(t, "authz.yml", baselineAuthorizationsYAML)
+		var res, err = NewAuthorizationFileReader(path, logger, []string{"GetUser"}).GetAuthorizations(ctx)
+		assert.Nil(t, err)
+		assert.Len(t, res, 1)
+		assert.Equal(t, "GetUser", *res[0].Action)
+	})
+}
+
+func TestCompositeAuthorizationReader(t *testing.T) {
+	var ctx = context.TODO()
+	var logger = log.NewNopLogger()
+	var baseline = NewAuthorizationFileReader(writeAuthorizationsFile(t, "baseline.yaml", baselineAuthorizationsYAML), logger)
+	var db = NewAuthorizationFileReader(writeAuthorizationsFile(t, "db.json", dbAuthorizationsJSON), logger)
+
+	t.Run("Failing source", func(t *testing.T) {
+		var missing = NewAuthorizationFileReader(filepath.Join(t.TempDir(), "missing.yaml"), logger)
+		var reader = NewCompositeAuthorizationReader(logger, AuthorizationSource{Name: "baseline", Reader: baseline}, AuthorizationSource{Name: "db", Reader: missing})
+		var _, err = reader.GetAuthorizations(ctx)
+		assert.NotNil(t, err)
+		assert.Contains(t, err.Error(), "db")
+	})
+	t.Run("Single source", func(t *testing.T) {
+		var reader = NewCompositeAuthorizationReader(logger, AuthorizationSource{Name: "baseline", Reader: baseline})
+		var res, err = reader.GetAuthorizations(ctx)
+		assert.Nil(t, err)
+		assert.Len(t, res, 2)
+		assert.Len(t, reader.Conflicts(), 0)
+	})
+	t.Run("Merged sources with conflict", func(t *testing.T) {
+		var reader = NewCompositeAuthorizationReader(logger, AuthorizationSource{Name: "baseline", Reader: baseline}, AuthorizationSource{Name: "db", Reader: db})
+		var res, err = reader.GetAuthorizations(ctx)
+		assert.Nil(t, err)
+		// GetRealm is provided by both sources and returned once, GetUser is both allowed and denied
+		assert.Len(t, res, 4)
+
+		var conflicts = reader.Conflicts()
+		assert.Len(t, conflicts, 1)
+		assert.Equal(t, "GetUser", *conflicts[0].Authorization.Action)
+		assert.False(t, conflicts[0].Authorization.Deny)
+		assert.Equal(t, []string{"db"}, conflicts[0].AllowedBy)
+		assert.Equal(t, []string{"baseline"}, conflicts[0].DeniedBy)
+	})
+}
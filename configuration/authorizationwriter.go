@@ -0,0 +1,225 @@
+package configuration
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/cloudtrust/common-service/v2/database/sqltypes"
+	cerrors "github.com/cloudtrust/common-service/v2/errors"
+	"github.com/cloudtrust/common-service/v2/log"
+)
+
+const (
+	selectGroupAuthzStmt = `SELECT realm_id, group_name, action, target_realm_id, target_group_name, deny, conditions FROM authorizations WHERE realm_id = ? AND group_name = ?;`
+	insertAuthzStmt      = `INSERT INTO authorizations (realm_id, group_name, action, target_realm_id, target_group_name, deny, conditions) VALUES (?, ?, ?, ?, ?, ?, ?);`
+	deleteAuthzStmt      = `DELETE FROM authorizations WHERE realm_id = ? AND group_name = ? AND action = ? AND target_realm_id <=> ? AND target_group_name <=> ? AND deny = ? AND CAST(conditions AS CHAR) <=> ?;`
+)
+
+// AuthorizationChanges lists the authorizations inserted and deleted by a synchronization
+type AuthorizationChanges struct {
+	Added   []Authorization
+	Removed []Authorization
+}
+
+// IsEmpty tells if no change has been applied
+func (c AuthorizationChanges) IsEmpty() bool {
+	return len(c.Added) == 0 && len(c.Removed) == 0
+}
+
+// storedAuthorizations are authorizations read from the database, indexed by authorizationKey. Their conditions are also kept
+// as stored: the stored text may differ from the conditions marshaled again (key order, whitespaces) and deletions must match it
+type storedAuthorizations struct {
+	authorizations map[string]Authorization
+	conditions     map[string]*string
+}
+
+func newStoredAuthorizations() storedAuthorizations {
+	return storedAuthorizations{authorizations: map[string]Authorization{}, conditions: map[string]*string{}}
+}
+
+func (s storedAuthorizations) add(authz Authorization, conditions *string) {
+	var key = authorizationKey(authz)
+	s.authorizations[key] = authz
+	s.conditions[key] = conditions
+}
+
+// delete deletes a stored authorization. It fails unless exactly one row is deleted
+func (s storedAuthorizations) delete(tx sqltypes.Transaction, authz Authorization) error {
+	var res, err = tx.Exec(deleteAuthzStmt, authz.RealmID, authz.GroupName, authz.Action, authz.TargetRealmID, authz.TargetGroupName, authz.Deny,
+		s.conditions[authorizationKey(authz)])
+	if err != nil {
+		return err
+	}
+	var count int64
+	if count, err = res.RowsAffected(); err != nil {
+		return err
+	}
+	if count != 1 {
+		return fmt.Errorf("%d authorizations deleted instead of 1", count)
+	}
+	return nil
+}
+
+// AuthorizationWriterDBModule struct
+type AuthorizationWriterDBModule struct {
+	db           sqltypes.CloudtrustDB
+	knownActions map[string]bool
+	logger       log.Logger
+}
+
+// NewAuthorizationWriterDBModule returns an AuthorizationWriterDBModule.
+// Known actions are usually provided by security.Actions.GetAllActionNames(). If actions is empty, any action name is accepted.
+// The authorizations table must have the deny and conditions columns (see ConfigurationReaderDBModule.GetAuthorizations)
+func NewAuthorizationWriterDBModule(db sqltypes.CloudtrustDB, logger log.Logger, actions []string) *AuthorizationWriterDBModule {
+	var knownActions map[string]bool
+	if len(actions) > 0 {
+		knownActions = make(map[string]bool)
+		for _, action := range actions {
+			knownActions[action] = true
+		}
+	}
+	return &AuthorizationWriterDBModule{
+		db:           db,
+		knownActions: knownActions,
+		logger:       logger,
+	}
+}
+
+// SyncGroupAuthorizations replaces the authorizations of a group with the desired ones.
+// Only the differences between the database and the desired authorizations are applied, in a single transaction.
+// The returned changes can be used to emit audit events
+func (w *AuthorizationWriterDBModule) SyncGroupAuthorizations(ctx context.Context, realmID string, groupName string, authorizations []Authorization) (AuthorizationChanges, error) {
+	var desired = map[string]Authorization{}
+	for _, authz := range authorizations {
+		if err := w.validateAuthorization(realmID, groupName, authz); err != nil {
+			w.logger.Warn(ctx, "msg", "Invalid authorization", "realm", realmID, "group", groupName, "err", err.Error())
+			return AuthorizationChanges{}, err
+		}
+		authz.RealmID = &realmID
+		authz.GroupName = &groupName
+		desired[authorizationKey(authz)] = authz
+	}
+
+	tx, err := w.db.BeginTx(ctx, nil)
+	if err != nil {
+		w.logger.Warn(ctx, "msg", "Can't start transaction", "err", err.Error())
+		return AuthorizationChanges{}, err
+	}
+	defer tx.Close()
+
+	current, err := w.getGroupAuthorizations(ctx, tx, realmID, groupName)
+	if err != nil {
+		return AuthorizationChanges{}, err
+	}
+
+	var changes = diffAuthorizations(current.authorizations, desired)
+	for _, authz := range changes.Removed {
+		if err = current.delete(tx, authz); err != nil {
+			w.logger.Warn(ctx, "msg", "Can't delete authorization", "realm", realmID, "group", groupName, "action", *authz.Action, "err", err.Error())
+			return AuthorizationChanges{}, err
+		}
+	}
+	for _, authz := range changes.Added {
+		if _, err = tx.Exec(insertAuthzStmt, authz.RealmID, authz.GroupName, authz.Action, authz.TargetRealmID, authz.TargetGroupName, authz.Deny, conditionsValue(authz.Conditions)); err != nil {
+			w.logger.Warn(ctx, "msg", "Can't insert authorization", "realm", realmID, "group", groupName, "action", *authz.Action, "err", err.Error())
+			return AuthorizationChanges{}, err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		w.logger.Warn(ctx, "msg", "Can't commit authorizations", "realm", realmID, "group", groupName, "err", err.Error())
+		return AuthorizationChanges{}, err
+	}
+
+	return changes, nil
+}
+
+func (w *AuthorizationWriterDBModule) validateAuthorization(realmID string, groupName string, authz Authorization) error {
+	if authz.Action == nil {
+		return cerrors.CreateMissingParameterError("action")
+	}
+	if w.knownActions != nil && !w.knownActions[*authz.Action] {
+		return cerrors.CreateBadRequestError(cerrors.MsgErrInvalidParam + ".action")
+	}
+	if authz.RealmID != nil && *authz.RealmID != realmID {
+		return cerrors.CreateBadRequestError(cerrors.MsgErrInvalidParam + ".realm_id")
+	}
+	if authz.GroupName != nil && *authz.GroupName != groupName {
+		return cerrors.CreateBadRequestError(cerrors.MsgErrInvalidParam + ".group_id")
+	}
+	if authz.TargetRealmID == nil && authz.TargetGroupName != nil {
+		return cerrors.CreateMissingParameterError("target_realm_id")
+	}
+	if authz.Conditions != nil {
+		return authz.Conditions.Validate()
+	}
+	return nil
+}
+
+func (w *AuthorizationWriterDBModule) getGroupAuthorizations(ctx context.Context, tx sqltypes.Transaction, realmID string, groupName string) (storedAuthorizations, error) {
+	rows, err := tx.Query(selectGroupAuthzStmt, realmID, groupName)
+	if err != nil {
+		w.logger.Warn(ctx, "msg", "Can't get authorizations", "realm", realmID, "group", groupName, "err", err.Error())
+		return storedAuthorizations{}, err
+	}
+	defer rows.Close()
+
+	var res = newStoredAuthorizations()
+	for rows.Next() {
+		authz, conditions, err := scanStoredAuthorization(rows)
+		if err != nil {
+			w.logger.Warn(ctx, "msg", "Can't get authorizations. Scan failed", "realm", realmID, "group", groupName, "err", err.Error())
+			return storedAuthorizations{}, err
+		}
+		res.add(authz, conditions)
+	}
+	if err = rows.Err(); err != nil {
+		w.logger.Warn(ctx, "msg", "Can't get authorizations. Failed to iterate on every items", "realm", realmID, "group", groupName, "err", err.Error())
+		return storedAuthorizations{}, err
+	}
+
+	return res, nil
+}
+
+func diffAuthorizations(current map[string]Authorization, desired map[string]Authorization) AuthorizationChanges {
+	var changes AuthorizationChanges
+	for key, authz := range current {
+		if _, ok := desired[key]; !ok {
+			changes.Removed = append(changes.Removed, authz)
+		}
+	}
+	for key, authz := range desired {
+		if _, ok := current[key]; !ok {
+			changes.Added = append(changes.Added, authz)
+		}
+	}
+	sortAuthorizations(changes.Removed)
+	sortAuthorizations(changes.Added)
+	return changes
+}
+
+func authorizationKey(authz Authorization) string {
+	var parts = []string{}
+	for _, value := range []*string{authz.RealmID, authz.GroupName, authz.Action, authz.TargetRealmID, authz.TargetGroupName} {
+		if value == nil {
+			parts = append(parts, "\x00")
+		} else {
+			parts = append(parts, *value)
+		}
+	}
+	if authz.Deny {
+		parts = append(parts, "deny")
+	}
+	if conditions := conditionsValue(authz.Conditions); conditions != nil {
+		parts = append(parts, *conditions)
+	}
+	return strings.Join(parts, "\x1f")
+}
+
+func sortAuthorizations(authorizations []Authorization) {
+	sort.Slice(authorizations, func(i, j int) bool {
+		return authorizationKey(authorizations[i]) < authorizationKey(authorizations[j])
+	})
+}
@@ -0,0 +1,193 @@
+package configuration
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"testing"
+
+	"github.com/cloudtrust/common-service/v2/configuration/mock"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+func ptr(value string) *string {
+	return &value
+}
+
+func mockAuthorizationRows(sqlRows *mock.SQLRows, items []Authorization) {
+	for _, item := range items {
+		sqlRows.EXPECT().Next().Return(true)
+		sqlRows.EXPECT().Scan(gomock.Any()).DoAndReturn(func(dest ...any) error {
+			*(dest[0]).(*string) = *item.RealmID
+			*(dest[1]).(*string) = *item.GroupName
+			*(dest[2]).(*string) = *item.Action
+			if item.TargetRealmID != nil {
+				*(dest[3]).(*sql.NullString) = sql.NullString{String: *item.TargetRealmID, Valid: true}
+			}
+			if item.TargetGroupName != nil {
+				*(dest[4]).(*sql.NullString) = sql.NullString{String: *item.TargetGroupName, Valid: true}
+			}
+			*(dest[5]).(*bool) = item.Deny
+			if conditions := conditionsValue(item.Conditions); conditions != nil {
+				*(dest[6]).(*sql.NullString) = sql.NullString{String: *conditions, Valid: true}
+			}
+			return nil
+		})
+	}
+	sqlRows.EXPECT().Next().Return(false)
+	sqlRows.EXPECT().Err()
+	sqlRows.EXPECT().Close()
+}
+
+func TestSyncGroupAuthorizations(t *testing.T) {
+	var mocks = newDbMocks(t)
+	defer mocks.finish()
+
+	var tx = mock.NewTransaction(mocks.mockCtrl)
+	var realm = "realm"
+	var group = "group"
+	var ctx = context.TODO()
+	var sqlError = errors.New("SQL error")
+	var module = NewAuthorizationWriterDBModule(mocks.db, mocks.logger, []string{"GetUser", "GetUsers", "DeleteUser"})
+
+	var existing = []Authorization{
+		{RealmID: &realm, GroupName: &group, Action: ptr("GetUser")},
+		{RealmID: &realm, GroupName: &group, Action: ptr("DeleteUser")},
+	}
+	var desired = []Authorization{
+		{Action: ptr("GetUser")},
+		{Action: ptr("GetUsers")},
+	}
+
+	t.Run("Unknown action", func(t *testing.T) {
+		var _, err = module.SyncGroupAuthorizations(ctx, realm, group, []Authorization{{Action: ptr("Unknown")}})
+		assert.NotNil(t, err)
+	})
+	t.Run("Missing action", func(t *testing.T) {
+		var _, err = module.SyncGroupAuthorizations(ctx, realm, group, []Authorization{{}})
+		assert.NotNil(t, err)
+	})
+	t.Run("Authorization for another group", func(t *testing.T) {
+		var _, err = module.SyncGroupAuthorizations(ctx, realm, group, []Authorization{{GroupName: ptr("other"), Action: ptr("GetUser")}})
+		assert.NotNil(t, err)
+	})
+	t.Run("Target group without target realm", func(t *testing.T) {
+		var _, err = module.SyncGroupAuthorizations(ctx, realm, group, []Authorization{{Action: ptr("GetUser"), TargetGroupName: ptr("*")}})
+		assert.NotNil(t, err)
+	})
+	t.Run("Can't start transaction", func(t *testing.T) {
+		mocks.db.EXPECT().BeginTx(ctx, nil).Return(nil, sqlError)
+		var _, err = module.SyncGroupAuthorizations(ctx, realm, group, desired)
+		assert.Equal(t, sqlError, err)
+	})
+	t.Run("Can't read current authorizations", func(t *testing.T) {
+		mocks.db.EXPECT().BeginTx(ctx, nil).Return(tx, nil)
+		tx.EXPECT().Query(gomock.Any(), realm, group).Return(nil, sqlError)
+		tx.EXPECT().Close()
+		var _, err = module.SyncGroupAuthorizations(ctx, realm, group, desired)
+		assert.Equal(t, sqlError, err)
+	})
+	t.Run("Delete fails", func(t *testing.T) {
+		mocks.db.EXPECT().BeginTx(ctx, nil).Return(tx, nil)
+		tx.EXPECT().Query(gomock.Any(), realm, group).Return(mocks.sqlRows, nil)
+		mockAuthorizationRows(mocks.sqlRows, existing)
+		tx.EXPECT().Exec(deleteAuthzStmt, gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, sqlError)
+		tx.EXPECT().Close()
+		var _, err = module.SyncGroupAuthorizations(ctx, realm, group, desired)
+		assert.Equal(t, sqlError, err)
+	})
+	t.Run("Delete matches no row", func(t *testing.T) {
+		mocks.db.EXPECT().BeginTx(ctx, nil).Return(tx, nil)
+		tx.EXPECT().Query(gomock.Any(), realm, group).Return(mocks.sqlRows, nil)
+		mockAuthorizationRows(mocks.sqlRows, existing)
+		tx.EXPECT().Exec(deleteAuthzStmt, gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(driver.RowsAffected(0), nil)
+		tx.EXPECT().Close()
+		var _, err = module.SyncGroupAuthorizations(ctx, realm, group, desired)
+		assert.NotNil(t, err)
+	})
+	t.Run("Delete matches the stored conditions", func(t *testing.T) {
+		var storedConditions = `{ "source_cidrs": ["10.0.0.0/8"] }`
+		mocks.db.EXPECT().BeginTx(ctx, nil).Return(tx, nil)
+		tx.EXPECT().Query(gomock.Any(), realm, group).Return(mocks.sqlRows, nil)
+		mocks.sqlRows.EXPECT().Next().Return(true)
+		mocks.sqlRows.EXPECT().Scan(gomock.Any()).DoAndReturn(func(dest ...any) error {
+			*(dest[0]).(*string) = realm
+			*(dest[1]).(*string) = group
+			*(dest[2]).(*string) = "DeleteUser"
+			*(dest[6]).(*sql.NullString) = sql.NullString{String: storedConditions, Valid: true}
+			return nil
+		})
+		mocks.sqlRows.EXPECT().Next().Return(false)
+		mocks.sqlRows.EXPECT().Err()
+		mocks.sqlRows.EXPECT().Close()
+		tx.EXPECT().Exec(deleteAuthzStmt, &realm, &group, ptr("DeleteUser"), nil, nil, false, &storedConditions).Return(driver.RowsAffected(1), nil)
+		tx.EXPECT().Commit()
+		tx.EXPECT().Close()
+		var changes, err = module.SyncGroupAuthorizations(ctx, realm, group, nil)
+		assert.Nil(t, err)
+		assert.Len(t, changes.Removed, 1)
+	})
+	t.Run("Insert fails", func(t *testing.T) {
+		mocks.db.EXPECT().BeginTx(ctx, nil).Return(tx, nil)
+		tx.EXPECT().Query(gomock.Any(), realm, group).Return(mocks.sqlRows, nil)
+		mockAuthorizationRows(mocks.sqlRows, existing)
+		tx.EXPECT().Exec(deleteAuthzStmt, gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(driver.RowsAffected(1), nil)
+		tx.EXPECT().Exec(insertAuthzStmt, gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, sqlError)
+		tx.EXPECT().Close()
+		var _, err = module.SyncGroupAuthorizations(ctx, realm, group, desired)
+		assert.Equal(t, sqlError, err)
+	})
+	t.Run("Commit fails", func(t *testing.T) {
+		mocks.db.EXPECT().BeginTx(ctx, nil).Return(tx, nil)
+		tx.EXPECT().Query(gomock.Any(), realm, group).Return(mocks.sqlRows, nil)
+		mockAuthorizationRows(mocks.sqlRows, existing)
+		tx.EXPECT().Exec(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(driver.RowsAffected(1), nil).Times(2)
+		tx.EXPECT().Commit().Return(sqlError)
+		tx.EXPECT().Close()
+		var _, err = module.SyncGroupAuthorizations(ctx, realm, group, desired)
+		assert.Equal(t, sqlError, err)
+	})
+	t.Run("Success", func(t *testing.T) {
+		mocks.db.EXPECT().BeginTx(ctx, nil).Return(tx, nil)
+		tx.EXPECT().Query(gomock.Any(), realm, group).Return(mocks.sqlRows, nil)
+		mockAuthorizationRows(mocks.sqlRows, existing)
+		tx.EXPECT().Exec(deleteAuthzStmt, &realm, &group, ptr("DeleteUser"), nil, nil, false, nil).Return(driver.RowsAffected(1), nil)
+		tx.EXPECT().Exec(insertAuthzStmt, &realm, &group, ptr("GetUsers"), nil, nil, false, nil).Return(driver.RowsAffected(1), nil)
+		tx.EXPECT().Commit()
+		tx.EXPECT().Close()
+		var changes, err = module.SyncGroupAuthorizations(ctx, realm, group, desired)
+		assert.Nil(t, err)
+		assert.False(t, changes.IsEmpty())
+		assert.Len(t, changes.Added, 1)
+		assert.Equal(t, "GetUsers", *changes.Added[0].Action)
+		assert.Len(t, changes.Removed, 1)
+		assert.Equal(t, "DeleteUser", *changes.Removed[0].Action)
+	})
+	t.Run("Nothing to change", func(t *testing.T) {
+		mocks.db.EXPECT().BeginTx(ctx, nil).Return(tx, nil)
+		tx.EXPECT().Query(gomock.Any(), realm, group).Return(mocks.sqlRows, nil)
+		mockAuthorizationRows(mocks.sqlRows, existing)
+		tx.EXPECT().Commit()
+		tx.EXPECT().Close()
+		var changes, err = module.SyncGroupAuthorizations(ctx, realm, group, existing)
+		assert.Nil(t, err)
+		assert.True(t, changes.IsEmpty())
+	})
+}
+
+func TestDiffAuthorizationsWithDeny(t *testing.T) {
+	var allow = Authorization{RealmID: ptr("realm"), GroupName: ptr("group"), Action: ptr("GetUser"), TargetRealmID: ptr("*")}
+	var deny = allow
+	deny.Deny = true
+
+	assert.NotEqual(t, authorizationKey(allow), authorizationKey(deny))
+
+	var changes = diffAuthorizations(
+		map[string]Authorization{authorizationKey(allow): allow},
+		map[string]Authorization{authorizationKey(deny): deny},
+	)
+	assert.Equal(t, []Authorization{deny}, changes.Added)
+	assert.Equal(t, []Authorization{allow}, changes.Removed)
+}
@@ -0,0 +1,544 @@
+package configuration
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/cloudtrust/common-service/v2/database/sqltypes"
+	cerrors "github.com/cloudtrust/common-service/v2/errors"
+	"github.com/cloudtrust/common-service/v2/log"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// RealmBundleVersion is the current version of the realm bundle format
+	RealmBundleVersion = 1
+
+	// BundleFormatJSON is the JSON serialization of a realm bundle
+	BundleFormatJSON = "json"
+	// BundleFormatYAML is the YAML serialization of a realm bundle
+	BundleFormatYAML = "yaml"
+
+	selectRealmConfigsStmt    = `SELECT configuration, admin_configuration FROM realm_configuration WHERE realm_id = ?`
+	upsertRealmConfigsStmt    = `INSERT INTO realm_configuration (realm_id, configuration, admin_configuration) VALUES (?, ?, ?) ON DUPLICATE KEY UPDATE configuration = IFNULL(VALUES(configuration), configuration), admin_configuration = IFNULL(VALUES(admin_configuration), admin_configuration)`
+	selectContextKeyRealmStmt = `SELECT customer_realm FROM context_key_configuration WHERE id = ?`
+	insertContextKeyStmt      = `INSERT INTO context_key_configuration (id, label, identities_realm, customer_realm, configuration, is_register_default) VALUES (?, ?, ?, ?, ?, ?)`
+	updateContextKeyStmt      = `UPDATE context_key_configuration SET label = ?, identities_realm = ?, configuration = ?, is_register_default = ? WHERE id = ? AND customer_realm = ?`
+	deleteContextKeyStmt      = `DELETE FROM context_key_configuration WHERE id = ? AND customer_realm = ?`
+	selectRealmAuthzStmt      = `SELECT realm_id, group_name, action, target_realm_id, target_group_name, deny, conditions FROM authorizations WHERE realm_id = ?;`
+	wildcardAllRealms         = "*"
+	wildcardAllNonMasterRealm = "/"
+)
+
+// RealmBundle is a versioned export of the configuration of a realm
+type RealmBundle struct {
+	Version            int                      `json:"version"`
+	ExportedAt         time.Time                `json:"exported_at"`
+	RealmID            string                   `json:"realm_id"`
+	RealmName          string                   `json:"realm_name"`
+	Configuration      *RealmConfiguration      `json:"configuration,omitempty"`
+	AdminConfiguration *RealmAdminConfiguration `json:"admin_configuration,omitempty"`
+	ContextKeys        []BundleContextKey       `json:"context_keys,omitempty"`
+	Authorizations     []Authorization          `json:"authorizations,omitempty"`
+}
+
+// BundleContextKey is the representation of a context key in a realm bundle
+type BundleContextKey struct {
+	ID                string                  `json:"id"`
+	Label             string                  `json:"label"`
+	IdentitiesRealm   string                  `json:"identities_realm"`
+	CustomerRealm     string                  `json:"customer_realm"`
+	Config            ContextKeyConfiguration `json:"configuration"`
+	IsRegisterDefault bool                    `json:"is_register_default"`
+}
+
+// RealmImportOptions defines how a realm bundle is imported
+type RealmImportOptions struct {
+	// TargetRealmID is the ID of the realm receiving the configuration. Defaults to the bundle realm ID
+	TargetRealmID string
+	// TargetRealmName is the name of the realm receiving the configuration. Defaults to the bundle realm name
+	TargetRealmName string
+	// RealmMapping remaps other realm names referenced by the bundle (identities realm, target realms, ...)
+	RealmMapping map[string]string
+	// DryRun computes the report without applying any change
+	DryRun bool
+}
+
+// RealmImportReport describes the changes applied (or which would be applied) by an import
+type RealmImportReport struct {
+	DryRun                    bool                 `json:"dry_run"`
+	RealmID                   string               `json:"realm_id"`
+	RealmName                 string               `json:"realm_name"`
+	ConfigurationChanged      bool                 `json:"configuration_changed"`
+	AdminConfigurationChanged bool                 `json:"admin_configuration_changed"`
+	ContextKeysAdded          []string             `json:"context_keys_added,omitempty"`
+	ContextKeysUpdated        []string             `json:"context_keys_updated,omitempty"`
+	ContextKeysRemoved        []string             `json:"context_keys_removed,omitempty"`
+	Authorizations            AuthorizationChanges `json:"authorizations"`
+}
+
+// Validate checks the consistency of a realm bundle
+func (b RealmBundle) Validate() error {
+	if b.Version < 1 || b.Version > RealmBundleVersion {
+		return cerrors.CreateBadRequestError(cerrors.MsgErrInvalidParam + ".version")
+	}
+	if b.RealmID == "" {
+		return cerrors.CreateMissingParameterError("realm_id")
+	}
+	if b.RealmName == "" {
+		return cerrors.CreateMissingParameterError("realm_name")
+	}
+	var ids = map[string]bool{}
+	for _, ctxKey := range b.ContextKeys {
+		if ctxKey.ID == "" {
+			return cerrors.CreateMissingParameterError("context_keys.id")
+		}
+		if ids[ctxKey.ID] {
+			return cerrors.CreateBadRequestError(cerrors.MsgErrInvalidParam + ".context_keys.id")
+		}
+		ids[ctxKey.ID] = true
+		if ctxKey.CustomerRealm != b.RealmName {
+			return cerrors.CreateBadRequestError(cerrors.MsgErrInvalidParam + ".context_keys.customer_realm")
+		}
+	}
+	for _, authz := range b.Authorizations {
+		if authz.RealmID == nil || *authz.RealmID != b.RealmName {
+			return cerrors.CreateBadRequestError(cerrors.MsgErrInvalidParam + ".authorizations.realm_id")
+		}
+		if authz.GroupName == nil {
+			return cerrors.CreateMissingParameterError("authorizations.group_id")
+		}
+		if authz.Action == nil {
+			return cerrors.CreateMissingParameterError("authorizations.action")
+		}
+		if authz.TargetRealmID == nil && authz.TargetGroupName != nil {
+			return cerrors.CreateMissingParameterError("authorizations.target_realm_id")
+		}
+		if authz.Conditions != nil {
+			if err := authz.Conditions.Validate(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// MarshalRealmBundle serializes a realm bundle in the given format
+func MarshalRealmBundle(bundle RealmBundle, format string) ([]byte, error) {
+	var bytes, err = json.MarshalIndent(bundle, "", "  ")
+	if err != nil || format == BundleFormatJSON {
+		return bytes, err
+	}
+	if format != BundleFormatYAML {
+		return nil, cerrors.CreateBadRequestError(cerrors.MsgErrInvalidParam + ".format")
+	}
+	// Converts through a generic structure to keep the JSON field names in the YAML document
+	var generic any
+	if err = json.Unmarshal(bytes, &generic); err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(generic)
+}
+
+// UnmarshalRealmBundle deserializes a realm bundle from the given format
+func UnmarshalRealmBundle(data []byte, format string) (RealmBundle, error) {
+	var bundle RealmBundle
+	switch format {
+	case BundleFormatJSON:
+	case BundleFormatYAML:
+		var generic any
+		if err := yaml.Unmarshal(data, &generic); err != nil {
+			return bundle, err
+		}
+		var err error
+		if data, err = json.Marshal(generic); err != nil {
+			return bundle, err
+		}
+	default:
+		return bundle, cerrors.CreateBadRequestError(cerrors.MsgErrInvalidParam + ".format")
+	}
+	var err = json.Unmarshal(data, &bundle)
+	return bundle, err
+}
+
+// RealmBundleDBModule exports and imports realm bundles
+type RealmBundleDBModule struct {
+	db           sqltypes.CloudtrustDB
+	knownActions map[string]bool
+	logger       log.Logger
+}
+
+// NewRealmBundleDBModule returns a RealmBundleDBModule.
+// Known actions are usually provided by security.Actions.GetAllActionNames(). If no action is provided, any action name is accepted
+func NewRealmBundleDBModule(db sqltypes.CloudtrustDB, logger log.Logger, actions ...[]string) *RealmBundleDBModule {
+	var knownActions map[string]bool
+	if len(actions) > 0 {
+		knownActions = make(map[string]bool)
+		for _, actionSet := range actions {
+			for _, action := range actionSet {
+				knownActions[action] = true
+			}
+		}
+	}
+	return &RealmBundleDBModule{
+		db:           db,
+		knownActions: knownActions,
+		logger:       logger,
+	}
+}
+
+// ExportRealm exports realm configuration, admin configuration, context keys and authorizations of a realm
+func (m *RealmBundleDBModule) ExportRealm(ctx context.Context, realmID string, realmName string) (RealmBundle, error) {
+	var bundle = RealmBundle{
+		Version:    RealmBundleVersion,
+		ExportedAt: time.Now().UTC(),
+		RealmID:    realmID,
+		RealmName:  realmName,
+	}
+	var err error
+
+	if bundle.Configuration, bundle.AdminConfiguration, err = m.getRealmConfigurations(ctx, m.db, realmID); err != nil {
+		return RealmBundle{}, err
+	}
+
+	var ctxKeys map[string]RealmContextKey
+	if ctxKeys, err = m.getContextKeys(ctx, m.db, realmName); err != nil {
+		return RealmBundle{}, err
+	}
+	for _, ctxKey := range ctxKeys {
+		bundle.ContextKeys = append(bundle.ContextKeys, BundleContextKey(ctxKey))
+	}
+	sort.Slice(bundle.ContextKeys, func(i, j int) bool {
+		return bundle.ContextKeys[i].ID < bundle.ContextKeys[j].ID
+	})
+
+	var authorizations storedAuthorizations
+	if authorizations, err = m.getRealmAuthorizations(ctx, m.db, realmName); err != nil {
+		return RealmBundle{}, err
+	}
+	for _, authz := range authorizations.authorizations {
+		bundle.Authorizations = append(bundle.Authorizations, authz)
+	}
+	sortAuthorizations(bundle.Authorizations)
+
+	return bundle, nil
+}
+
+// ImportRealm validates a bundle, remaps its realm names and applies it in a single transaction.
+// When options.DryRun is set, the transaction is rolled back and only the report is returned
+func (m *RealmBundleDBModule) ImportRealm(ctx context.Context, bundle RealmBundle, options RealmImportOptions) (RealmImportReport, error) {
+	if err := bundle.Validate(); err != nil {
+		m.logger.Info(ctx, "msg", "Invalid realm bundle", "realm", bundle.RealmName, "err", err.Error())
+		return RealmImportReport{}, err
+	}
+	for _, authz := range bundle.Authorizations {
+		if m.knownActions != nil && !m.knownActions[*authz.Action] {
+			m.logger.Info(ctx, "msg", "Unknown action in realm bundle", "realm", bundle.RealmName, "action", *authz.Action)
+			return RealmImportReport{}, cerrors.CreateBadRequestError(cerrors.MsgErrInvalidParam + ".authorizations.action")
+		}
+	}
+	bundle = remapRealmBundle(bundle, options)
+
+	var report = RealmImportReport{
+		DryRun:    options.DryRun,
+		RealmID:   bundle.RealmID,
+		RealmName: bundle.RealmName,
+	}
+
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		m.logger.Warn(ctx, "msg", "Can't start transaction", "err", err.Error())
+		return RealmImportReport{}, err
+	}
+	defer tx.Close()
+
+	if err = m.importRealmConfigurations(ctx, tx, bundle, &report); err != nil {
+		return RealmImportReport{}, err
+	}
+	if err = m.importContextKeys(ctx, tx, bundle, &report); err != nil {
+		return RealmImportReport{}, err
+	}
+	if err = m.importAuthorizations(ctx, tx, bundle, &report); err != nil {
+		return RealmImportReport{}, err
+	}
+
+	if options.DryRun {
+		return report, nil
+	}
+	if err = tx.Commit(); err != nil {
+		m.logger.Warn(ctx, "msg", "Can't commit realm import", "realm", bundle.RealmName, "err", err.Error())
+		return RealmImportReport{}, err
+	}
+	m.logger.Info(ctx, "msg", "Realm bundle imported", "realm", bundle.RealmName)
+	return report, nil
+}
+
+func remapRealmBundle(bundle RealmBundle, options RealmImportOptions) RealmBundle {
+	var mapping = map[string]string{}
+	for source, target := range options.RealmMapping {
+		mapping[source] = target
+	}
+	if options.TargetRealmName != "" {
+		mapping[bundle.RealmName] = options.TargetRealmName
+	}
+	var remap = func(realm string) string {
+		if target, ok := mapping[realm]; ok {
+			return target
+		}
+		return realm
+	}
+	var remapPtr = func(realm *string) *string {
+		if realm == nil || *realm == wildcardAllRealms || *realm == wildcardAllNonMasterRealm {
+			return realm
+		}
+		var res = remap(*realm)
+		return &res
+	}
+
+	if options.TargetRealmID != "" {
+		bundle.RealmID = options.TargetRealmID
+	}
+	bundle.RealmName = remap(bundle.RealmName)
+
+	var ctxKeys []BundleContextKey
+	for _, ctxKey := range bundle.ContextKeys {
+		ctxKey.CustomerRealm = remap(ctxKey.CustomerRealm)
+		ctxKey.IdentitiesRealm = remap(ctxKey.IdentitiesRealm)
+		if ctxKey.Config.Accreditation != nil {
+			var accreditation = *ctxKey.Config.Accreditation
+			accreditation.EmailThemeRealm = remapPtr(accreditation.EmailThemeRealm)
+			ctxKey.Config.Accreditation = &accreditation
+		}
+		if ctxKey.Config.AutoVoucher != nil {
+			var autoVoucher = *ctxKey.Config.AutoVoucher
+			autoVoucher.BilledRealm = remapPtr(autoVoucher.BilledRealm)
+			ctxKey.Config.AutoVoucher = &autoVoucher
+		}
+		ctxKeys = append(ctxKeys, ctxKey)
+	}
+	bundle.ContextKeys = ctxKeys
+
+	var authorizations []Authorization
+	for _, authz := range bundle.Authorizations {
+		authz.RealmID = remapPtr(authz.RealmID)
+		authz.TargetRealmID = remapPtr(authz.TargetRealmID)
+		authorizations = append(authorizations, authz)
+	}
+	bundle.Authorizations = authorizations
+
+	return bundle
+}
+
+func (m *RealmBundleDBModule) importRealmConfigurations(ctx context.Context, tx sqltypes.Transaction, bundle RealmBundle, report *RealmImportReport) error {
+	if bundle.Configuration == nil && bundle.AdminConfiguration == nil {
+		return nil
+	}
+	var currentConf, currentAdminConf, err = m.getRealmConfigurations(ctx, tx, bundle.RealmID)
+	if err != nil {
+		return err
+	}
+	report.ConfigurationChanged = bundle.Configuration != nil && !sameJSON(currentConf, bundle.Configuration)
+	report.AdminConfigurationChanged = bundle.AdminConfiguration != nil && !sameJSON(currentAdminConf, bundle.AdminConfiguration)
+	if !report.ConfigurationChanged && !report.AdminConfigurationChanged {
+		return nil
+	}
+
+	var confJSON, adminConfJSON *string
+	if bundle.Configuration != nil {
+		if confJSON, err = toJSONString(bundle.Configuration); err != nil {
+			return err
+		}
+	}
+	if bundle.AdminConfiguration != nil {
+		if adminConfJSON, err = toJSONString(bundle.AdminConfiguration); err != nil {
+			return err
+		}
+	}
+	if _, err = tx.Exec(upsertRealmConfigsStmt, bundle.RealmID, confJSON, adminConfJSON); err != nil {
+		m.logger.Warn(ctx, "msg", "Can't store realm configuration", "realm", bundle.RealmName, "err", err.Error())
+		return err
+	}
+	return nil
+}
+
+// importContextKeys only writes context keys of the imported realm. A context key of the bundle whose ID already belongs to
+// another realm is rejected: it is not moved to the imported realm
+func (m *RealmBundleDBModule) importContextKeys(ctx context.Context, tx sqltypes.Transaction, bundle RealmBundle, report *RealmImportReport) error {
+	var current, err = m.getContextKeys(ctx, tx, bundle.RealmName)
+	if err != nil {
+		return err
+	}
+
+	var desired = map[string]bool{}
+	for _, ctxKey := range bundle.ContextKeys {
+		desired[ctxKey.ID] = true
+		var existing, ok = current[ctxKey.ID]
+		if ok && sameJSON(BundleContextKey(existing), ctxKey) {
+			continue
+		}
+		var confJSON *string
+		if confJSON, err = toJSONString(ctxKey.Config); err != nil {
+			return err
+		}
+		if ok {
+			report.ContextKeysUpdated = append(report.ContextKeysUpdated, ctxKey.ID)
+			_, err = tx.Exec(updateContextKeyStmt, ctxKey.Label, ctxKey.IdentitiesRealm, confJSON, ctxKey.IsRegisterDefault, ctxKey.ID, ctxKey.CustomerRealm)
+		} else {
+			if err = m.checkContextKeyAvailable(ctx, tx, bundle.RealmName, ctxKey.ID); err != nil {
+				return err
+			}
+			report.ContextKeysAdded = append(report.ContextKeysAdded, ctxKey.ID)
+			_, err = tx.Exec(insertContextKeyStmt, ctxKey.ID, ctxKey.Label, ctxKey.IdentitiesRealm, ctxKey.CustomerRealm, confJSON, ctxKey.IsRegisterDefault)
+		}
+		if err != nil {
+			m.logger.Warn(ctx, "msg", "Can't store context key", "realm", bundle.RealmName, "id", ctxKey.ID, "err", err.Error())
+			return err
+		}
+	}
+	for id := range current {
+		if !desired[id] {
+			report.ContextKeysRemoved = append(report.ContextKeysRemoved, id)
+		}
+	}
+	sort.Strings(report.ContextKeysRemoved)
+	for _, id := range report.ContextKeysRemoved {
+		if _, err = tx.Exec(deleteContextKeyStmt, id, bundle.RealmName); err != nil {
+			m.logger.Warn(ctx, "msg", "Can't delete context key", "realm", bundle.RealmName, "id", id, "err", err.Error())
+			return err
+		}
+	}
+	return nil
+}
+
+// checkContextKeyAvailable fails when a context key ID, unknown in the imported realm, is used by another realm
+func (m *RealmBundleDBModule) checkContextKeyAvailable(ctx context.Context, tx sqltypes.Transaction, realmName string, id string) error {
+	var owner string
+	var err = tx.QueryRow(selectContextKeyRealmStmt, id).Scan(&owner)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		m.logger.Warn(ctx, "msg", "Can't get realm of context key", "realm", realmName, "id", id, "err", err.Error())
+		return err
+	}
+	m.logger.Info(ctx, "msg", "Context key belongs to another realm", "realm", realmName, "id", id, "owner", owner)
+	return cerrors.CreateBadRequestError(cerrors.MsgErrInvalidParam + ".context_keys.id")
+}
+
+func (m *RealmBundleDBModule) importAuthorizations(ctx context.Context, tx sqltypes.Transaction, bundle RealmBundle, report *RealmImportReport) error {
+	var current, err = m.getRealmAuthorizations(ctx, tx, bundle.RealmName)
+	if err != nil {
+		return err
+	}
+	var desired = map[string]Authorization{}
+	for _, authz := range bundle.Authorizations {
+		desired[authorizationKey(authz)] = authz
+	}
+
+	report.Authorizations = diffAuthorizations(current.authorizations, desired)
+	for _, authz := range report.Authorizations.Removed {
+		if err = current.delete(tx, authz); err != nil {
+			m.logger.Warn(ctx, "msg", "Can't delete authorization", "realm", bundle.RealmName, "err", err.Error())
+			return err
+		}
+	}
+	for _, authz := range report.Authorizations.Added {
+		if _, err = tx.Exec(insertAuthzStmt, authz.RealmID, authz.GroupName, authz.Action, authz.TargetRealmID, authz.TargetGroupName, authz.Deny, conditionsValue(authz.Conditions)); err != nil {
+			m.logger.Warn(ctx, "msg", "Can't insert authorization", "realm", bundle.RealmName, "err", err.Error())
+			return err
+		}
+	}
+	return nil
+}
+
+// queryExecutor is implemented by both sqltypes.CloudtrustDB and sqltypes.Transaction
+type queryExecutor interface {
+	Query(query string, args ...any) (sqltypes.SQLRows, error)
+	QueryRow(query string, args ...any) sqltypes.SQLRow
+}
+
+func (m *RealmBundleDBModule) getRealmConfigurations(ctx context.Context, db queryExecutor, realmID string) (*RealmConfiguration, *RealmAdminConfiguration, error) {
+	var confJSON, adminConfJSON sql.NullString
+	if err := db.QueryRow(selectRealmConfigsStmt, realmID).Scan(&confJSON, &adminConfJSON); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil, nil
+		}
+		m.logger.Warn(ctx, "msg", "Can't get realm configuration", "realmID", realmID, "err", err.Error())
+		return nil, nil, err
+	}
+
+	var conf *RealmConfiguration
+	var adminConf *RealmAdminConfiguration
+	if confJSON.Valid {
+		var value, err = NewRealmConfiguration(confJSON.String)
+		if err != nil {
+			return nil, nil, err
+		}
+		conf = &value
+	}
+	if adminConfJSON.Valid {
+		var value, err = NewRealmAdminConfiguration(adminConfJSON.String)
+		if err != nil {
+			return nil, nil, err
+		}
+		adminConf = &value
+	}
+	return conf, adminConf, nil
+}
+
+func (m *RealmBundleDBModule) getContextKeys(ctx context.Context, db queryExecutor, realmName string) (map[string]RealmContextKey, error) {
+	rows, err := db.Query(selectContextKeyConfig, nil, realmName)
+	if err != nil {
+		m.logger.Warn(ctx, "msg", "Can't get context keys", "realm", realmName, "err", err.Error())
+		return nil, err
+	}
+	defer rows.Close()
+
+	var res = map[string]RealmContextKey{}
+	for rows.Next() {
+		ctxKey, err := scanContextKeyConfiguration(rows)
+		if err != nil {
+			m.logger.Warn(ctx, "msg", "Can't get context keys. Scan failed", "realm", realmName, "err", err.Error())
+			return nil, err
+		}
+		res[ctxKey.ID] = ctxKey
+	}
+	if err = rows.Err(); err != nil {
+		m.logger.Warn(ctx, "msg", "Can't get context keys. Failed to iterate on every items", "realm", realmName, "err", err.Error())
+		return nil, err
+	}
+	return res, nil
+}
+
+func (m *RealmBundleDBModule) getRealmAuthorizations(ctx context.Context, db queryExecutor, realmName string) (storedAuthorizations, error) {
+	rows, err := db.Query(selectRealmAuthzStmt, realmName)
+	if err != nil {
+		m.logger.Warn(ctx, "msg", "Can't get authorizations", "realm", realmName, "err", err.Error())
+		return storedAuthorizations{}, err
+	}
+	defer rows.Close()
+
+	var res = newStoredAuthorizations()
+	for rows.Next() {
+		authz, conditions, err := scanStoredAuthorization(rows)
+		if err != nil {
+			m.logger.Warn(ctx, "msg", "Can't get authorizations. Scan failed", "realm", realmName, "err", err.Error())
+			return storedAuthorizations{}, err
+		}
+		res.add(authz, conditions)
+	}
+	if err = rows.Err(); err != nil {
+		m.logger.Warn(ctx, "msg", "Can't get authorizations. Failed to iterate on every items", "realm", realmName, "err", err.Error())
+		return storedAuthorizations{}, err
+	}
+	return res, nil
+}
+
+func sameJSON(value1 any, value2 any) bool {
+	var bytes1, err1 = json.Marshal(value1)
+	var bytes2, err2 = json.Marshal(value2)
+	return err1 == nil && err2 == nil && string(bytes1) == string(bytes2)
+}
@@ -0,0 +1,332 @@
+package configuration
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/cloudtrust/common-service/v2/configuration/mock"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+func createTestBundle() RealmBundle {
+	var realm = "source"
+	return RealmBundle{
+		Version:            RealmBundleVersion,
+		RealmID:            "source-id",
+		RealmName:          realm,
+		Configuration:      &RealmConfiguration{DefaultClientID: ptr("client")},
+		AdminConfiguration: &RealmAdminConfiguration{Mode: ptr("trustID")},
+		ContextKeys: []BundleContextKey{
+			{
+				ID:              "key-1",
+				Label:           "label",
+				IdentitiesRealm: "identities",
+				CustomerRealm:   realm,
+				Config: ContextKeyConfiguration{
+					AutoVoucher: &ContextKeyConfAutovoucher{BilledRealm: ptr(realm)},
+				},
+			},
+		},
+		Authorizations: []Authorization{
+			{RealmID: ptr(realm), GroupName: ptr("admins"), Action: ptr("GetUsers"), TargetRealmID: ptr(realm), TargetGroupName: ptr("*")},
+			{RealmID: ptr(realm), GroupName: ptr("admins"), Action: ptr("GetRealm"), TargetRealmID: ptr("/")},
+		},
+	}
+}
+
+func TestRealmBundleValidate(t *testing.T) {
+	t.Run("Valid", func(t *testing.T) {
+		assert.Nil(t, createTestBundle().Validate())
+	})
+	t.Run("Invalid version", func(t *testing.T) {
+		var bundle = createTestBundle()
+		bundle.Version = RealmBundleVersion + 1
+		assert.NotNil(t, bundle.Validate())
+	})
+	t.Run("Missing realm ID", func(t *testing.T) {
+		var bundle = createTestBundle()
+		bundle.RealmID = ""
+		assert.NotNil(t, bundle.Validate())
+	})
+	t.Run("Missing realm name", func(t *testing.T) {
+		var bundle = createTestBundle()
+		bundle.RealmName = ""
+		assert.NotNil(t, bundle.Validate())
+	})
+	t.Run("Duplicate context key", func(t *testing.T) {
+		var bundle = createTestBundle()
+		bundle.ContextKeys = append(bundle.ContextKeys, bundle.ContextKeys[0])
+		assert.NotNil(t, bundle.Validate())
+	})
+	t.Run("Context key of another realm", func(t *testing.T) {
+		var bundle = createTestBundle()
+		bundle.ContextKeys[0].CustomerRealm = "other"
+		assert.NotNil(t, bundle.Validate())
+	})
+	t.Run("Authorization of another realm", func(t *testing.T) {
+		var bundle = createTestBundle()
+		bundle.Authorizations[0].RealmID = ptr("other")
+		assert.NotNil(t, bundle.Validate())
+	})
+	t.Run("Authorization without action", func(t *testing.T) {
+		var bundle = createTestBundle()
+		bundle.Authorizations[0].Action = nil
+		assert.NotNil(t, bundle.Validate())
+	})
+}
+
+func TestMarshalRealmBundle(t *testing.T) {
+	var bundle = createTestBundle()
+
+	for _, format := range []string{BundleFormatJSON, BundleFormatYAML} {
+		t.Run(format, func(t *testing.T) {
+			var bytes, err = MarshalRealmBundle(bundle, format)
+			assert.Nil(t, err)
+
+			res, err := UnmarshalRealmBundle(bytes, format)
+			assert.Nil(t, err)
+			assert.Equal(t, bundle, res)
+		})
+	}
+	t.Run("Unknown format", func(t *testing.T) {
+		var _, err = MarshalRealmBundle(bundle, "xml")
+		assert.NotNil(t, err)
+		_, err = UnmarshalRealmBundle([]byte("<xml/>"), "xml")
+		assert.NotNil(t, err)
+	})
+	t.Run("Invalid YAML", func(t *testing.T) {
+		var _, err = UnmarshalRealmBundle([]byte("version: [1"), BundleFormatYAML)
+		assert.NotNil(t, err)
+	})
+}
+
+func TestRemapRealmBundle(t *testing.T) {
+	var bundle = remapRealmBundle(createTestBundle(), RealmImportOptions{
+		TargetRealmID:   "target-id",
+		TargetRealmName: "target",
+		RealmMapping:    map[string]string{"identities": "target-identities"},
+	})
+	assert.Equal(t, "target-id", bundle.RealmID)
+	assert.Equal(t, "target", bundle.RealmName)
+	assert.Equal(t, "target", bundle.ContextKeys[0].CustomerRealm)
+	assert.Equal(t, "target-identities", bundle.ContextKeys[0].IdentitiesRealm)
+	assert.Equal(t, "target", *bundle.ContextKeys[0].Config.AutoVoucher.BilledRealm)
+	assert.Equal(t, "target", *bundle.Authorizations[0].RealmID)
+	assert.Equal(t, "target", *bundle.Authorizations[0].TargetRealmID)
+	assert.Equal(t, "/", *bundle.Authorizations[1].TargetRealmID)
+}
+
+func mockBundleContextKeys(sqlRows *mock.SQLRows, items []BundleContextKey) {
+	for _, item := range items {
+		var bytes, _ = json.Marshal(item.Config)
+		sqlRows.EXPECT().Next().Return(true)
+		sqlRows.EXPECT().Scan(gomock.Any()).DoAndReturn(func(dest ...any) error {
+			*(dest[0]).(*string) = item.ID
+			*(dest[1]).(*string) = item.Label
+			*(dest[2]).(*string) = item.IdentitiesRealm
+			*(dest[3]).(*string) = item.CustomerRealm
+			*(dest[4]).(*string) = string(bytes)
+			*(dest[5]).(*bool) = item.IsRegisterDefault
+			return nil
+		})
+	}
+	sqlRows.EXPECT().Next().Return(false)
+	sqlRows.EXPECT().Err()
+	sqlRows.EXPECT().Close()
+}
+
+func TestExportRealm(t *testing.T) {
+	var mocks = newDbMocks(t)
+	defer mocks.finish()
+
+	var bundle = createTestBundle()
+	var ctx = context.TODO()
+	var sqlError = errors.New("SQL error")
+	var module = NewRealmBundleDBModule(mocks.db, mocks.logger)
+
+	var mockConfigurations = func() {
+		mocks.db.EXPECT().QueryRow(selectRealmConfigsStmt, bundle.RealmID).Return(mocks.sqlRow)
+		mocks.sqlRow.EXPECT().Scan(gomock.Any()).DoAndReturn(func(dest ...any) error {
+			*(dest[0]).(*sql.NullString) = sql.NullString{String: `{"default_client_id":"client","barcode_type":null}`, Valid: true}
+			*(dest[1]).(*sql.NullString) = sql.NullString{String: `{"mode":"trustID"}`, Valid: true}
+			return nil
+		})
+	}
+
+	t.Run("Can't get configurations", func(t *testing.T) {
+		mocks.db.EXPECT().QueryRow(selectRealmConfigsStmt, bundle.RealmID).Return(mocks.sqlRow)
+		mocks.sqlRow.EXPECT().Scan(gomock.Any()).Return(sqlError)
+		var _, err = module.ExportRealm(ctx, bundle.RealmID, bundle.RealmName)
+		assert.Equal(t, sqlError, err)
+	})
+	t.Run("Can't get context keys", func(t *testing.T) {
+		mockConfigurations()
+		mocks.db.EXPECT().Query(selectContextKeyConfig, nil, bundle.RealmName).Return(nil, sqlError)
+		var _, err = module.ExportRealm(ctx, bundle.RealmID, bundle.RealmName)
+		assert.Equal(t, sqlError, err)
+	})
+	t.Run("Can't get authorizations", func(t *testing.T) {
+		mockConfigurations()
+		mocks.db.EXPECT().Query(selectContextKeyConfig, nil, bundle.RealmName).Return(mocks.sqlRows, nil)
+		mockBundleContextKeys(mocks.sqlRows, bundle.ContextKeys)
+		mocks.db.EXPECT().Query(selectRealmAuthzStmt, bundle.RealmName).Return(nil, sqlError)
+		var _, err = module.ExportRealm(ctx, bundle.RealmID, bundle.RealmName)
+		assert.Equal(t, sqlError, err)
+	})
+	t.Run("Success", func(t *testing.T) {
+		mockConfigurations()
+		mocks.db.EXPECT().Query(selectContextKeyConfig, nil, bundle.RealmName).Return(mocks.sqlRows, nil)
+		mockBundleContextKeys(mocks.sqlRows, bundle.ContextKeys)
+		mocks.db.EXPECT().Query(selectRealmAuthzStmt, bundle.RealmName).Return(mocks.sqlRows, nil)
+		mockAuthorizationRows(mocks.sqlRows, bundle.Authorizations)
+		var res, err = module.ExportRealm(ctx, bundle.RealmID, bundle.RealmName)
+		assert.Nil(t, err)
+		assert.Equal(t, RealmBundleVersion, res.Version)
+		assert.Equal(t, bundle.Configuration, res.Configuration)
+		assert.Equal(t, bundle.AdminConfiguration, res.AdminConfiguration)
+		assert.Equal(t, bundle.ContextKeys, res.ContextKeys)
+		assert.Len(t, res.Authorizations, 2)
+	})
+}
+
+func TestImportRealm(t *testing.T) {
+	var mocks = newDbMocks(t)
+	defer mocks.finish()
+
+	var tx = mock.NewTransaction(mocks.mockCtrl)
+	var bundle = createTestBundle()
+	var ctx = context.TODO()
+	var sqlError = errors.New("SQL error")
+	var module = NewRealmBundleDBModule(mocks.db, mocks.logger, []string{"GetUsers", "GetRealm"})
+
+	var mockCurrentState = func(realmID, realmName string, ctxKeys []BundleContextKey, authorizations []Authorization) {
+		tx.EXPECT().QueryRow(selectRealmConfigsStmt, realmID).Return(mocks.sqlRow)
+		mocks.sqlRow.EXPECT().Scan(gomock.Any()).Return(sql.ErrNoRows)
+		tx.EXPECT().Query(selectContextKeyConfig, nil, realmName).Return(mocks.sqlRows, nil)
+		mockBundleContextKeys(mocks.sqlRows, ctxKeys)
+		tx.EXPECT().Query(selectRealmAuthzStmt, realmName).Return(mocks.sqlRows, nil)
+		mockAuthorizationRows(mocks.sqlRows, authorizations)
+	}
+
+	t.Run("Invalid bundle", func(t *testing.T) {
+		var _, err = module.ImportRealm(ctx, RealmBundle{}, RealmImportOptions{})
+		assert.NotNil(t, err)
+	})
+	t.Run("Unknown action", func(t *testing.T) {
+		var invalid = createTestBundle()
+		invalid.Authorizations[0].Action = ptr("Unknown")
+		var _, err = module.ImportRealm(ctx, invalid, RealmImportOptions{})
+		assert.NotNil(t, err)
+	})
+	t.Run("Can't start transaction", func(t *testing.T) {
+		mocks.db.EXPECT().BeginTx(ctx, nil).Return(nil, sqlError)
+		var _, err = module.ImportRealm(ctx, bundle, RealmImportOptions{})
+		assert.Equal(t, sqlError, err)
+	})
+	t.Run("Can't store configuration", func(t *testing.T) {
+		mocks.db.EXPECT().BeginTx(ctx, nil).Return(tx, nil)
+		tx.EXPECT().QueryRow(selectRealmConfigsStmt, bundle.RealmID).Return(mocks.sqlRow)
+		mocks.sqlRow.EXPECT().Scan(gomock.Any()).Return(sql.ErrNoRows)
+		tx.EXPECT().Exec(upsertRealmConfigsStmt, gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, sqlError)
+		tx.EXPECT().Close()
+		var _, err = module.ImportRealm(ctx, bundle, RealmImportOptions{})
+		assert.Equal(t, sqlError, err)
+	})
+	t.Run("Dry run", func(t *testing.T) {
+		var existingKey = BundleContextKey{ID: "key-2", CustomerRealm: "target"}
+		var existingAuthz = Authorization{RealmID: ptr("target"), GroupName: ptr("admins"), Action: ptr("GetRealm"), TargetRealmID: ptr("/")}
+		mocks.db.EXPECT().BeginTx(ctx, nil).Return(tx, nil)
+		mockCurrentState("target-id", "target", []BundleContextKey{existingKey}, []Authorization{existingAuthz})
+		tx.EXPECT().Exec(upsertRealmConfigsStmt, "target-id", gomock.Any(), gomock.Any()).Return(nil, nil)
+		tx.EXPECT().QueryRow(selectContextKeyRealmStmt, "key-1").Return(mocks.sqlRow)
+		mocks.sqlRow.EXPECT().Scan(gomock.Any()).Return(sql.ErrNoRows)
+		tx.EXPECT().Exec(insertContextKeyStmt, "key-1", "label", "identities", "target", gomock.Any(), false).Return(nil, nil)
+		tx.EXPECT().Exec(deleteContextKeyStmt, "key-2", "target").Return(nil, nil)
+		tx.EXPECT().Exec(insertAuthzStmt, ptr("target"), ptr("admins"), ptr("GetUsers"), ptr("target"), ptr("*"), false, nil).Return(nil, nil)
+		tx.EXPECT().Close()
+
+		var report, err = module.ImportRealm(ctx, bundle, RealmImportOptions{TargetRealmID: "target-id", TargetRealmName: "target", DryRun: true})
+		assert.Nil(t, err)
+		assert.True(t, report.DryRun)
+		assert.True(t, report.ConfigurationChanged)
+		assert.True(t, report.AdminConfigurationChanged)
+		assert.Equal(t, []string{"key-1"}, report.ContextKeysAdded)
+		assert.Equal(t, []string{"key-2"}, report.ContextKeysRemoved)
+		assert.Len(t, report.Authorizations.Added, 1)
+		assert.Len(t, report.Authorizations.Removed, 0)
+	})
+	t.Run("Context key of another realm", func(t *testing.T) {
+		// Bundle imported in the same environment under another realm name: key-1 still belongs to the source realm
+		mocks.db.EXPECT().BeginTx(ctx, nil).Return(tx, nil)
+		tx.EXPECT().QueryRow(selectRealmConfigsStmt, "target-id").Return(mocks.sqlRow)
+		mocks.sqlRow.EXPECT().Scan(gomock.Any()).Return(sql.ErrNoRows)
+		tx.EXPECT().Exec(upsertRealmConfigsStmt, "target-id", gomock.Any(), gomock.Any()).Return(nil, nil)
+		tx.EXPECT().Query(selectContextKeyConfig, nil, "target").Return(mocks.sqlRows, nil)
+		mockBundleContextKeys(mocks.sqlRows, nil)
+		tx.EXPECT().QueryRow(selectContextKeyRealmStmt, "key-1").Return(mocks.sqlRow)
+		mocks.sqlRow.EXPECT().Scan(gomock.Any()).DoAndReturn(func(dest ...any) error {
+			*(dest[0]).(*string) = bundle.RealmName
+			return nil
+		})
+		tx.EXPECT().Close()
+
+		var report, err = module.ImportRealm(ctx, bundle, RealmImportOptions{TargetRealmID: "target-id", TargetRealmName: "target"})
+		assert.NotNil(t, err)
+		assert.Nil(t, report.ContextKeysAdded)
+	})
+	t.Run("Update context key of the realm", func(t *testing.T) {
+		var noConfBundle = createTestBundle()
+		noConfBundle.Configuration = nil
+		noConfBundle.AdminConfiguration = nil
+		noConfBundle.Authorizations = nil
+		var existingKey = noConfBundle.ContextKeys[0]
+		existingKey.Label = "previous label"
+		mocks.db.EXPECT().BeginTx(ctx, nil).Return(tx, nil)
+		tx.EXPECT().Query(selectContextKeyConfig, nil, noConfBundle.RealmName).Return(mocks.sqlRows, nil)
+		mockBundleContextKeys(mocks.sqlRows, []BundleContextKey{existingKey})
+		tx.EXPECT().Exec(updateContextKeyStmt, "label", "identities", gomock.Any(), false, "key-1", noConfBundle.RealmName).Return(nil, nil)
+		tx.EXPECT().Query(selectRealmAuthzStmt, noConfBundle.RealmName).Return(mocks.sqlRows, nil)
+		mockAuthorizationRows(mocks.sqlRows, nil)
+		tx.EXPECT().Commit()
+		tx.EXPECT().Close()
+
+		var report, err = module.ImportRealm(ctx, noConfBundle, RealmImportOptions{})
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"key-1"}, report.ContextKeysUpdated)
+	})
+	t.Run("Commit fails", func(t *testing.T) {
+		var noConfBundle = createTestBundle()
+		noConfBundle.Configuration = nil
+		noConfBundle.AdminConfiguration = nil
+		noConfBundle.ContextKeys = nil
+		noConfBundle.Authorizations = nil
+		mocks.db.EXPECT().BeginTx(ctx, nil).Return(tx, nil)
+		tx.EXPECT().Query(selectContextKeyConfig, nil, noConfBundle.RealmName).Return(mocks.sqlRows, nil)
+		mockBundleContextKeys(mocks.sqlRows, nil)
+		tx.EXPECT().Query(selectRealmAuthzStmt, noConfBundle.RealmName).Return(mocks.sqlRows, nil)
+		mockAuthorizationRows(mocks.sqlRows, nil)
+		tx.EXPECT().Commit().Return(sqlError)
+		tx.EXPECT().Close()
+		var _, err = module.ImportRealm(ctx, noConfBundle, RealmImportOptions{})
+		assert.Equal(t, sqlError, err)
+	})
+	t.Run("Success", func(t *testing.T) {
+		mocks.db.EXPECT().BeginTx(ctx, nil).Return(tx, nil)
+		mockCurrentState(bundle.RealmID, bundle.RealmName, nil, nil)
+		tx.EXPECT().Exec(upsertRealmConfigsStmt, bundle.RealmID, gomock.Any(), gomock.Any()).Return(nil, nil)
+		tx.EXPECT().QueryRow(selectContextKeyRealmStmt, "key-1").Return(mocks.sqlRow)
+		mocks.sqlRow.EXPECT().Scan(gomock.Any()).Return(sql.ErrNoRows)
+		tx.EXPECT().Exec(insertContextKeyStmt, "key-1", "label", "identities", bundle.RealmName, gomock.Any(), false).Return(nil, nil)
+		tx.EXPECT().Exec(insertAuthzStmt, gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil).Times(2)
+		tx.EXPECT().Commit()
+		tx.EXPECT().Close()
+		var report, err = module.ImportRealm(ctx, bundle, RealmImportOptions{})
+		assert.Nil(t, err)
+		assert.False(t, report.DryRun)
+		assert.Len(t, report.Authorizations.Added, 2)
+	})
+}
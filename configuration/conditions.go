@@ -0,0 +1,110 @@
+package configuration
+
+import (
+	"encoding/json"
+	"net/netip"
+	"slices"
+	"strings"
+	"time"
+
+	cerrors "github.com/cloudtrust/common-service/v2/errors"
+)
+
+const timeOfDayLayout = "15:04"
+
+var weekDays = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+
+// Validate checks the conditions of an authorization
+func (c *AuthorizationConditions) Validate() error {
+	for _, window := range c.TimeWindows {
+		if err := window.Validate(); err != nil {
+			return err
+		}
+	}
+	if _, err := c.ParseSourceCIDRs(); err != nil {
+		return err
+	}
+	for claim := range c.RequiredClaims {
+		if claim == "" {
+			return cerrors.CreateBadRequestError(cerrors.MsgErrInvalidParam + ".conditions.required_claims")
+		}
+	}
+	return nil
+}
+
+// ParseSourceCIDRs parses the source CIDR ranges. A single IP address is accepted as a range of one address
+func (c *AuthorizationConditions) ParseSourceCIDRs() ([]netip.Prefix, error) {
+	var res []netip.Prefix
+	for _, cidr := range c.SourceCIDRs {
+		var prefix, err = netip.ParsePrefix(cidr)
+		if err != nil {
+			var addr, errAddr = netip.ParseAddr(cidr)
+			if errAddr != nil {
+				return nil, cerrors.CreateBadRequestError(cerrors.MsgErrInvalidParam + ".conditions.source_cidrs")
+			}
+			prefix = netip.PrefixFrom(addr, addr.BitLen())
+		}
+		res = append(res, prefix.Masked())
+	}
+	return res, nil
+}
+
+// Validate checks a time window
+func (w TimeWindow) Validate() error {
+	if _, err := time.Parse(timeOfDayLayout, w.Start); err != nil {
+		return cerrors.CreateBadRequestError(cerrors.MsgErrInvalidParam + ".conditions.time_windows.start")
+	}
+	if _, err := time.Parse(timeOfDayLayout, w.End); err != nil {
+		return cerrors.CreateBadRequestError(cerrors.MsgErrInvalidParam + ".conditions.time_windows.end")
+	}
+	for _, day := range w.Days {
+		if !slices.Contains(weekDays, strings.ToLower(day)) {
+			return cerrors.CreateBadRequestError(cerrors.MsgErrInvalidParam + ".conditions.time_windows.days")
+		}
+	}
+	if _, err := time.LoadLocation(w.Location); err != nil {
+		return cerrors.CreateBadRequestError(cerrors.MsgErrInvalidParam + ".conditions.time_windows.location")
+	}
+	return nil
+}
+
+// Includes tells whether the given time is in the time window. An invalid time window includes no time
+func (w TimeWindow) Includes(t time.Time) bool {
+	var location, err = time.LoadLocation(w.Location)
+	if err != nil {
+		return false
+	}
+	start, errStart := time.Parse(timeOfDayLayout, w.Start)
+	end, errEnd := time.Parse(timeOfDayLayout, w.End)
+	if errStart != nil || errEnd != nil {
+		return false
+	}
+
+	t = t.In(location)
+	var minutes = t.Hour()*60 + t.Minute()
+	var startMinutes, endMinutes = start.Hour()*60 + start.Minute(), end.Hour()*60 + end.Minute()
+	var day = t.Weekday()
+	if startMinutes <= endMinutes {
+		if minutes < startMinutes || minutes >= endMinutes {
+			return false
+		}
+	} else if minutes < endMinutes {
+		// Window spanning midnight: the window started the day before
+		day = (day + 6) % 7
+	} else if minutes < startMinutes {
+		return false
+	}
+	return len(w.Days) == 0 || slices.ContainsFunc(w.Days, func(d string) bool {
+		return strings.EqualFold(d, weekDays[day])
+	})
+}
+
+// conditionsValue returns the value stored in database for the conditions of an authorization
+func conditionsValue(conditions *AuthorizationConditions) *string {
+	if conditions == nil {
+		return nil
+	}
+	var bytes, _ = json.Marshal(conditions)
+	var value = string(bytes)
+	return &value
+}
@@ -0,0 +1,71 @@
+package configuration
+
+import (
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuthorizationConditionsValidate(t *testing.T) {
+	var officeHours = TimeWindow{Days: []string{"mon", "TUE"}, Start: "08:00", End: "18:00", Location: "Europe/Zurich"}
+
+	assert.Nil(t, (&AuthorizationConditions{}).Validate())
+	assert.Nil(t, (&AuthorizationConditions{
+		TimeWindows:    []TimeWindow{officeHours},
+		SourceCIDRs:    []string{"10.0.0.0/8", "192.168.1.12", "fd00::/8"},
+		RequiredClaims: map[string]string{"acr": "2"},
+		RequiredRoles:  []string{"support"},
+	}).Validate())
+
+	for _, window := range []TimeWindow{
+		{Start: "8h", End: "18:00"},
+		{Start: "08:00", End: "25:00"},
+		{Start: "08:00", End: "18:00", Days: []string{"monday"}},
+		{Start: "08:00", End: "18:00", Location: "Nowhere/Unknown"},
+	} {
+		assert.NotNil(t, (&AuthorizationConditions{TimeWindows: []TimeWindow{window}}).Validate(), window)
+	}
+	assert.NotNil(t, (&AuthorizationConditions{SourceCIDRs: []string{"10.0.0.0/33"}}).Validate())
+	assert.NotNil(t, (&AuthorizationConditions{RequiredClaims: map[string]string{"": "value"}}).Validate())
+}
+
+func TestParseSourceCIDRs(t *testing.T) {
+	var prefixes, err = (&AuthorizationConditions{SourceCIDRs: []string{"10.1.2.3/8", "192.168.1.12"}}).ParseSourceCIDRs()
+	assert.Nil(t, err)
+	assert.Equal(t, []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8"), netip.MustParsePrefix("192.168.1.12/32")}, prefixes)
+}
+
+func TestTimeWindowIncludes(t *testing.T) {
+	// 2026-10-19 is a monday
+	var monday = func(hour, minute int) time.Time {
+		return time.Date(2026, time.October, 19, hour, minute, 0, 0, time.UTC)
+	}
+
+	t.Run("Daily window", func(t *testing.T) {
+		var window = TimeWindow{Days: []string{"mon"}, Start: "08:00", End: "18:00"}
+		assert.False(t, window.Includes(monday(7, 59)))
+		assert.True(t, window.Includes(monday(8, 0)))
+		assert.True(t, window.Includes(monday(17, 59)))
+		assert.False(t, window.Includes(monday(18, 0)))
+		assert.False(t, window.Includes(monday(12, 0).AddDate(0, 0, 1)))
+	})
+	t.Run("Location", func(t *testing.T) {
+		var window = TimeWindow{Start: "08:00", End: "18:00", Location: "Europe/Zurich"}
+		// 07:30 UTC is 09:30 in Zurich (summer time)
+		assert.True(t, window.Includes(monday(7, 30)))
+		assert.False(t, window.Includes(monday(16, 30)))
+	})
+	t.Run("Window spanning midnight", func(t *testing.T) {
+		var window = TimeWindow{Days: []string{"mon"}, Start: "22:00", End: "06:00"}
+		assert.True(t, window.Includes(monday(23, 0)))
+		assert.False(t, window.Includes(monday(5, 0)))
+		assert.True(t, window.Includes(monday(5, 0).AddDate(0, 0, 1)))
+		assert.False(t, window.Includes(monday(12, 0)))
+	})
+	t.Run("Invalid window", func(t *testing.T) {
+		assert.False(t, TimeWindow{Start: "invalid", End: "18:00"}.Includes(monday(12, 0)))
+		assert.False(t, TimeWindow{Start: "08:00", End: "18:00", Location: "Nowhere/Unknown"}.Includes(monday(12, 0)))
+	})
+}
@@ -48,47 +48,73 @@ type RealmConfiguration struct {
 
 // RealmAdminConfiguration struct
 type RealmAdminConfiguration struct {
-	Mode                                           *string         `json:"mode"`
-	AvailableChecks                                map[string]bool `json:"available-checks,omitempty"`
-	SelfRegisterEnabled                            *bool           `json:"self_register_enabled"`
-	RegisterTheme                                  *string         `json:"register_theme,omitempty"`
-	SseTheme                                       *string         `json:"sse_theme,omitempty"`
-	BoTheme                                        *string         `json:"bo_theme,omitempty"`
-	SignerTheme                                    *string         `json:"signer_theme,omitempty"`
-	NeedVerifiedContact                            *bool           `json:"need_verified_contact,omitempty"`
-	NeedVerifiedContactAuxiliary                   *bool           `json:"need_verified_contact_auxiliary,omitempty"`
-	ConsentRequiredSocial                          *bool           `json:"consent_required,omitempty"`
-	ConsentRequiredCorporate                       *bool           `json:"consent_required_corp,omitempty"`
-	ConsentRequiredCorporateAuxiliary              *bool           `json:"consent_required_corporate_auxiliary,omitempty"`
-	AccreditationRenewalWindowDays                 *int            `json:"accreditation_renewal_window_days"`
-	VideoIdentificationVoucherEnabled              *bool           `json:"video_identification_voucher_enabled"`
-	VideoIdentificationAccountingEnabled           *bool           `json:"video_identification_accounting_enabled"`
-	VideoIdentificationPrepaymentRequired          *bool           `json:"video_identification_prepayment_required"`
-	AuxiliaryVideoIdentificationVoucherEnabled     *bool           `json:"auxiliary_video_identification_voucher_enabled"`
-	AuxiliaryVideoIdentificationAccountingEnabled  *bool           `json:"auxiliary_video_identification_accounting_enabled"`
-	AuxiliaryVideoIdentificationPrepaymentRequired *bool           `json:"auxiliary_video_identification_prepayment_required"`
-	AutoIdentificationVoucherEnabled               *bool           `json:"auto_identification_voucher_enabled"`
-	AutoIdentificationAccountingEnabled            *bool           `json:"auto_identification_accounting_enabled"`
-	AutoIdentificationPrepaymentRequired           *bool           `json:"auto_identification_prepayment_required"`
-	VideoIdentificationAllowedRoles                []string        `json:"video_identification_allowed_roles,omitempty"`
-	AuxiliaryVideoIdentificationAllowedRoles       []string        `json:"auxiliary_video_identification_allowed_roles,omitempty"`
-	AutoIdentificationAllowedRoles                 []string        `json:"auto_identification_allowed_roles,omitempty"`
-	PhysicalIdentificationAllowedRoles             []string        `json:"physical_identification_allowed_roles,omitempty"`
-	AuxiliaryPhysicalIdentificationAllowedRoles    []string        `json:"auxiliary_physical_identification_allowed_roles,omitempty"`
-	OnboardingStatusEnabled                        *bool           `json:"onboarding_status_enabled"`
-	AutoGeneratedUsernameEnabled                   *bool           `json:"auto_generated_username_enabled"`
-	AutoGeneratedUsernameToggleEnabled             *bool           `json:"auto_generated_username_toggle_enabled"`
-	RegisterMode                                   *string         `json:"register_mode,omitempty"`
-	BOExternalIDPManagementEnabled                 *bool           `json:"bo_external_idp_management_enabled"`
-}
-
-// Authorization struct
+	Mode                                           *string             `json:"mode"`
+	AvailableChecks                                map[string]bool     `json:"available-checks,omitempty"`
+	SelfRegisterEnabled                            *bool               `json:"self_register_enabled"`
+	RegisterTheme                                  *string             `json:"register_theme,omitempty"`
+	SseTheme                                       *string             `json:"sse_theme,omitempty"`
+	BoTheme                                        *string             `json:"bo_theme,omitempty"`
+	SignerTheme                                    *string             `json:"signer_theme,omitempty"`
+	NeedVerifiedContact                            *bool               `json:"need_verified_contact,omitempty"`
+	NeedVerifiedContactAuxiliary                   *bool               `json:"need_verified_contact_auxiliary,omitempty"`
+	ConsentRequiredSocial                          *bool               `json:"consent_required,omitempty"`
+	ConsentRequiredCorporate                       *bool               `json:"consent_required_corp,omitempty"`
+	ConsentRequiredCorporateAuxiliary              *bool               `json:"consent_required_corporate_auxiliary,omitempty"`
+	AccreditationRenewalWindowDays                 *int                `json:"accreditation_renewal_window_days"`
+	VideoIdentificationVoucherEnabled              *bool               `json:"video_identification_voucher_enabled"`
+	VideoIdentificationAccountingEnabled           *bool               `json:"video_identification_accounting_enabled"`
+	VideoIdentificationPrepaymentRequired          *bool               `json:"video_identification_prepayment_required"`
+	AuxiliaryVideoIdentificationVoucherEnabled     *bool               `json:"auxiliary_video_identification_voucher_enabled"`
+	AuxiliaryVideoIdentificationAccountingEnabled  *bool               `json:"auxiliary_video_identification_accounting_enabled"`
+	AuxiliaryVideoIdentificationPrepaymentRequired *bool               `json:"auxiliary_video_identification_prepayment_required"`
+	AutoIdentificationVoucherEnabled               *bool               `json:"auto_identification_voucher_enabled"`
+	AutoIdentificationAccountingEnabled            *bool               `json:"auto_identification_accounting_enabled"`
+	AutoIdentificationPrepaymentRequired           *bool               `json:"auto_identification_prepayment_required"`
+	VideoIdentificationAllowedRoles                []string            `json:"video_identification_allowed_roles,omitempty"`
+	AuxiliaryVideoIdentificationAllowedRoles       []string            `json:"auxiliary_video_identification_allowed_roles,omitempty"`
+	AutoIdentificationAllowedRoles                 []string            `json:"auto_identification_allowed_roles,omitempty"`
+	PhysicalIdentificationAllowedRoles             []string            `json:"physical_identification_allowed_roles,omitempty"`
+	AuxiliaryPhysicalIdentificationAllowedRoles    []string            `json:"auxiliary_physical_identification_allowed_roles,omitempty"`
+	AllowedRoles                                   map[string][]string `json:"allowed_roles,omitempty"`
+	OnboardingStatusEnabled                        *bool               `json:"onboarding_status_enabled"`
+	AutoGeneratedUsernameEnabled                   *bool               `json:"auto_generated_username_enabled"`
+	AutoGeneratedUsernameToggleEnabled             *bool               `json:"auto_generated_username_toggle_enabled"`
+	RegisterMode                                   *string             `json:"register_mode,omitempty"`
+	BOExternalIDPManagementEnabled                 *bool               `json:"bo_external_idp_management_enabled"`
+}
+
+// Authorization struct. Deny entries forbid the action on the targets even if other entries allow it.
+// An authorization with conditions only applies when the request fulfills them
 type Authorization struct {
-	RealmID         *string `json:"realm_id"`
-	GroupName       *string `json:"group_id"`
-	Action          *string `json:"action"`
-	TargetRealmID   *string `json:"target_realm_id,omitempty"`
-	TargetGroupName *string `json:"target_group_name,omitempty"`
+	RealmID         *string                  `json:"realm_id"`
+	GroupName       *string                  `json:"group_id"`
+	Action          *string                  `json:"action"`
+	TargetRealmID   *string                  `json:"target_realm_id,omitempty"`
+	TargetGroupName *string                  `json:"target_group_name,omitempty"`
+	Deny            bool                     `json:"deny,omitempty"`
+	Conditions      *AuthorizationConditions `json:"conditions,omitempty"`
+}
+
+// AuthorizationConditions are the attribute based conditions of an authorization. All the given conditions must be fulfilled:
+//   - the request is received during one of the time windows
+//   - the client IP address is in one of the source CIDR ranges
+//   - the access token contains all the required claims with the given values
+//   - the caller has all the required roles
+type AuthorizationConditions struct {
+	TimeWindows    []TimeWindow      `json:"time_windows,omitempty"`
+	SourceCIDRs    []string          `json:"source_cidrs,omitempty"`
+	RequiredClaims map[string]string `json:"required_claims,omitempty"`
+	RequiredRoles  []string          `json:"required_roles,omitempty"`
+}
+
+// TimeWindow is a daily time window such as office hours. Start and End use the HH:MM format, End being excluded. A window
+// ending before its start spans midnight. Days are three letters english week days (mon, tue, ...), all days if empty.
+// Location is an IANA time zone, UTC if empty
+type TimeWindow struct {
+	Days     []string `json:"days,omitempty"`
+	Start    string   `json:"start"`
+	End      string   `json:"end"`
+	Location string   `json:"location,omitempty"`
 }
 
 // ThemeConfiguration struct
@@ -3,9 +3,12 @@ package configuration
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
 
 	"github.com/cloudtrust/common-service/v2/database/sqltypes"
 	"github.com/cloudtrust/common-service/v2/log"
+	"github.com/go-sql-driver/mysql"
 )
 
 const (
@@ -13,7 +16,11 @@ const (
 	selectConfigStmt       = `SELECT configuration FROM realm_configuration WHERE realm_id = ? AND configuration IS NOT NULL`
 	selectAdminConfigStmt  = `SELECT admin_configuration FROM realm_configuration WHERE realm_id = ? AND admin_configuration IS NOT NULL`
 	selectContextKeyConfig = `SELECT id, label, identities_realm, customer_realm, configuration, is_register_default FROM context_key_configuration WHERE id=IFNULL(?, id) AND customer_realm=IFNULL(?, customer_realm)`
-	selectAllAuthzStmt     = `SELECT realm_id, group_name, action, target_realm_id, target_group_name FROM authorizations;`
+	selectAllAuthzStmt     = `SELECT realm_id, group_name, action, target_realm_id, target_group_name, deny, conditions FROM authorizations;`
+	// selectAllLegacyAuthzStmt reads the authorizations of the schemas created before the deny and conditions columns
+	selectAllLegacyAuthzStmt = `SELECT realm_id, group_name, action, target_realm_id, target_group_name, FALSE, NULL FROM authorizations;`
+
+	mysqlErrUnknownColumn = 1054
 )
 
 // ConfigurationReaderDBModule struct
@@ -151,7 +158,7 @@ func (c *ConfigurationReaderDBModule) GetContextKey(ctx context.Context, ctxKeyI
 
 func (c *ConfigurationReaderDBModule) getSingleContextKey(ctx context.Context, ctxKeyID *string, customerRealm *string) (RealmContextKey, error) {
 	row := c.db.QueryRow(selectContextKeyConfig, ctxKeyID, customerRealm)
-	ctxKeyConf, err := c.scanContextKeyConfiguration(row)
+	ctxKeyConf, err := scanContextKeyConfiguration(row)
 	if err != nil {
 		c.logger.Warn(ctx, "msg", "Can't get context key configuration", "realm", customerRealm, "err", err.Error())
 		return RealmContextKey{}, err
@@ -173,7 +180,7 @@ func (c *ConfigurationReaderDBModule) getMultipleContextKeys(ctx context.Context
 
 	var res []RealmContextKey
 	for rows.Next() {
-		ctxKeyConf, err := c.scanContextKeyConfiguration(rows)
+		ctxKeyConf, err := scanContextKeyConfiguration(rows)
 		if err != nil {
 			c.logger.Warn(ctx, "msg", "Can't get context key configuration. Scan failed", "realm", customerRealm, "err", err.Error())
 			return nil, err
@@ -188,7 +195,7 @@ func (c *ConfigurationReaderDBModule) getMultipleContextKeys(ctx context.Context
 	return res, nil
 }
 
-func (c *ConfigurationReaderDBModule) scanContextKeyConfiguration(scanner sqltypes.SQLRow) (RealmContextKey, error) {
+func scanContextKeyConfiguration(scanner sqltypes.SQLRow) (RealmContextKey, error) {
 	var (
 		id                string
 		label             string
@@ -218,10 +225,17 @@ func (c *ConfigurationReaderDBModule) scanContextKeyConfiguration(scanner sqltyp
 	}, nil
 }
 
-// GetAuthorizations returns authorizations
+// GetAuthorizations returns authorizations.
+// Schemas without the deny and conditions columns are still read, all their authorizations being unconditional allow entries.
+// Deny entries and conditions need the columns to be added:
+//
+//	ALTER TABLE authorizations ADD COLUMN deny BOOLEAN NOT NULL DEFAULT FALSE, ADD COLUMN conditions TEXT NULL;
 func (c *ConfigurationReaderDBModule) GetAuthorizations(ctx context.Context) ([]Authorization, error) {
 	// Get Authorizations from DB
 	rows, err := c.db.Query(selectAllAuthzStmt)
+	if err != nil && isUnknownColumnError(err) {
+		rows, err = c.db.Query(selectAllLegacyAuthzStmt)
+	}
 	if err != nil {
 		c.logger.Warn(ctx, "msg", "Can't get authorizations", "err", err.Error())
 		return nil, err
@@ -231,7 +245,7 @@ func (c *ConfigurationReaderDBModule) GetAuthorizations(ctx context.Context) ([]
 	var authz Authorization
 	var res = make([]Authorization, 0)
 	for rows.Next() {
-		authz, err = c.scanAuthorization(rows)
+		authz, err = scanAuthorization(rows)
 		if err != nil {
 			c.logger.Warn(ctx, "msg", "Can't get authorizations. Scan failed", "err", err.Error())
 			return nil, err
@@ -248,24 +262,39 @@ func (c *ConfigurationReaderDBModule) GetAuthorizations(ctx context.Context) ([]
 	return res, nil
 }
 
-func (c *ConfigurationReaderDBModule) scanAuthorization(scanner sqltypes.SQLRow) (Authorization, error) {
+// isUnknownColumnError tells whether err is the MySQL error 1054 raised when a query uses a column which does not exist
+func isUnknownColumnError(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlErrUnknownColumn
+}
+
+func scanAuthorization(scanner sqltypes.SQLRow) (Authorization, error) {
+	var authz, _, err = scanStoredAuthorization(scanner)
+	return authz, err
+}
+
+// scanStoredAuthorization scans an authorization and also returns its conditions as stored in the database
+func scanStoredAuthorization(scanner sqltypes.SQLRow) (Authorization, *string, error) {
 	var (
 		realmID         string
 		groupName       string
 		action          string
 		targetGroupName sql.NullString
 		targetRealmID   sql.NullString
+		deny            bool
+		conditions      sql.NullString
 	)
 
-	err := scanner.Scan(&realmID, &groupName, &action, &targetRealmID, &targetGroupName)
+	err := scanner.Scan(&realmID, &groupName, &action, &targetRealmID, &targetGroupName, &deny, &conditions)
 	if err != nil {
-		return Authorization{}, err
+		return Authorization{}, nil, err
 	}
 
 	var authz = Authorization{
 		RealmID:   &realmID,
 		GroupName: &groupName,
 		Action:    &action,
+		Deny:      deny,
 	}
 
 	if targetRealmID.Valid {
@@ -276,7 +305,13 @@ func (c *ConfigurationReaderDBModule) scanAuthorization(scanner sqltypes.SQLRow)
 		authz.TargetGroupName = &targetGroupName.String
 	}
 
-	return authz, nil
+	if !conditions.Valid {
+		return authz, nil, nil
+	}
+	if err = json.Unmarshal([]byte(conditions.String), &authz.Conditions); err != nil {
+		return Authorization{}, nil, err
+	}
+	return authz, &conditions.String, nil
 }
 
 func (c *ConfigurationReaderDBModule) isInAuthorizationScope(action string) bool {
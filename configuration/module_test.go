@@ -5,10 +5,12 @@ import (
 	"database/sql"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"testing"
 
 	"github.com/cloudtrust/common-service/v2/configuration/mock"
 	"github.com/cloudtrust/common-service/v2/log"
+	"github.com/go-sql-driver/mysql"
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/mock/gomock"
 )
@@ -350,6 +352,17 @@ func TestGetAuthorizations(t *testing.T) {
 		assert.Equal(t, sqlError, err)
 	})
 
+	t.Run("Schema without deny and conditions columns", func(t *testing.T) {
+		var columnError = &mysql.MySQLError{Number: 1054, Message: "Unknown column 'deny' in 'field list'"}
+		mocks.db.EXPECT().Query(selectAllAuthzStmt).Return(nil, columnError)
+		mocks.db.EXPECT().Query(selectAllLegacyAuthzStmt).Return(mocks.sqlRows, nil)
+		mockAuthorizationRows(mocks.sqlRows, []Authorization{{RealmID: ptr("realm"), GroupName: ptr("group"), Action: &allowedAction}})
+
+		var res, err = module.GetAuthorizations(ctx)
+		assert.Nil(t, err)
+		assert.Len(t, res, 1)
+	})
+
 	// Now, query will always be successful
 	mocks.db.EXPECT().Query(gomock.Any()).Return(mocks.sqlRows, nil).AnyTimes()
 	mocks.sqlRows.EXPECT().Close().AnyTimes()
@@ -390,6 +403,8 @@ func TestGetAuthorizations(t *testing.T) {
 				*(dest[2]).(*string) = allowedAction
 				*(dest[3]).(*sql.NullString) = sql.NullString{Valid: true, String: "targetRealm"}
 				*(dest[4]).(*sql.NullString) = sql.NullString{Valid: true, String: "targetGroup"}
+				*(dest[5]).(*bool) = true
+				*(dest[6]).(*sql.NullString) = sql.NullString{Valid: true, String: `{"source_cidrs":["10.0.0.0/8"]}`}
 				return nil
 			}),
 			mocks.sqlRows.EXPECT().Next().Return(false),
@@ -400,6 +415,8 @@ func TestGetAuthorizations(t *testing.T) {
 		assert.Nil(t, err)
 		assert.Len(t, res, 1)
 		assert.Equal(t, allowedAction, *res[0].Action)
+		assert.True(t, res[0].Deny)
+		assert.Equal(t, []string{"10.0.0.0/8"}, res[0].Conditions.SourceCIDRs)
 	})
 }
 
@@ -413,3 +430,10 @@ func TestIsInScope(t *testing.T) {
 	assert.True(t, module.isInAuthorizationScope("auth2"))
 	assert.True(t, module.isInAuthorizationScope("auth3"))
 }
+
+func TestIsUnknownColumnError(t *testing.T) {
+	assert.True(t, isUnknownColumnError(&mysql.MySQLError{Number: 1054, Message: "Unknown column 'deny' in 'field list'"}))
+	assert.True(t, isUnknownColumnError(fmt.Errorf("query failed: %w", &mysql.MySQLError{Number: 1054})))
+	assert.False(t, isUnknownColumnError(&mysql.MySQLError{Number: 1146, Message: "Table 'authorizations' doesn't exist"}))
+	assert.False(t, isUnknownColumnError(errors.New("Error 1054 (42S22): Unknown column 'deny' in 'field list'")))
+}
@@ -0,0 +1,265 @@
+package configuration
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"regexp"
+	"strings"
+
+	"github.com/cloudtrust/common-service/v2/database/sqltypes"
+	cerrors "github.com/cloudtrust/common-service/v2/errors"
+	"github.com/cloudtrust/common-service/v2/log"
+	"github.com/cloudtrust/common-service/v2/validation"
+)
+
+const (
+	selectThemeETagStmt   = `SELECT etag FROM theme_configuration WHERE realm_name = ?`
+	selectThemeConfigStmt = `SELECT settings, translations, logo, favicon, etag FROM theme_configuration WHERE realm_name = ?`
+	upsertThemeConfigStmt = `INSERT INTO theme_configuration (realm_name, settings, translations, logo, favicon, etag) VALUES (?, ?, ?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE settings = VALUES(settings), translations = VALUES(translations), logo = VALUES(logo), favicon = VALUES(favicon), etag = VALUES(etag)`
+
+	regExpThemeColor       = `^#([0-9a-fA-F]{3}|[0-9a-fA-F]{6}|[0-9a-fA-F]{8})$`
+	regExpThemeFontFamily  = `^[a-zA-Z0-9 ,'"_-]+$`
+	regExpThemeMenuTheme   = `^[a-zA-Z0-9_-]+$`
+	regExpTranslationLang  = `^[a-z]{2}(-[A-Za-z]{2,4})?$`
+	regExpTranslationKey   = `^[a-zA-Z0-9_.-]+$`
+	maxThemeFontFamilyLen  = 255
+	maxThemeMenuThemeLen   = 50
+	maxTranslationKeyDepth = 5
+)
+
+// ThemeAssetLimits defines the constraints applied to the images of a theme configuration
+type ThemeAssetLimits struct {
+	MaxLogoSize         int
+	MaxFaviconSize      int
+	AllowedLogoTypes    []string
+	AllowedFaviconTypes []string
+}
+
+var (
+	// DefaultThemeAssetLimits are the limits used when validating a theme configuration without explicit limits
+	DefaultThemeAssetLimits = ThemeAssetLimits{
+		MaxLogoSize:         512 * 1024,
+		MaxFaviconSize:      64 * 1024,
+		AllowedLogoTypes:    []string{"image/png", "image/jpeg", "image/gif", "image/svg+xml"},
+		AllowedFaviconTypes: []string{"image/png", "image/gif", "image/svg+xml"},
+	}
+
+	translationLangRegExp = regexp.MustCompile(regExpTranslationLang)
+	translationKeyRegExp  = regexp.MustCompile(regExpTranslationKey)
+)
+
+// Validate validates a theme configuration using the default asset limits
+func (t ThemeConfiguration) Validate() error {
+	return t.ValidateWithLimits(DefaultThemeAssetLimits)
+}
+
+// ValidateWithLimits validates a theme configuration using the given asset limits
+func (t ThemeConfiguration) ValidateWithLimits(limits ThemeAssetLimits) error {
+	return validation.NewParameterValidator().
+		ValidateParameterImageMimeType("logo", t.Logo, limits.AllowedLogoTypes, false).
+		ValidateParameterFunc(func() error { return validateAssetSize("logo", t.Logo, limits.MaxLogoSize) }).
+		ValidateParameterImageMimeType("favicon", t.Favicon, limits.AllowedFaviconTypes, false).
+		ValidateParameterFunc(func() error { return validateAssetSize("favicon", t.Favicon, limits.MaxFaviconSize) }).
+		ValidateParameter("settings", t.Settings, false).
+		ValidateParameterOnlyStrings("translations", t.Translations, false).
+		ValidateParameterFunc(func() error { return validateTranslationKeys(t.Translations) }).
+		Status()
+}
+
+// Validate validates the theme settings
+func (s *ThemeConfigurationSettings) Validate() error {
+	return validation.NewParameterValidator().
+		ValidateParameterRegExp("settings.color", s.Color, regExpThemeColor, false).
+		ValidateParameterLength("settings.font_family", s.FontFamily, 1, maxThemeFontFamilyLen, false).
+		ValidateParameterRegExp("settings.font_family", s.FontFamily, regExpThemeFontFamily, false).
+		ValidateParameterLength("settings.menu_theme", s.MenuTheme, 1, maxThemeMenuThemeLen, false).
+		ValidateParameterRegExp("settings.menu_theme", s.MenuTheme, regExpThemeMenuTheme, false).
+		Status()
+}
+
+func validateAssetSize(prmName string, value []byte, maxSize int) error {
+	if maxSize > 0 && len(value) > maxSize {
+		return cerrors.CreateBadRequestError(cerrors.MsgErrInvalidLength + "." + prmName)
+	}
+	return nil
+}
+
+// validateTranslationKeys checks that first level keys are language codes and that nested keys are valid translation keys
+func validateTranslationKeys(translations map[string]any) error {
+	for lang, value := range translations {
+		if !translationLangRegExp.MatchString(lang) {
+			return cerrors.CreateBadRequestError(cerrors.MsgErrInvalidParam + ".translations")
+		}
+		if err := validateNestedTranslationKeys(value, 1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateNestedTranslationKeys(value any, depth int) error {
+	var nested, ok = value.(map[string]any)
+	if !ok {
+		return nil
+	}
+	if depth > maxTranslationKeyDepth {
+		return cerrors.CreateBadRequestError(cerrors.MsgErrInvalidParam + ".translations")
+	}
+	for key, nestedValue := range nested {
+		if !translationKeyRegExp.MatchString(key) {
+			return cerrors.CreateBadRequestError(cerrors.MsgErrInvalidParam + ".translations")
+		}
+		if err := validateNestedTranslationKeys(nestedValue, depth+1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ComputeETag computes the entity tag of a theme configuration
+func (t ThemeConfiguration) ComputeETag() (string, error) {
+	var bytes, err = json.Marshal(t)
+	if err != nil {
+		return "", err
+	}
+	var hash = sha256.Sum256(bytes)
+	return `"` + hex.EncodeToString(hash[:]) + `"`, nil
+}
+
+// MatchesETag tells if an If-None-Match header value matches the given entity tag
+func MatchesETag(ifNoneMatch string, etag string) bool {
+	if ifNoneMatch == "" || etag == "" {
+		return false
+	}
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// ThemeConfigurationDBModule struct
+type ThemeConfigurationDBModule struct {
+	db     sqltypes.CloudtrustDB
+	limits ThemeAssetLimits
+	logger log.Logger
+}
+
+// NewThemeConfigurationDBModule returns a ThemeConfigurationDBModule. If no limits are provided, DefaultThemeAssetLimits are used
+func NewThemeConfigurationDBModule(db sqltypes.CloudtrustDB, logger log.Logger, limits ...ThemeAssetLimits) *ThemeConfigurationDBModule {
+	var assetLimits = DefaultThemeAssetLimits
+	if len(limits) > 0 {
+		assetLimits = limits[0]
+	}
+	return &ThemeConfigurationDBModule{
+		db:     db,
+		limits: assetLimits,
+		logger: logger,
+	}
+}
+
+// GetThemeConfiguration returns the theme configuration of a realm and its entity tag
+func (m *ThemeConfigurationDBModule) GetThemeConfiguration(ctx context.Context, realmName string) (ThemeConfiguration, string, error) {
+	var (
+		settingsJSON     sql.NullString
+		translationsJSON sql.NullString
+		logo             []byte
+		favicon          []byte
+		etag             string
+	)
+	var row = m.db.QueryRow(selectThemeConfigStmt, realmName)
+	if err := row.Scan(&settingsJSON, &translationsJSON, &logo, &favicon, &etag); err != nil {
+		if err == sql.ErrNoRows {
+			m.logger.Warn(ctx, "msg", "Theme configuration not found in DB", "realm", realmName)
+		} else {
+			m.logger.Warn(ctx, "msg", "Can't get theme configuration", "realm", realmName, "err", err.Error())
+		}
+		return ThemeConfiguration{}, "", err
+	}
+
+	var conf = ThemeConfiguration{
+		RealmName: &realmName,
+		Logo:      logo,
+		Favicon:   favicon,
+	}
+	if settingsJSON.Valid {
+		if err := json.Unmarshal([]byte(settingsJSON.String), &conf.Settings); err != nil {
+			m.logger.Warn(ctx, "msg", "Can't unmarshal theme settings", "realm", realmName, "err", err.Error())
+			return ThemeConfiguration{}, "", err
+		}
+	}
+	if translationsJSON.Valid {
+		if err := json.Unmarshal([]byte(translationsJSON.String), &conf.Translations); err != nil {
+			m.logger.Warn(ctx, "msg", "Can't unmarshal theme translations", "realm", realmName, "err", err.Error())
+			return ThemeConfiguration{}, "", err
+		}
+	}
+
+	return conf, etag, nil
+}
+
+// GetThemeConfigurationIfNoneMatch returns the theme configuration of a realm only if its entity tag does not match ifNoneMatch.
+// When the entity tag matches, only the entity tag is loaded from the database and notModified is true
+func (m *ThemeConfigurationDBModule) GetThemeConfigurationIfNoneMatch(ctx context.Context, realmName string, ifNoneMatch string) (ThemeConfiguration, string, bool, error) {
+	if ifNoneMatch != "" {
+		var etag string
+		if err := m.db.QueryRow(selectThemeETagStmt, realmName).Scan(&etag); err != nil {
+			if err != sql.ErrNoRows {
+				m.logger.Warn(ctx, "msg", "Can't get theme configuration entity tag", "realm", realmName, "err", err.Error())
+			}
+			return ThemeConfiguration{}, "", false, err
+		}
+		if MatchesETag(ifNoneMatch, etag) {
+			return ThemeConfiguration{}, etag, true, nil
+		}
+	}
+
+	var conf, etag, err = m.GetThemeConfiguration(ctx, realmName)
+	return conf, etag, false, err
+}
+
+// UpdateThemeConfiguration validates and stores the theme configuration of a realm. It returns the new entity tag
+func (m *ThemeConfigurationDBModule) UpdateThemeConfiguration(ctx context.Context, realmName string, conf ThemeConfiguration) (string, error) {
+	conf.RealmName = &realmName
+	if err := conf.ValidateWithLimits(m.limits); err != nil {
+		m.logger.Info(ctx, "msg", "Invalid theme configuration", "realm", realmName, "err", err.Error())
+		return "", err
+	}
+
+	var etag, err = conf.ComputeETag()
+	if err != nil {
+		return "", err
+	}
+	var settingsJSON, translationsJSON *string
+	if conf.Settings != nil {
+		if settingsJSON, err = toJSONString(conf.Settings); err != nil {
+			return "", err
+		}
+	}
+	if conf.Translations != nil {
+		if translationsJSON, err = toJSONString(conf.Translations); err != nil {
+			return "", err
+		}
+	}
+
+	if _, err = m.db.Exec(upsertThemeConfigStmt, realmName, settingsJSON, translationsJSON, conf.Logo, conf.Favicon, etag); err != nil {
+		m.logger.Warn(ctx, "msg", "Can't store theme configuration", "realm", realmName, "err", err.Error())
+		return "", err
+	}
+	return etag, nil
+}
+
+func toJSONString(value any) (*string, error) {
+	var bytes, err = json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var res = string(bytes)
+	return &res, nil
+}
@@ -0,0 +1,224 @@
+package configuration
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+var (
+	pngImage = []byte{0x89, 0x50, 0x4E, 0x47}
+	jpgImage = []byte{0xFF, 0xD8, 0xFF, 0xE0}
+)
+
+func TestThemeConfigurationValidate(t *testing.T) {
+	var validConf = func() ThemeConfiguration {
+		return ThemeConfiguration{
+			Settings: &ThemeConfigurationSettings{
+				Color:      ptr("#12ab34"),
+				MenuTheme:  ptr("dark"),
+				FontFamily: ptr("'Open Sans', Arial"),
+			},
+			Translations: map[string]any{
+				"en":    map[string]any{"title": "Title", "menu": map[string]any{"home.label": "Home"}},
+				"de-CH": map[string]any{"title": "Titel"},
+			},
+			Logo:    pngImage,
+			Favicon: pngImage,
+		}
+	}
+
+	t.Run("Valid", func(t *testing.T) {
+		assert.Nil(t, validConf().Validate())
+	})
+	t.Run("Empty", func(t *testing.T) {
+		assert.Nil(t, ThemeConfiguration{}.Validate())
+	})
+	t.Run("Invalid logo type", func(t *testing.T) {
+		var conf = validConf()
+		conf.Logo = []byte{0x00, 0x01, 0x02}
+		assert.NotNil(t, conf.Validate())
+	})
+	t.Run("Favicon type not allowed", func(t *testing.T) {
+		var conf = validConf()
+		conf.Favicon = jpgImage
+		assert.NotNil(t, conf.Validate())
+	})
+	t.Run("Logo too large", func(t *testing.T) {
+		var conf = validConf()
+		assert.NotNil(t, conf.ValidateWithLimits(ThemeAssetLimits{MaxLogoSize: 2, AllowedLogoTypes: []string{"image/png"}, AllowedFaviconTypes: []string{"image/png"}}))
+	})
+	t.Run("Invalid color", func(t *testing.T) {
+		var conf = validConf()
+		conf.Settings.Color = ptr("red")
+		assert.NotNil(t, conf.Validate())
+	})
+	t.Run("Invalid font family", func(t *testing.T) {
+		var conf = validConf()
+		conf.Settings.FontFamily = ptr("Arial; }")
+		assert.NotNil(t, conf.Validate())
+	})
+	t.Run("Invalid menu theme", func(t *testing.T) {
+		var conf = validConf()
+		conf.Settings.MenuTheme = ptr("")
+		assert.NotNil(t, conf.Validate())
+	})
+	t.Run("Invalid translation language", func(t *testing.T) {
+		var conf = validConf()
+		conf.Translations["english"] = map[string]any{"title": "Title"}
+		assert.NotNil(t, conf.Validate())
+	})
+	t.Run("Invalid translation key", func(t *testing.T) {
+		var conf = validConf()
+		conf.Translations["fr"] = map[string]any{"<title>": "Titre"}
+		assert.NotNil(t, conf.Validate())
+	})
+	t.Run("Translation value is not a string", func(t *testing.T) {
+		var conf = validConf()
+		conf.Translations["fr"] = map[string]any{"title": 3}
+		assert.NotNil(t, conf.Validate())
+	})
+}
+
+func TestMatchesETag(t *testing.T) {
+	var etag = `"abc"`
+	assert.False(t, MatchesETag("", etag))
+	assert.False(t, MatchesETag(`"abc"`, ""))
+	assert.False(t, MatchesETag(`"def"`, etag))
+	assert.True(t, MatchesETag(`"abc"`, etag))
+	assert.True(t, MatchesETag(`W/"abc"`, etag))
+	assert.True(t, MatchesETag(`"def", "abc"`, etag))
+	assert.True(t, MatchesETag(`*`, etag))
+}
+
+func TestComputeETag(t *testing.T) {
+	var conf = ThemeConfiguration{RealmName: ptr("realm"), Logo: pngImage}
+	var etag1, err = conf.ComputeETag()
+	assert.Nil(t, err)
+
+	conf.Logo = jpgImage
+	etag2, err := conf.ComputeETag()
+	assert.Nil(t, err)
+	assert.NotEqual(t, etag1, etag2)
+}
+
+func TestThemeConfigurationDBModule(t *testing.T) {
+	var mocks = newDbMocks(t)
+	defer mocks.finish()
+
+	var realm = "realm"
+	var etag = `"etag"`
+	var ctx = context.TODO()
+	var sqlError = errors.New("SQL error")
+	var module = NewThemeConfigurationDBModule(mocks.db, mocks.logger)
+
+	var mockThemeRow = func(settings string, translations string) {
+		mocks.db.EXPECT().QueryRow(selectThemeConfigStmt, realm).Return(mocks.sqlRow)
+		mocks.sqlRow.EXPECT().Scan(gomock.Any()).DoAndReturn(func(dest ...any) error {
+			*(dest[0]).(*sql.NullString) = sql.NullString{String: settings, Valid: true}
+			*(dest[1]).(*sql.NullString) = sql.NullString{String: translations, Valid: true}
+			*(dest[2]).(*[]byte) = pngImage
+			*(dest[4]).(*string) = etag
+			return nil
+		})
+	}
+
+	t.Run("GetThemeConfiguration", func(t *testing.T) {
+		t.Run("Not found", func(t *testing.T) {
+			mocks.db.EXPECT().QueryRow(selectThemeConfigStmt, realm).Return(mocks.sqlRow)
+			mocks.sqlRow.EXPECT().Scan(gomock.Any()).Return(sql.ErrNoRows)
+			var _, _, err = module.GetThemeConfiguration(ctx, realm)
+			assert.Equal(t, sql.ErrNoRows, err)
+		})
+		t.Run("SQL error", func(t *testing.T) {
+			mocks.db.EXPECT().QueryRow(selectThemeConfigStmt, realm).Return(mocks.sqlRow)
+			mocks.sqlRow.EXPECT().Scan(gomock.Any()).Return(sqlError)
+			var _, _, err = module.GetThemeConfiguration(ctx, realm)
+			assert.Equal(t, sqlError, err)
+		})
+		t.Run("Invalid settings", func(t *testing.T) {
+			mockThemeRow(`{`, `{}`)
+			var _, _, err = module.GetThemeConfiguration(ctx, realm)
+			assert.NotNil(t, err)
+		})
+		t.Run("Invalid translations", func(t *testing.T) {
+			mockThemeRow(`{}`, `[`)
+			var _, _, err = module.GetThemeConfiguration(ctx, realm)
+			assert.NotNil(t, err)
+		})
+		t.Run("Success", func(t *testing.T) {
+			mockThemeRow(`{"color":"#fff"}`, `{"en":{"title":"Title"}}`)
+			var conf, resETag, err = module.GetThemeConfiguration(ctx, realm)
+			assert.Nil(t, err)
+			assert.Equal(t, etag, resETag)
+			assert.Equal(t, realm, *conf.RealmName)
+			assert.Equal(t, "#fff", *conf.Settings.Color)
+			assert.Equal(t, pngImage, conf.Logo)
+			assert.Nil(t, conf.Favicon)
+		})
+	})
+
+	t.Run("GetThemeConfigurationIfNoneMatch", func(t *testing.T) {
+		t.Run("Can't get entity tag", func(t *testing.T) {
+			mocks.db.EXPECT().QueryRow(selectThemeETagStmt, realm).Return(mocks.sqlRow)
+			mocks.sqlRow.EXPECT().Scan(gomock.Any()).Return(sqlError)
+			var _, _, _, err = module.GetThemeConfigurationIfNoneMatch(ctx, realm, etag)
+			assert.Equal(t, sqlError, err)
+		})
+		t.Run("Not modified", func(t *testing.T) {
+			mocks.db.EXPECT().QueryRow(selectThemeETagStmt, realm).Return(mocks.sqlRow)
+			mocks.sqlRow.EXPECT().Scan(gomock.Any()).DoAndReturn(func(dest ...any) error {
+				*(dest[0]).(*string) = etag
+				return nil
+			})
+			var _, resETag, notModified, err = module.GetThemeConfigurationIfNoneMatch(ctx, realm, etag)
+			assert.Nil(t, err)
+			assert.True(t, notModified)
+			assert.Equal(t, etag, resETag)
+		})
+		t.Run("Modified", func(t *testing.T) {
+			mocks.db.EXPECT().QueryRow(selectThemeETagStmt, realm).Return(mocks.sqlRow)
+			mocks.sqlRow.EXPECT().Scan(gomock.Any()).DoAndReturn(func(dest ...any) error {
+				*(dest[0]).(*string) = etag
+				return nil
+			})
+			mockThemeRow(`{}`, `{}`)
+			var _, _, notModified, err = module.GetThemeConfigurationIfNoneMatch(ctx, realm, `"other"`)
+			assert.Nil(t, err)
+			assert.False(t, notModified)
+		})
+		t.Run("No entity tag provided", func(t *testing.T) {
+			mockThemeRow(`{}`, `{}`)
+			var _, _, notModified, err = module.GetThemeConfigurationIfNoneMatch(ctx, realm, "")
+			assert.Nil(t, err)
+			assert.False(t, notModified)
+		})
+	})
+
+	t.Run("UpdateThemeConfiguration", func(t *testing.T) {
+		var conf = ThemeConfiguration{
+			Settings:     &ThemeConfigurationSettings{Color: ptr("#000")},
+			Translations: map[string]any{"en": map[string]any{"title": "Title"}},
+			Logo:         pngImage,
+		}
+		t.Run("Invalid configuration", func(t *testing.T) {
+			var _, err = module.UpdateThemeConfiguration(ctx, realm, ThemeConfiguration{Logo: []byte{0, 0, 0}})
+			assert.NotNil(t, err)
+		})
+		t.Run("SQL error", func(t *testing.T) {
+			mocks.db.EXPECT().Exec(upsertThemeConfigStmt, realm, gomock.Any(), gomock.Any(), pngImage, gomock.Nil(), gomock.Any()).Return(nil, sqlError)
+			var _, err = module.UpdateThemeConfiguration(ctx, realm, conf)
+			assert.Equal(t, sqlError, err)
+		})
+		t.Run("Success", func(t *testing.T) {
+			mocks.db.EXPECT().Exec(upsertThemeConfigStmt, realm, ptr(`{"color":"#000"}`), ptr(`{"en":{"title":"Title"}}`), pngImage, gomock.Nil(), gomock.Any()).Return(nil, nil)
+			var resETag, err = module.UpdateThemeConfiguration(ctx, realm, conf)
+			assert.Nil(t, err)
+			assert.NotEqual(t, "", resETag)
+		})
+	})
+}
@@ -0,0 +1,222 @@
+package configuration
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/cloudtrust/common-service/v2/database/sqltypes"
+	"github.com/cloudtrust/common-service/v2/log"
+)
+
+// ChangeSource identifies a set of configuration values watched for changes
+type ChangeSource string
+
+// Watched change sources
+const (
+	ChangeSourceRealmConfiguration ChangeSource = "realm_configuration"
+	ChangeSourceContextKeys        ChangeSource = "context_key_configuration"
+	ChangeSourceAuthorizations     ChangeSource = "authorizations"
+)
+
+// Change markers are checksums of the rows: they need no specific column and detect any insertion, update or deletion.
+// Authorization markers are computed by realm from the number of rows and the sum of the checksums of the rows
+var changeMarkerStmts = map[ChangeSource]string{
+	ChangeSourceRealmConfiguration: `SELECT realm_id, MD5(CONCAT_WS(',', QUOTE(configuration), QUOTE(admin_configuration))) FROM realm_configuration`,
+	ChangeSourceContextKeys: `SELECT id, MD5(CONCAT_WS(',', QUOTE(label), QUOTE(identities_realm), QUOTE(customer_realm), QUOTE(configuration), ` +
+		`QUOTE(is_register_default))) FROM context_key_configuration`,
+	ChangeSourceAuthorizations: `SELECT realm_id, CONCAT(COUNT(*), '-', SUM(CRC32(CONCAT_WS(',', QUOTE(group_name), QUOTE(action), QUOTE(target_realm_id), ` +
+		`QUOTE(target_group_name), QUOTE(deny), QUOTE(conditions))))) FROM authorizations GROUP BY realm_id`,
+}
+
+// legacyChangeMarkerStmts are used by schemas created before the deny and conditions columns of the authorizations
+var legacyChangeMarkerStmts = map[ChangeSource]string{
+	ChangeSourceAuthorizations: `SELECT realm_id, CONCAT(COUNT(*), '-', SUM(CRC32(CONCAT_WS(',', QUOTE(group_name), QUOTE(action), QUOTE(target_realm_id), ` +
+		`QUOTE(target_group_name))))) FROM authorizations GROUP BY realm_id`,
+}
+
+// DefaultWatchInterval is the polling interval used when the given one is not positive
+const DefaultWatchInterval = 30 * time.Second
+
+// ChangeCallback is invoked with the keys (realm ID, context key ID or realm name for authorizations) which changed since the previous poll
+type ChangeCallback func(ctx context.Context, source ChangeSource, changedKeys []string)
+
+// ConfigurationWatcher polls change markers of the configuration tables and notifies registered callbacks
+type ConfigurationWatcher struct {
+	db        sqltypes.CloudtrustDB
+	interval  time.Duration
+	logger    log.Logger
+	pollMutex sync.Mutex
+	mutex     sync.Mutex
+	markers   map[ChangeSource]map[string]string
+	callbacks map[ChangeSource][]ChangeCallback
+	stop      chan struct{}
+}
+
+// NewConfigurationWatcher creates a ConfigurationWatcher polling the database at the given interval once started.
+// DefaultWatchInterval is used if interval is not positive
+func NewConfigurationWatcher(db sqltypes.CloudtrustDB, interval time.Duration, logger log.Logger) *ConfigurationWatcher {
+	if interval <= 0 {
+		interval = DefaultWatchInterval
+	}
+	return &ConfigurationWatcher{
+		db:        db,
+		interval:  interval,
+		logger:    logger,
+		markers:   map[ChangeSource]map[string]string{},
+		callbacks: map[ChangeSource][]ChangeCallback{},
+	}
+}
+
+// OnChange registers a callback invoked when values of the given source change
+func (w *ConfigurationWatcher) OnChange(source ChangeSource, callback ChangeCallback) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.callbacks[source] = append(w.callbacks[source], callback)
+}
+
+// Poll reads the change markers of every watched source and invokes the callbacks of the changed ones.
+// The first poll of a source only records its markers. Concurrent polls are serialized: callbacks must not call Poll
+func (w *ConfigurationWatcher) Poll(ctx context.Context) error {
+	w.pollMutex.Lock()
+	defer w.pollMutex.Unlock()
+
+	w.mutex.Lock()
+	var sources []ChangeSource
+	for source := range w.callbacks {
+		sources = append(sources, source)
+	}
+	w.mutex.Unlock()
+	sort.Slice(sources, func(i, j int) bool {
+		return sources[i] < sources[j]
+	})
+
+	var resErr error
+	for _, source := range sources {
+		if err := w.pollSource(ctx, source); err != nil && resErr == nil {
+			resErr = err
+		}
+	}
+	return resErr
+}
+
+func (w *ConfigurationWatcher) pollSource(ctx context.Context, source ChangeSource) error {
+	var markers, err = w.readMarkers(ctx, source)
+	if err != nil {
+		return err
+	}
+
+	w.mutex.Lock()
+	var previous, initialized = w.markers[source]
+	w.markers[source] = markers
+	var callbacks = append([]ChangeCallback{}, w.callbacks[source]...)
+	w.mutex.Unlock()
+
+	if !initialized {
+		return nil
+	}
+	var changedKeys = diffMarkers(previous, markers)
+	if len(changedKeys) == 0 {
+		return nil
+	}
+
+	w.logger.Info(ctx, "msg", "Configuration change detected", "source", string(source), "keys", len(changedKeys))
+	for _, callback := range callbacks {
+		callback(ctx, source, changedKeys)
+	}
+	return nil
+}
+
+func (w *ConfigurationWatcher) readMarkers(ctx context.Context, source ChangeSource) (map[string]string, error) {
+	rows, err := w.db.Query(changeMarkerStmts[source])
+	if legacyStmt, ok := legacyChangeMarkerStmts[source]; ok && err != nil && isUnknownColumnError(err) {
+		rows, err = w.db.Query(legacyStmt)
+	}
+	if err != nil {
+		w.logger.Warn(ctx, "msg", "Can't get change markers", "source", string(source), "err", err.Error())
+		return nil, err
+	}
+	defer rows.Close()
+
+	var markers = map[string]string{}
+	for rows.Next() {
+		var key string
+		var marker sql.NullString
+		if err = rows.Scan(&key, &marker); err != nil {
+			w.logger.Warn(ctx, "msg", "Can't get change markers. Scan failed", "source", string(source), "err", err.Error())
+			return nil, err
+		}
+		markers[key] = marker.String
+	}
+	if err = rows.Err(); err != nil {
+		w.logger.Warn(ctx, "msg", "Can't get change markers. Failed to iterate on every items", "source", string(source), "err", err.Error())
+		return nil, err
+	}
+	return markers, nil
+}
+
+func diffMarkers(previous map[string]string, current map[string]string) []string {
+	var changedKeys []string
+	for key, marker := range current {
+		if previousMarker, ok := previous[key]; !ok || previousMarker != marker {
+			changedKeys = append(changedKeys, key)
+		}
+	}
+	for key := range previous {
+		if _, ok := current[key]; !ok {
+			changedKeys = append(changedKeys, key)
+		}
+	}
+	sort.Strings(changedKeys)
+	return changedKeys
+}
+
+// Start polls the database in background until Stop is called or the context is done
+func (w *ConfigurationWatcher) Start(ctx context.Context) {
+	w.mutex.Lock()
+	if w.stop != nil {
+		w.mutex.Unlock()
+		return
+	}
+	var stop = make(chan struct{})
+	w.stop = stop
+	w.mutex.Unlock()
+
+	// Initializes markers: changes will be detected from now on
+	_ = w.Poll(ctx)
+
+	go func() {
+		var ticker = time.NewTicker(w.interval)
+		defer ticker.Stop()
+		// Once the context is done, the watcher can be started again
+		defer func() {
+			w.mutex.Lock()
+			if w.stop == stop {
+				w.stop = nil
+			}
+			w.mutex.Unlock()
+		}()
+		for {
+			select {
+			case <-ticker.C:
+				_ = w.Poll(ctx)
+			case <-stop:
+				return
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops the background polling
+func (w *ConfigurationWatcher) Stop() {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if w.stop != nil {
+		close(w.stop)
+		w.stop = nil
+	}
+}
@@ -0,0 +1,170 @@
+package configuration
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cloudtrust/common-service/v2/configuration/mock"
+	"github.com/cloudtrust/common-service/v2/database/sqltypes"
+	"github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+func mockChangeMarkers(sqlRows *mock.SQLRows, markers [][2]string) {
+	for _, marker := range markers {
+		sqlRows.EXPECT().Next().Return(true)
+		sqlRows.EXPECT().Scan(gomock.Any()).DoAndReturn(func(dest ...any) error {
+			*(dest[0]).(*string) = marker[0]
+			*(dest[1]).(*sql.NullString) = sql.NullString{String: marker[1], Valid: true}
+			return nil
+		})
+	}
+	sqlRows.EXPECT().Next().Return(false)
+	sqlRows.EXPECT().Err()
+	sqlRows.EXPECT().Close()
+}
+
+func TestDiffMarkers(t *testing.T) {
+	var previous = map[string]string{"a": "1", "b": "1", "c": "1"}
+	var current = map[string]string{"a": "1", "b": "2", "d": "1"}
+	assert.Equal(t, []string{"b", "c", "d"}, diffMarkers(previous, current))
+	assert.Nil(t, diffMarkers(current, current))
+}
+
+func TestConfigurationWatcherPoll(t *testing.T) {
+	var mocks = newDbMocks(t)
+	defer mocks.finish()
+
+	var ctx = context.TODO()
+	var sqlError = errors.New("SQL error")
+	var watcher = NewConfigurationWatcher(mocks.db, time.Minute, mocks.logger)
+	var notified [][]string
+	watcher.OnChange(ChangeSourceAuthorizations, func(_ context.Context, source ChangeSource, changedKeys []string) {
+		assert.Equal(t, ChangeSourceAuthorizations, source)
+		notified = append(notified, changedKeys)
+	})
+	var stmt = changeMarkerStmts[ChangeSourceAuthorizations]
+
+	t.Run("SQL error", func(t *testing.T) {
+		mocks.db.EXPECT().Query(stmt).Return(nil, sqlError)
+		assert.Equal(t, sqlError, watcher.Poll(ctx))
+	})
+	t.Run("Scan error", func(t *testing.T) {
+		mocks.db.EXPECT().Query(stmt).Return(mocks.sqlRows, nil)
+		mocks.sqlRows.EXPECT().Next().Return(true)
+		mocks.sqlRows.EXPECT().Scan(gomock.Any()).Return(sqlError)
+		mocks.sqlRows.EXPECT().Close()
+		assert.Equal(t, sqlError, watcher.Poll(ctx))
+	})
+	t.Run("First poll initializes markers", func(t *testing.T) {
+		mocks.db.EXPECT().Query(stmt).Return(mocks.sqlRows, nil)
+		mockChangeMarkers(mocks.sqlRows, [][2]string{{"master", "3-2024"}, {"realm", "1-2024"}})
+		assert.Nil(t, watcher.Poll(ctx))
+		assert.Len(t, notified, 0)
+	})
+	t.Run("No change", func(t *testing.T) {
+		mocks.db.EXPECT().Query(stmt).Return(mocks.sqlRows, nil)
+		mockChangeMarkers(mocks.sqlRows, [][2]string{{"master", "3-2024"}, {"realm", "1-2024"}})
+		assert.Nil(t, watcher.Poll(ctx))
+		assert.Len(t, notified, 0)
+	})
+	t.Run("Changes detected", func(t *testing.T) {
+		mocks.db.EXPECT().Query(stmt).Return(mocks.sqlRows, nil)
+		mockChangeMarkers(mocks.sqlRows, [][2]string{{"master", "2-2024"}})
+		assert.Nil(t, watcher.Poll(ctx))
+		assert.Equal(t, [][]string{{"master", "realm"}}, notified)
+	})
+}
+
+func TestConfigurationWatcherConcurrentPolls(t *testing.T) {
+	var mocks = newDbMocks(t)
+	defer mocks.finish()
+
+	var ctx = context.TODO()
+	var watcher = NewConfigurationWatcher(mocks.db, time.Minute, mocks.logger)
+	var notified atomic.Int32
+	watcher.OnChange(ChangeSourceAuthorizations, func(context.Context, ChangeSource, []string) {
+		notified.Add(1)
+	})
+
+	var inFlight, maxInFlight atomic.Int32
+	var version atomic.Int32
+	mocks.db.EXPECT().Query(changeMarkerStmts[ChangeSourceAuthorizations]).DoAndReturn(func(string, ...any) (sqltypes.SQLRows, error) {
+		var current = inFlight.Add(1)
+		if current > maxInFlight.Load() {
+			maxInFlight.Store(current)
+		}
+		time.Sleep(10 * time.Millisecond)
+		inFlight.Add(-1)
+		var rows = mock.NewSQLRows(mocks.mockCtrl)
+		mockChangeMarkers(rows, [][2]string{{"realm", strconv.Itoa(int(version.Add(1)))}})
+		return rows, nil
+	}).Times(3)
+
+	var wg sync.WaitGroup
+	for range 3 {
+		wg.Go(func() {
+			assert.Nil(t, watcher.Poll(ctx))
+		})
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), maxInFlight.Load())
+	// The first poll initializes the markers, each next one detects a change
+	assert.Equal(t, int32(2), notified.Load())
+}
+
+func TestConfigurationWatcherStartStop(t *testing.T) {
+	var mocks = newDbMocks(t)
+	defer mocks.finish()
+
+	var watcher = NewConfigurationWatcher(mocks.db, time.Millisecond, mocks.logger)
+	watcher.OnChange(ChangeSourceContextKeys, func(_ context.Context, _ ChangeSource, _ []string) {})
+
+	var polled = make(chan bool, 100)
+	mocks.db.EXPECT().Query(changeMarkerStmts[ChangeSourceContextKeys]).DoAndReturn(func(_ string, _ ...any) (sqltypes.SQLRows, error) {
+		polled <- true
+		return nil, errors.New("SQL error")
+	}).MinTimes(2)
+
+	watcher.Start(context.TODO())
+	// Starting twice has no effect
+	watcher.Start(context.TODO())
+	<-polled
+	<-polled
+	watcher.Stop()
+	watcher.Stop()
+
+	t.Run("Can be started again once the context is done", func(t *testing.T) {
+		var ctx, cancel = context.WithCancel(context.TODO())
+		watcher.Start(ctx)
+		cancel()
+		assert.Eventually(t, func() bool {
+			watcher.mutex.Lock()
+			defer watcher.mutex.Unlock()
+			return watcher.stop == nil
+		}, time.Second, time.Millisecond)
+	})
+	t.Run("Invalid interval", func(t *testing.T) {
+		assert.Equal(t, DefaultWatchInterval, NewConfigurationWatcher(mocks.db, 0, mocks.logger).interval)
+	})
+}
+
+func TestConfigurationWatcherLegacySchema(t *testing.T) {
+	var mocks = newDbMocks(t)
+	defer mocks.finish()
+
+	var watcher = NewConfigurationWatcher(mocks.db, time.Minute, mocks.logger)
+	watcher.OnChange(ChangeSourceAuthorizations, func(_ context.Context, _ ChangeSource, _ []string) {})
+
+	mocks.db.EXPECT().Query(changeMarkerStmts[ChangeSourceAuthorizations]).Return(nil, &mysql.MySQLError{Number: 1054, Message: "Unknown column 'deny' in 'field list'"})
+	mocks.db.EXPECT().Query(legacyChangeMarkerStmts[ChangeSourceAuthorizations]).Return(mocks.sqlRows, nil)
+	mockChangeMarkers(mocks.sqlRows, [][2]string{{"master", "3-1234"}})
+	assert.Nil(t, watcher.Poll(context.TODO()))
+}
@@ -0,0 +1,145 @@
+package commonservice
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	yamlConfiguration = `
+db-host-port: localhost:3306
+db:
+  Max-Open-Conns: 20
+  timeout: 5s
+  ratio: 0.25
+origins:
+  - a
+  - b
+enabled: true
+not-before: 2024-01-01T10:00:00Z
+unexpected: value
+`
+	jsonConfiguration = `{"db": {"timeout": "1m", "username": "json-user"}, "other": 12}`
+)
+
+func TestBasicConfigurationSources(t *testing.T) {
+	var conf Configuration = NewBasicConfiguration()
+	var basicConf = conf.(*BasicConfiguration)
+	basicConf.lookupEnv = func(name string) (string, bool) {
+		var envs = map[string]string{"DB_USER": "env-user", "DB.RATIO": "0.75"}
+		var value, ok = envs[name]
+		return value, ok
+	}
+
+	assert.Nil(t, basicConf.ReadConfig(strings.NewReader(yamlConfiguration), ConfigurationFormatYAML))
+	assert.Nil(t, basicConf.ReadConfig(strings.NewReader(jsonConfiguration), ConfigurationFormatJSON))
+
+	conf.SetDefault("db.username", "default-user")
+	conf.SetDefault("db.max-idle-conns", 2)
+	conf.SetDefault("enabled", false)
+
+	t.Run("Defaults", func(t *testing.T) {
+		assert.Equal(t, 2, conf.GetInt("db.max-idle-conns"))
+		assert.Nil(t, conf.Get("missing"))
+		assert.Equal(t, "", conf.GetString("missing"))
+		assert.Equal(t, []string{}, conf.GetStringSlice("missing"))
+	})
+	t.Run("Files override defaults, last file wins", func(t *testing.T) {
+		assert.Equal(t, "json-user", conf.GetString("db.username"))
+		assert.Equal(t, time.Minute, conf.GetDuration("db.timeout"))
+		assert.True(t, conf.GetBool("enabled"))
+	})
+	t.Run("Environment overrides files", func(t *testing.T) {
+		assert.Nil(t, conf.BindEnv("db.username", "DB_USER"))
+		assert.Nil(t, conf.BindEnv("db.ratio"))
+		assert.Nil(t, conf.BindEnv("db.unset-env", "UNSET_ENV"))
+		assert.Equal(t, "env-user", conf.GetString("db.username"))
+		assert.Equal(t, 0.75, conf.GetFloat64("db.ratio"))
+		assert.Nil(t, conf.Get("db.unset-env"))
+		assert.NotNil(t, conf.BindEnv())
+	})
+	t.Run("Set overrides everything", func(t *testing.T) {
+		conf.Set("DB.Username", "set-user")
+		assert.Equal(t, "set-user", conf.GetString("db.username"))
+	})
+	t.Run("Unknown keys", func(t *testing.T) {
+		assert.Equal(t, []string{"db-host-port", "db.max-open-conns", "db.timeout", "not-before", "origins", "other", "unexpected"}, basicConf.UnknownKeys())
+	})
+}
+
+func TestBasicConfigurationGetters(t *testing.T) {
+	var conf = NewBasicConfiguration()
+	assert.Nil(t, conf.ReadConfig(strings.NewReader(yamlConfiguration), ConfigurationFormatYAML))
+
+	assert.Equal(t, "localhost:3306", conf.GetString("DB-HOST-PORT"))
+	assert.Equal(t, "20", conf.GetString("db.max-open-conns"))
+	assert.Equal(t, 20, conf.GetInt("db.max-open-conns"))
+	assert.Equal(t, int32(20), conf.GetInt32("db.max-open-conns"))
+	assert.Equal(t, int64(20), conf.GetInt64("db.max-open-conns"))
+	assert.Equal(t, float64(20), conf.GetFloat64("db.max-open-conns"))
+	assert.Equal(t, 5*time.Second, conf.GetDuration("db.timeout"))
+	assert.Equal(t, []string{"a", "b"}, conf.GetStringSlice("origins"))
+	assert.Equal(t, time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC), conf.GetTime("not-before").UTC())
+
+	conf.Set("string-values", "x y  z")
+	conf.Set("string-bool", "true")
+	conf.Set("string-int", "42")
+	conf.Set("string-float-int", "4.2")
+	conf.Set("string-duration", "1000")
+	conf.Set("string-date", "2024-02-03")
+	conf.Set("int-time", 60)
+	assert.Equal(t, []string{"x", "y", "z"}, conf.GetStringSlice("string-values"))
+	assert.True(t, conf.GetBool("string-bool"))
+	assert.Equal(t, 42, conf.GetInt("string-int"))
+	assert.Equal(t, 4, conf.GetInt("string-float-int"))
+	assert.Equal(t, time.Microsecond, conf.GetDuration("string-duration"))
+	assert.Equal(t, time.Date(2024, 2, 3, 0, 0, 0, 0, time.UTC), conf.GetTime("string-date"))
+	assert.Equal(t, int64(60), conf.GetTime("int-time").Unix())
+	assert.Equal(t, time.Duration(60), conf.GetDuration("int-time"))
+}
+
+func TestBasicConfigurationReadFile(t *testing.T) {
+	var dir = t.TempDir()
+	var yamlFile = filepath.Join(dir, "conf.yml")
+	var jsonFile = filepath.Join(dir, "conf.JSON")
+	assert.Nil(t, os.WriteFile(yamlFile, []byte(yamlConfiguration), 0600))
+	assert.Nil(t, os.WriteFile(jsonFile, []byte(jsonConfiguration), 0600))
+
+	var conf = NewBasicConfiguration()
+	assert.Nil(t, conf.ReadFile(yamlFile))
+	assert.Nil(t, conf.ReadFile(jsonFile))
+	assert.Equal(t, "localhost:3306", conf.GetString("db-host-port"))
+	assert.Equal(t, 12, conf.GetInt("other"))
+
+	t.Run("Missing file", func(t *testing.T) {
+		assert.NotNil(t, conf.ReadFile(filepath.Join(dir, "missing.yaml")))
+	})
+	t.Run("Invalid content", func(t *testing.T) {
+		assert.NotNil(t, conf.ReadConfig(strings.NewReader("{"), ConfigurationFormatJSON))
+		assert.NotNil(t, conf.ReadConfig(strings.NewReader("a: [b"), ConfigurationFormatYAML))
+	})
+	t.Run("Unsupported format", func(t *testing.T) {
+		assert.NotNil(t, conf.ReadConfig(strings.NewReader(""), "toml"))
+	})
+	t.Run("Empty YAML", func(t *testing.T) {
+		assert.Nil(t, conf.ReadConfig(strings.NewReader(""), ConfigurationFormatYAML))
+	})
+}
+
+func TestBindBasicConfiguration(t *testing.T) {
+	type target struct {
+		HostPort string        `mapstructure:"host-port" validate:"required"`
+		Timeout  time.Duration `mapstructure:"timeout"`
+	}
+	var conf = NewBasicConfiguration()
+	assert.Nil(t, conf.ReadConfig(strings.NewReader("db:\n  host-port: h:1\n  timeout: 3s\n"), ConfigurationFormatYAML))
+
+	var res, err = Bind[target](conf, "db")
+	assert.Nil(t, err)
+	assert.Equal(t, target{HostPort: "h:1", Timeout: 3 * time.Second}, res)
+}
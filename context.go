@@ -22,4 +22,8 @@ const (
 	CtContextIssuerDomain CtContext = iota
 	// CtContextRoles is the roles context key
 	CtContextRoles CtContext = iota
+	// CtContextClientIP is the client IP address context key
+	CtContextClientIP CtContext = iota
+	// CtContextClaims is the access token claims context key
+	CtContextClaims CtContext = iota
 )
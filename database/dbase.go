@@ -85,23 +85,23 @@ func (db *basicCloudtrustDB) Stats() sql.DBStats {
 	return db.dbConn.Stats()
 }
 
-// DbConfig Db configuration parameters
+// DbConfig Db configuration parameters. Default values are the ones of ConfigureDbDefault, it can be created with commonservice.Bind
 type DbConfig struct {
-	Enabled           bool   `mapstructure:"enabled"`
+	Enabled           bool   `mapstructure:"enabled" default:"true"`
 	HostPort          string `mapstructure:"host-port"`
 	Username          string `mapstructure:"username"`
 	Password          string `mapstructure:"password"`
 	Database          string `mapstructure:"database"`
 	Protocol          string `mapstructure:"protocol"`
 	Parameters        string `mapstructure:"parameters"`
-	MaxOpenConns      int    `mapstructure:"max-open-conns"`
-	MaxIdleConns      int    `mapstructure:"max-idle-conns"`
-	ConnMaxLifetime   int    `mapstructure:"conn-max-lifetime"`
-	ConnMaxIdleTime   int    `mapstructure:"conn-max-idle-time"`
+	MaxOpenConns      int    `mapstructure:"max-open-conns" default:"10"`
+	MaxIdleConns      int    `mapstructure:"max-idle-conns" default:"2"`
+	ConnMaxLifetime   int    `mapstructure:"conn-max-lifetime" default:"900"`
+	ConnMaxIdleTime   int    `mapstructure:"conn-max-idle-time" default:"300"`
 	MigrationEnabled  bool   `mapstructure:"migration"`
 	MigrationVersion  string `mapstructure:"migration-version"`
-	ConnectionCheck   bool   `mapstructure:"connection-check"`
-	PingTimeoutMillis int    `mapstructure:"ping-timeout-ms"`
+	ConnectionCheck   bool   `mapstructure:"connection-check" default:"true"`
+	PingTimeoutMillis int    `mapstructure:"ping-timeout-ms" default:"1500"`
 }
 
 // ConfigureDbDefaultForKey configure default database parameters for a given prefix
@@ -6,6 +6,7 @@ import (
 	"testing"
 	"time"
 
+	cs "github.com/cloudtrust/common-service/v2"
 	"github.com/cloudtrust/common-service/v2/database/sqltypes"
 	"github.com/cloudtrust/common-service/v2/log"
 
@@ -107,6 +108,31 @@ func TestGetDbConfig(t *testing.T) {
 	assert.Equal(t, "value-host-port", cfg.HostPort)
 }
 
+func TestBindDbConfig(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockConf = mock.NewConfiguration(mockCtrl)
+
+	var prefix = "mydb"
+
+	mockConf.EXPECT().Get(prefix + ".host-port").Return("localhost:3306").AnyTimes()
+	mockConf.EXPECT().Get(gomock.Any()).Return(nil).AnyTimes()
+	mockConf.EXPECT().GetString(prefix + ".host-port").Return("localhost:3306")
+
+	var cfg, err = cs.Bind[DbConfig](mockConf, prefix)
+	assert.Nil(t, err)
+	assert.Equal(t, DbConfig{
+		Enabled:           true,
+		HostPort:          "localhost:3306",
+		MaxOpenConns:      10,
+		MaxIdleConns:      2,
+		ConnMaxLifetime:   900,
+		ConnMaxIdleTime:   300,
+		ConnectionCheck:   true,
+		PingTimeoutMillis: 1500,
+	}, cfg)
+}
+
 func TestCheckMigrationVersion(t *testing.T) {
 	var mockCtrl = gomock.NewController(t)
 	defer mockCtrl.Finish()
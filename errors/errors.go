@@ -17,6 +17,7 @@ const (
 	MsgErrDisabledEndpoint          = "disabledEndpoint"
 	MsgErrInvalidLength             = "invalidLength"
 	MsgErrDecryptionKeyNotAvailable = "decryptionKeyNotAvailable"
+	MsgErrTruncated                 = "truncated"
 	MsgErrUnknown                   = "unknownError"
 
 	EncryptDecrypt = "encryptOrDecrypt"
@@ -0,0 +1,262 @@
+package features
+
+import (
+	"context"
+	"hash/fnv"
+	"slices"
+	"time"
+
+	cs "github.com/cloudtrust/common-service/v2"
+	"github.com/cloudtrust/common-service/v2/configuration"
+	errorhandler "github.com/cloudtrust/common-service/v2/errors"
+	"github.com/cloudtrust/common-service/v2/log"
+	"github.com/cloudtrust/common-service/v2/middleware"
+)
+
+// Flag is the definition of a feature flag
+type Flag struct {
+	Name    string `json:"name"`
+	Default bool   `json:"default"`
+	Rules   []Rule `json:"rules,omitempty"`
+}
+
+// Rule overrides the default value of a flag for the subjects it matches.
+// Empty criteria match any subject. Percentage, when provided, enables the rule for a stable subset of the users only. It must be
+// between 0 and 100
+type Rule struct {
+	Enabled    bool       `json:"enabled"`
+	Realms     []string   `json:"realms,omitempty"`
+	Groups     []string   `json:"groups,omitempty"`
+	Roles      []string   `json:"roles,omitempty"`
+	Users      []string   `json:"users,omitempty"`
+	Percentage *int       `json:"percentage,omitempty"`
+	NotBefore  *time.Time `json:"not_before,omitempty"`
+	NotAfter   *time.Time `json:"not_after,omitempty"`
+}
+
+// Subject is the entity for which flags are evaluated
+type Subject struct {
+	Realm  string
+	UserID string
+	Groups []string
+	Roles  []string
+}
+
+// SubjectFromContext builds a subject from the values stored in the context by the authentication middleware
+func SubjectFromContext(ctx context.Context) Subject {
+	var subject Subject
+	if value, ok := ctx.Value(cs.CtContextRealm).(string); ok {
+		subject.Realm = value
+	}
+	if value, ok := ctx.Value(cs.CtContextUserID).(string); ok {
+		subject.UserID = value
+	}
+	if value, ok := ctx.Value(cs.CtContextGroups).([]string); ok {
+		subject.Groups = value
+	}
+	if value, ok := ctx.Value(cs.CtContextRoles).([]string); ok {
+		subject.Roles = value
+	}
+	return subject
+}
+
+// RealmOverridesProvider provides realm level values of flags. They apply when no rule of a flag matches
+type RealmOverridesProvider interface {
+	GetRealmOverrides(ctx context.Context, realm string) (map[string]bool, error)
+}
+
+// Engine evaluates feature flags
+type Engine interface {
+	IsEnabled(ctx context.Context, flagName string, subject Subject) (bool, error)
+	GetEffectiveFlags(ctx context.Context, subject Subject) (map[string]bool, error)
+	NewAvailabilityChecker(flagName string) middleware.EndpointAvailabilityChecker
+}
+
+type engine struct {
+	flags     map[string]Flag
+	overrides RealmOverridesProvider
+	now       func() time.Time
+}
+
+// NewEngine creates a feature flags engine. Overrides provider is optional. It fails if a flag is not valid
+func NewEngine(flags []Flag, overrides RealmOverridesProvider) (Engine, error) {
+	var flagsByName = map[string]Flag{}
+	for _, flag := range flags {
+		if err := flag.Validate(); err != nil {
+			return nil, err
+		}
+		flagsByName[flag.Name] = flag
+	}
+	return &engine{
+		flags:     flagsByName,
+		overrides: overrides,
+		now:       time.Now,
+	}, nil
+}
+
+// Validate checks the consistency of a flag
+func (f Flag) Validate() error {
+	if f.Name == "" {
+		return errorhandler.CreateMissingParameterError("name")
+	}
+	for _, rule := range f.Rules {
+		if rule.Percentage != nil && (*rule.Percentage < 0 || *rule.Percentage > 100) {
+			return errorhandler.CreateBadRequestError(errorhandler.MsgErrInvalidParam + ".rules.percentage")
+		}
+	}
+	return nil
+}
+
+// IsEnabled evaluates a flag for a subject: the first matching rule applies, then the realm override, then the default value.
+// Unknown flags are disabled
+func (e *engine) IsEnabled(ctx context.Context, flagName string, subject Subject) (bool, error) {
+	var flag, ok = e.flags[flagName]
+	if !ok {
+		return false, nil
+	}
+	var overrides, err = e.getRealmOverrides(ctx, subject.Realm)
+	if err != nil {
+		return false, err
+	}
+	return e.evaluate(flag, subject, overrides), nil
+}
+
+// GetEffectiveFlags evaluates all the known flags for a subject
+func (e *engine) GetEffectiveFlags(ctx context.Context, subject Subject) (map[string]bool, error) {
+	var overrides, err = e.getRealmOverrides(ctx, subject.Realm)
+	if err != nil {
+		return nil, err
+	}
+	var res = map[string]bool{}
+	for name, flag := range e.flags {
+		res[name] = e.evaluate(flag, subject, overrides)
+	}
+	return res, nil
+}
+
+func (e *engine) getRealmOverrides(ctx context.Context, realm string) (map[string]bool, error) {
+	if e.overrides == nil || realm == "" {
+		return nil, nil
+	}
+	return e.overrides.GetRealmOverrides(ctx, realm)
+}
+
+func (e *engine) evaluate(flag Flag, subject Subject, overrides map[string]bool) bool {
+	var now = e.now()
+	for _, rule := range flag.Rules {
+		if rule.isActive(now) && rule.matches(subject) && rule.includes(flag.Name, subject) {
+			return rule.Enabled
+		}
+	}
+	if value, ok := overrides[flag.Name]; ok {
+		return value
+	}
+	return flag.Default
+}
+
+func (r Rule) isActive(now time.Time) bool {
+	if r.NotBefore != nil && now.Before(*r.NotBefore) {
+		return false
+	}
+	return r.NotAfter == nil || now.Before(*r.NotAfter)
+}
+
+func (r Rule) matches(subject Subject) bool {
+	if len(r.Realms) > 0 && !slices.Contains(r.Realms, subject.Realm) {
+		return false
+	}
+	if len(r.Users) > 0 && !slices.Contains(r.Users, subject.UserID) {
+		return false
+	}
+	if len(r.Groups) > 0 && !containsAny(r.Groups, subject.Groups) {
+		return false
+	}
+	return len(r.Roles) == 0 || containsAny(r.Roles, subject.Roles)
+}
+
+// includes tells if the subject is part of the rollout percentage. The bucket of a subject is stable for a given flag
+func (r Rule) includes(flagName string, subject Subject) bool {
+	if r.Percentage == nil {
+		return true
+	}
+	var key = subject.UserID
+	if key == "" {
+		key = subject.Realm
+	}
+	var hash = fnv.New32a()
+	_, _ = hash.Write([]byte(flagName + ":" + key))
+	return int(hash.Sum32()%100) < *r.Percentage
+}
+
+func containsAny(values []string, searched []string) bool {
+	for _, value := range searched {
+		if slices.Contains(values, value) {
+			return true
+		}
+	}
+	return false
+}
+
+type availabilityChecker struct {
+	engine   *engine
+	flagName string
+}
+
+// NewAvailabilityChecker creates an EndpointAvailabilityChecker based on a flag. It can be used with middleware.MakeEndpointAvailableCheckMW
+func (e *engine) NewAvailabilityChecker(flagName string) middleware.EndpointAvailabilityChecker {
+	return &availabilityChecker{
+		engine:   e,
+		flagName: flagName,
+	}
+}
+
+func (ac *availabilityChecker) CheckAvailability(ctx context.Context, logger log.Logger) (context.Context, error) {
+	return ac.CheckAvailabilityForRealm(ctx, ctx.Value(cs.CtContextRealm).(string), logger)
+}
+
+// CheckAvailabilityForRealm evaluates the flag for the target realm. The user, groups and roles of the caller belong to the realm
+// of the caller: they are only considered when the target realm is the realm of the caller
+func (ac *availabilityChecker) CheckAvailabilityForRealm(ctx context.Context, targetRealm string, logger log.Logger) (context.Context, error) {
+	var subject = SubjectFromContext(ctx)
+	if subject.Realm != targetRealm {
+		subject = Subject{Realm: targetRealm}
+	}
+
+	var enabled, err = ac.engine.IsEnabled(ctx, ac.flagName, subject)
+	if err != nil {
+		logger.Info(ctx, "msg", "Can't evaluate feature flag", "realm", targetRealm, "feat", ac.flagName, "err", err.Error())
+		return ctx, err
+	}
+	if !enabled {
+		logger.Info(ctx, "msg", "Feature not enabled", "realm", targetRealm, "feat", ac.flagName)
+		return ctx, errorhandler.CreateEndpointNotEnabled(targetRealm)
+	}
+	return ctx, nil
+}
+
+type availableChecksProvider struct {
+	idRetriever   middleware.IDRetriever
+	confRetriever middleware.AdminConfigurationRetriever
+}
+
+// NewAvailableChecksProvider creates a RealmOverridesProvider using RealmAdminConfiguration.AvailableChecks as realm level values.
+// The access token used to resolve the realm ID is read from the context
+func NewAvailableChecksProvider(idRetriever middleware.IDRetriever, confRetriever middleware.AdminConfigurationRetriever) RealmOverridesProvider {
+	return &availableChecksProvider{
+		idRetriever:   idRetriever,
+		confRetriever: confRetriever,
+	}
+}
+
+func (p *availableChecksProvider) GetRealmOverrides(ctx context.Context, realm string) (map[string]bool, error) {
+	var accessToken, _ = ctx.Value(cs.CtContextAccessToken).(string)
+	var realmID, err = p.idRetriever.GetID(accessToken, realm)
+	if err != nil {
+		return nil, err
+	}
+	var conf configuration.RealmAdminConfiguration
+	if conf, err = p.confRetriever.GetAdminConfiguration(ctx, realmID); err != nil {
+		return nil, err
+	}
+	return conf.AvailableChecks, nil
+}
@@ -0,0 +1,244 @@
+package features
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	cs "github.com/cloudtrust/common-service/v2"
+	"github.com/cloudtrust/common-service/v2/configuration"
+	"github.com/cloudtrust/common-service/v2/features/mock"
+	"github.com/cloudtrust/common-service/v2/log"
+	"github.com/cloudtrust/common-service/v2/middleware"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+func intPtr(value int) *int {
+	return &value
+}
+
+func TestSubjectFromContext(t *testing.T) {
+	assert.Equal(t, Subject{}, SubjectFromContext(context.TODO()))
+
+	var ctx = context.WithValue(context.TODO(), cs.CtContextRealm, "realm")
+	ctx = context.WithValue(ctx, cs.CtContextUserID, "user")
+	ctx = context.WithValue(ctx, cs.CtContextGroups, []string{"group"})
+	ctx = context.WithValue(ctx, cs.CtContextRoles, []string{"role"})
+	assert.Equal(t, Subject{Realm: "realm", UserID: "user", Groups: []string{"group"}, Roles: []string{"role"}}, SubjectFromContext(ctx))
+}
+
+func TestIsEnabled(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockOverrides = mock.NewRealmOverridesProvider(mockCtrl)
+
+	var now = time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	var before = now.Add(-time.Hour)
+	var after = now.Add(time.Hour)
+	var ctx = context.TODO()
+	var subject = Subject{Realm: "realm", UserID: "user", Groups: []string{"support"}, Roles: []string{"agent"}}
+
+	var newEngine = func(flag Flag) *engine {
+		var res, _ = NewEngine([]Flag{flag}, mockOverrides)
+		var e = res.(*engine)
+		e.now = func() time.Time { return now }
+		return e
+	}
+
+	t.Run("Unknown flag", func(t *testing.T) {
+		var enabled, err = newEngine(Flag{Name: "flag"}).IsEnabled(ctx, "unknown", subject)
+		assert.Nil(t, err)
+		assert.False(t, enabled)
+	})
+	t.Run("Overrides provider fails", func(t *testing.T) {
+		var expectedErr = errors.New("db error")
+		mockOverrides.EXPECT().GetRealmOverrides(ctx, "realm").Return(nil, expectedErr)
+		var _, err = newEngine(Flag{Name: "flag"}).IsEnabled(ctx, "flag", subject)
+		assert.Equal(t, expectedErr, err)
+	})
+	t.Run("Default value", func(t *testing.T) {
+		mockOverrides.EXPECT().GetRealmOverrides(ctx, "realm").Return(nil, nil)
+		var enabled, _ = newEngine(Flag{Name: "flag", Default: true}).IsEnabled(ctx, "flag", subject)
+		assert.True(t, enabled)
+	})
+	t.Run("Realm override", func(t *testing.T) {
+		mockOverrides.EXPECT().GetRealmOverrides(ctx, "realm").Return(map[string]bool{"flag": false}, nil)
+		var enabled, _ = newEngine(Flag{Name: "flag", Default: true}).IsEnabled(ctx, "flag", subject)
+		assert.False(t, enabled)
+	})
+	t.Run("Matching rules", func(t *testing.T) {
+		for name, rule := range map[string]Rule{
+			"realm":        {Enabled: true, Realms: []string{"realm"}},
+			"group":        {Enabled: true, Groups: []string{"support"}},
+			"role":         {Enabled: true, Roles: []string{"agent"}},
+			"user":         {Enabled: true, Users: []string{"user"}},
+			"window":       {Enabled: true, NotBefore: &before, NotAfter: &after},
+			"100 percents": {Enabled: true, Percentage: intPtr(100)},
+		} {
+			t.Run(name, func(t *testing.T) {
+				mockOverrides.EXPECT().GetRealmOverrides(ctx, "realm").Return(map[string]bool{"flag": false}, nil)
+				var enabled, _ = newEngine(Flag{Name: "flag", Rules: []Rule{rule}}).IsEnabled(ctx, "flag", subject)
+				assert.True(t, enabled)
+			})
+		}
+	})
+	t.Run("Not matching rules", func(t *testing.T) {
+		for name, rule := range map[string]Rule{
+			"realm":      {Enabled: true, Realms: []string{"other"}},
+			"group":      {Enabled: true, Groups: []string{"other"}},
+			"role":       {Enabled: true, Roles: []string{"other"}},
+			"user":       {Enabled: true, Users: []string{"other"}},
+			"not yet":    {Enabled: true, NotBefore: &after},
+			"expired":    {Enabled: true, NotAfter: &before},
+			"0 percents": {Enabled: true, Percentage: intPtr(0)},
+		} {
+			t.Run(name, func(t *testing.T) {
+				mockOverrides.EXPECT().GetRealmOverrides(ctx, "realm").Return(nil, nil)
+				var enabled, _ = newEngine(Flag{Name: "flag", Rules: []Rule{rule}}).IsEnabled(ctx, "flag", subject)
+				assert.False(t, enabled)
+			})
+		}
+	})
+	t.Run("First matching rule wins", func(t *testing.T) {
+		mockOverrides.EXPECT().GetRealmOverrides(ctx, "realm").Return(nil, nil)
+		var flag = Flag{Name: "flag", Rules: []Rule{{Enabled: false, Users: []string{"user"}}, {Enabled: true}}}
+		var enabled, _ = newEngine(flag).IsEnabled(ctx, "flag", subject)
+		assert.False(t, enabled)
+	})
+}
+
+func TestNewEngine(t *testing.T) {
+	t.Run("Valid flags", func(t *testing.T) {
+		var _, err = NewEngine([]Flag{{Name: "flag", Rules: []Rule{{Percentage: intPtr(0)}, {Percentage: intPtr(100)}}}}, nil)
+		assert.Nil(t, err)
+	})
+	t.Run("Missing name", func(t *testing.T) {
+		var _, err = NewEngine([]Flag{{Default: true}}, nil)
+		assert.NotNil(t, err)
+	})
+	for _, percentage := range []int{-1, 101} {
+		t.Run("Invalid percentage", func(t *testing.T) {
+			var _, err = NewEngine([]Flag{{Name: "flag", Rules: []Rule{{Enabled: true, Percentage: intPtr(percentage)}}}}, nil)
+			assert.NotNil(t, err)
+		})
+	}
+}
+
+func TestPercentageRollout(t *testing.T) {
+	var rule = Rule{Enabled: true, Percentage: intPtr(30)}
+	var included = 0
+	for _, userID := range []string{"a", "b", "c", "d", "e", "f", "g", "h", "i", "j", "k", "l", "m", "n", "o", "p", "q", "r", "s", "t"} {
+		var subject = Subject{UserID: userID}
+		var first = rule.includes("flag", subject)
+		// Bucket of a user is stable
+		assert.Equal(t, first, rule.includes("flag", subject))
+		if first {
+			included++
+		}
+	}
+	assert.True(t, included > 0 && included < 20)
+}
+
+func TestGetEffectiveFlags(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockOverrides = mock.NewRealmOverridesProvider(mockCtrl)
+
+	var ctx = context.TODO()
+	var e, _ = NewEngine([]Flag{{Name: "flag1", Default: true}, {Name: "flag2"}, {Name: "flag3"}}, mockOverrides)
+
+	t.Run("Overrides provider fails", func(t *testing.T) {
+		mockOverrides.EXPECT().GetRealmOverrides(ctx, "realm").Return(nil, errors.New("db error"))
+		var _, err = e.GetEffectiveFlags(ctx, Subject{Realm: "realm"})
+		assert.NotNil(t, err)
+	})
+	t.Run("Success", func(t *testing.T) {
+		mockOverrides.EXPECT().GetRealmOverrides(ctx, "realm").Return(map[string]bool{"flag2": true}, nil)
+		var flags, err = e.GetEffectiveFlags(ctx, Subject{Realm: "realm"})
+		assert.Nil(t, err)
+		assert.Equal(t, map[string]bool{"flag1": true, "flag2": true, "flag3": false}, flags)
+	})
+	t.Run("No realm", func(t *testing.T) {
+		var flags, err = e.GetEffectiveFlags(ctx, Subject{})
+		assert.Nil(t, err)
+		assert.Equal(t, map[string]bool{"flag1": true, "flag2": false, "flag3": false}, flags)
+	})
+}
+
+func TestAvailabilityCheckerMW(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockIDRetriever = mock.NewIDRetriever(mockCtrl)
+	var mockConfRetriever = mock.NewAdminConfigurationRetriever(mockCtrl)
+
+	var accessToken = "jwtaccesstoken"
+	var realmName = "myrealm"
+	var realmID = "abcdefgh-1234-5678"
+	var feature = configuration.CheckKeyPhysical
+	var logger = log.NewNopLogger()
+	var e, _ = NewEngine([]Flag{{Name: feature, Rules: []Rule{{Enabled: true, Users: []string{"beta-user"}}}}},
+		NewAvailableChecksProvider(mockIDRetriever, mockConfRetriever))
+	var dummyHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	var m = middleware.MakeEndpointAvailableCheckMW(e.NewAvailabilityChecker(feature), logger)(dummyHandler)
+
+	var ctx = context.WithValue(context.TODO(), cs.CtContextAccessToken, accessToken)
+	ctx = context.WithValue(ctx, cs.CtContextRealm, realmName)
+	var newRequest = func(ctx context.Context) *http.Request {
+		return httptest.NewRequest("POST", "http://cloudtrust.io/api", bytes.NewReader([]byte{})).WithContext(ctx)
+	}
+
+	t.Run("Can't get realm ID", func(t *testing.T) {
+		var w = httptest.NewRecorder()
+		mockIDRetriever.EXPECT().GetID(accessToken, realmName).Return("", errors.New("unexpected"))
+		m.ServeHTTP(w, newRequest(ctx))
+		assert.Equal(t, http.StatusInternalServerError, w.Result().StatusCode)
+	})
+	t.Run("Can't get admin configuration", func(t *testing.T) {
+		var w = httptest.NewRecorder()
+		mockIDRetriever.EXPECT().GetID(accessToken, realmName).Return(realmID, nil)
+		mockConfRetriever.EXPECT().GetAdminConfiguration(gomock.Any(), realmID).Return(configuration.RealmAdminConfiguration{}, errors.New("unexpected"))
+		m.ServeHTTP(w, newRequest(ctx))
+		assert.Equal(t, http.StatusInternalServerError, w.Result().StatusCode)
+	})
+	t.Run("Feature is not enabled", func(t *testing.T) {
+		var w = httptest.NewRecorder()
+		mockIDRetriever.EXPECT().GetID(accessToken, realmName).Return(realmID, nil)
+		mockConfRetriever.EXPECT().GetAdminConfiguration(gomock.Any(), realmID).Return(configuration.RealmAdminConfiguration{}, nil)
+		m.ServeHTTP(w, newRequest(ctx))
+		assert.Equal(t, http.StatusConflict, w.Result().StatusCode)
+	})
+	t.Run("Feature is enabled for the realm", func(t *testing.T) {
+		var w = httptest.NewRecorder()
+		var adminConfig = configuration.RealmAdminConfiguration{AvailableChecks: map[string]bool{feature: true}}
+		mockIDRetriever.EXPECT().GetID(accessToken, realmName).Return(realmID, nil)
+		mockConfRetriever.EXPECT().GetAdminConfiguration(gomock.Any(), realmID).Return(adminConfig, nil)
+		m.ServeHTTP(w, newRequest(ctx))
+		assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+	})
+	t.Run("Feature is enabled for the user", func(t *testing.T) {
+		var w = httptest.NewRecorder()
+		mockIDRetriever.EXPECT().GetID(accessToken, realmName).Return(realmID, nil)
+		mockConfRetriever.EXPECT().GetAdminConfiguration(gomock.Any(), realmID).Return(configuration.RealmAdminConfiguration{}, nil)
+		m.ServeHTTP(w, newRequest(context.WithValue(ctx, cs.CtContextUserID, "beta-user")))
+		assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+	})
+	t.Run("User of another realm is not considered", func(t *testing.T) {
+		var otherRealmID = "other-realm-id"
+		mockIDRetriever.EXPECT().GetID(accessToken, "other").Return(otherRealmID, nil)
+		mockConfRetriever.EXPECT().GetAdminConfiguration(gomock.Any(), otherRealmID).Return(configuration.RealmAdminConfiguration{}, nil)
+		var userCtx = context.WithValue(ctx, cs.CtContextUserID, "beta-user")
+		var _, err = e.NewAvailabilityChecker(feature).CheckAvailabilityForRealm(userCtx, "other", logger)
+		assert.NotNil(t, err)
+	})
+	t.Run("CheckAvailability uses the realm of the context", func(t *testing.T) {
+		mockIDRetriever.EXPECT().GetID(accessToken, realmName).Return(realmID, nil)
+		mockConfRetriever.EXPECT().GetAdminConfiguration(gomock.Any(), realmID).Return(configuration.RealmAdminConfiguration{}, nil)
+		var _, err = e.NewAvailabilityChecker(feature).CheckAvailability(ctx, logger)
+		assert.NotNil(t, err)
+	})
+}
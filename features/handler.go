@@ -0,0 +1,23 @@
+package features
+
+import (
+	"net/http"
+
+	commonhttp "github.com/cloudtrust/common-service/v2/http"
+	"github.com/cloudtrust/common-service/v2/log"
+)
+
+// MakeEffectiveFlagsHandler makes a HTTP handler that returns the feature flags evaluated for the current user
+func MakeEffectiveFlagsHandler(engine Engine, logger log.Logger) http.HandlerFunc {
+	var errorHandler = commonhttp.ErrorHandler(logger)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var ctx = r.Context()
+		var flags, err = engine.GetEffectiveFlags(ctx, SubjectFromContext(ctx))
+		if err != nil {
+			errorHandler(ctx, err, w)
+			return
+		}
+		_ = commonhttp.EncodeReply(ctx, w, flags)
+	})
+}
@@ -0,0 +1,43 @@
+package features
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	cs "github.com/cloudtrust/common-service/v2"
+	"github.com/cloudtrust/common-service/v2/features/mock"
+	"github.com/cloudtrust/common-service/v2/log"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+func TestMakeEffectiveFlagsHandler(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockOverrides = mock.NewRealmOverridesProvider(mockCtrl)
+
+	var e, _ = NewEngine([]Flag{{Name: "flag1", Default: true}, {Name: "flag2"}}, mockOverrides)
+	var handler = MakeEffectiveFlagsHandler(e, log.NewNopLogger())
+	var ctx = context.WithValue(context.TODO(), cs.CtContextRealm, "realm")
+
+	t.Run("Evaluation fails", func(t *testing.T) {
+		var w = httptest.NewRecorder()
+		mockOverrides.EXPECT().GetRealmOverrides(gomock.Any(), "realm").Return(nil, errors.New("error"))
+		handler.ServeHTTP(w, httptest.NewRequest("GET", "http://cloudtrust.io/features", nil).WithContext(ctx))
+		assert.Equal(t, http.StatusInternalServerError, w.Result().StatusCode)
+	})
+	t.Run("Success", func(t *testing.T) {
+		var w = httptest.NewRecorder()
+		mockOverrides.EXPECT().GetRealmOverrides(gomock.Any(), "realm").Return(map[string]bool{"flag2": true}, nil)
+		handler.ServeHTTP(w, httptest.NewRequest("GET", "http://cloudtrust.io/features", nil).WithContext(ctx))
+		assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+
+		var response map[string]bool
+		assert.Nil(t, json.NewDecoder(w.Result().Body).Decode(&response))
+		assert.Equal(t, map[string]bool{"flag1": true, "flag2": true}, response)
+	})
+}
@@ -0,0 +1,56 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/cloudtrust/common-service/v2/features (interfaces: RealmOverridesProvider)
+//
+// Generated by this command:
+//
+//	mockgen --build_flags=--mod=mod -destination=./mock/features.go -package=mock -mock_names=RealmOverridesProvider=RealmOverridesProvider github.com/cloudtrust/common-service/v2/features RealmOverridesProvider
+//
+
+// Package mock is a generated GoMock package.
+package mock
+
+import (
+	context "context"
+	reflect "reflect"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// RealmOverridesProvider is a mock of RealmOverridesProvider interface.
+type RealmOverridesProvider struct {
+	ctrl     *gomock.Controller
+	recorder *RealmOverridesProviderMockRecorder
+	isgomock struct{}
+}
+
+// RealmOverridesProviderMockRecorder is the mock recorder for RealmOverridesProvider.
+type RealmOverridesProviderMockRecorder struct {
+	mock *RealmOverridesProvider
+}
+
+// NewRealmOverridesProvider creates a new mock instance.
+func NewRealmOverridesProvider(ctrl *gomock.Controller) *RealmOverridesProvider {
+	mock := &RealmOverridesProvider{ctrl: ctrl}
+	mock.recorder = &RealmOverridesProviderMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *RealmOverridesProvider) EXPECT() *RealmOverridesProviderMockRecorder {
+	return m.recorder
+}
+
+// GetRealmOverrides mocks base method.
+func (m *RealmOverridesProvider) GetRealmOverrides(ctx context.Context, realm string) (map[string]bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetRealmOverrides", ctx, realm)
+	ret0, _ := ret[0].(map[string]bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetRealmOverrides indicates an expected call of GetRealmOverrides.
+func (mr *RealmOverridesProviderMockRecorder) GetRealmOverrides(ctx, realm any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRealmOverrides", reflect.TypeOf((*RealmOverridesProvider)(nil).GetRealmOverrides), ctx, realm)
+}
@@ -0,0 +1,4 @@
+package features
+
+//go:generate mockgen --build_flags=--mod=mod -destination=./mock/features.go -package=mock -mock_names=RealmOverridesProvider=RealmOverridesProvider github.com/cloudtrust/common-service/v2/features RealmOverridesProvider
+//go:generate mockgen --build_flags=--mod=mod -destination=./mock/middleware.go -package=mock -mock_names=IDRetriever=IDRetriever,AdminConfigurationRetriever=AdminConfigurationRetriever github.com/cloudtrust/common-service/v2/middleware IDRetriever,AdminConfigurationRetriever
@@ -0,0 +1,56 @@
+package healthcheck
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/cloudtrust/common-service/v2/security"
+)
+
+// HealthAuthorizations provides the status of the authorizations reloads. It is implemented by security.AuthorizationManager
+type HealthAuthorizations interface {
+	GetReloadStatus() security.AuthorizationsReloadStatus
+}
+
+type authorizationsChecker struct {
+	alias          string
+	authorizations HealthAuthorizations
+	maxAge         time.Duration
+	response       HealthStatus
+}
+
+// newAuthorizationsChecker creates an authorizations health checker. Authorizations are considered down when they have never
+// been loaded or, if maxAge is not zero, when the last successful reload is older than maxAge
+func newAuthorizationsChecker(alias string, authorizations HealthAuthorizations, maxAge time.Duration, cacheDuration time.Duration, timeProvider TimeProvider) BasicChecker {
+	var typeAuthorizations = "authorizations"
+	return &authorizationsChecker{
+		alias:          alias,
+		authorizations: authorizations,
+		maxAge:         maxAge,
+		response:       HealthStatus{Name: &alias, Type: &typeAuthorizations, CacheDuration: cacheDuration, TimeProvider: timeProvider},
+	}
+}
+
+func (ac *authorizationsChecker) CheckStatus() HealthStatus {
+	if !ac.response.hasExpired() {
+		return ac.response
+	}
+
+	var status = ac.authorizations.GetReloadStatus()
+	var lastError = "none"
+	if status.LastError != nil {
+		lastError = status.LastError.Error()
+	}
+
+	if status.LastSuccess.IsZero() {
+		ac.response.stateDown("Authorizations never loaded. Last error: " + lastError)
+	} else if ac.maxAge > 0 && ac.response.TimeProvider.Now().Sub(status.LastSuccess) > ac.maxAge {
+		ac.response.stateDown(fmt.Sprintf("Authorizations not reloaded since %s. Last error: %s", status.LastSuccess.Format(time.RFC3339), lastError))
+	} else {
+		ac.response.connection(fmt.Sprintf("%d entries loaded at %s", status.Entries, status.LastSuccess.Format(time.RFC3339)))
+		ac.response.stateUp()
+	}
+
+	ac.response.touch()
+	return ac.response
+}
@@ -0,0 +1,62 @@
+package healthcheck
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/cloudtrust/common-service/v2/healthcheck/mock"
+	"github.com/cloudtrust/common-service/v2/log"
+	"github.com/cloudtrust/common-service/v2/security"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+func TestAuthorizationsHealthCheck(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	var mockAuthorizations = mock.NewHealthAuthorizations(mockCtrl)
+	var mockTime = mock.NewTimeProvider(mockCtrl)
+	mockTime.EXPECT().Now().Return(testTime).AnyTimes()
+
+	t.Run("Never loaded", func(t *testing.T) {
+		var checker = newAuthorizationsChecker("alias", mockAuthorizations, time.Hour, 10*time.Second, mockTime)
+		mockAuthorizations.EXPECT().GetReloadStatus().Return(security.AuthorizationsReloadStatus{LastAttempt: testTime, LastError: errors.New("db error")})
+		var res = checker.CheckStatus()
+		assert.Equal(t, "DOWN", *res.State)
+		assert.Equal(t, "Authorizations never loaded. Last error: db error", *res.Message)
+
+		// Result is cached
+		res = checker.CheckStatus()
+		assert.Equal(t, "DOWN", *res.State)
+	})
+	t.Run("Outdated", func(t *testing.T) {
+		var checker = newAuthorizationsChecker("alias", mockAuthorizations, time.Hour, 10*time.Second, mockTime)
+		mockAuthorizations.EXPECT().GetReloadStatus().Return(security.AuthorizationsReloadStatus{LastSuccess: testTime.Add(-2 * time.Hour), Entries: 3})
+		var res = checker.CheckStatus()
+		assert.Equal(t, "DOWN", *res.State)
+		assert.Equal(t, "Authorizations not reloaded since 1998-09-03T13:00:00Z. Last error: none", *res.Message)
+	})
+	t.Run("Up", func(t *testing.T) {
+		var checker = newAuthorizationsChecker("alias", mockAuthorizations, 0, 10*time.Second, mockTime)
+		mockAuthorizations.EXPECT().GetReloadStatus().Return(security.AuthorizationsReloadStatus{LastSuccess: testTime.Add(-2 * time.Hour), Entries: 3})
+		var res = checker.CheckStatus()
+		assert.Equal(t, "UP", *res.State)
+		assert.Equal(t, "3 entries loaded at 1998-09-03T13:00:00Z", *res.Connection)
+	})
+}
+
+func TestAddAuthorizations(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	var mockAuthorizations = mock.NewHealthAuthorizations(mockCtrl)
+	mockAuthorizations.EXPECT().GetReloadStatus().Return(security.AuthorizationsReloadStatus{LastSuccess: time.Now(), Entries: 1})
+
+	var hc = NewHealthChecker("module", log.NewNopLogger())
+	hc.AddAuthorizations("authorizations", mockAuthorizations, time.Minute, time.Minute)
+	var res = hc.CheckStatus()
+	assert.True(t, res.Healthy)
+	assert.Len(t, res.Details, 1)
+}
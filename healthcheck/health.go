@@ -19,6 +19,7 @@ type HealthChecker interface {
 	AddHTTPEndpoints(endpoints map[string]string, timeoutDuration time.Duration, expectedStatus int, cacheDuration time.Duration)
 	AddDatabase(name string, db HealthDatabase, cacheDuration time.Duration)
 	AddAuditEventsReporterModule(name string, reporter events.AuditEventsReporterModule, timeout time.Duration, cacheDuration time.Duration)
+	AddAuthorizations(name string, authorizations HealthAuthorizations, maxAge time.Duration, cacheDuration time.Duration)
 	MakeHandler(rateLimit ratelimit.Allower) http.HandlerFunc
 }
 
@@ -139,6 +140,11 @@ func (hc *healthchecker) AddAuditEventsReporterModule(name string, reporter even
 	hc.AddHealthChecker(name, newAuditEventsReporterChecker(name, reporter, timeout, cacheDuration, hc.logger, RealTimeProvider{}))
 }
 
+func (hc *healthchecker) AddAuthorizations(name string, authorizations HealthAuthorizations, maxAge time.Duration, cacheDuration time.Duration) {
+	hc.logger.Info(context.Background(), "msg", "Adding authorizations", "processor", name)
+	hc.AddHealthChecker(name, newAuthorizationsChecker(name, authorizations, maxAge, cacheDuration, RealTimeProvider{}))
+}
+
 // MakeHandler makes a HTTP handler that returns health check information
 func (hc *healthchecker) MakeHandler(rateLimit ratelimit.Allower) http.HandlerFunc {
 	var ctx = context.Background()
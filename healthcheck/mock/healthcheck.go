@@ -1,9 +1,9 @@
 // Code generated by MockGen. DO NOT EDIT.
-// Source: github.com/cloudtrust/common-service/v2/healthcheck (interfaces: HealthDatabase)
+// Source: github.com/cloudtrust/common-service/v2/healthcheck (interfaces: HealthDatabase,HealthAuthorizations)
 //
 // Generated by this command:
 //
-//	mockgen --build_flags=--mod=mod -destination=./mock/healthcheck.go -package=mock -mock_names=HealthDatabase=HealthDatabase github.com/cloudtrust/common-service/v2/healthcheck HealthDatabase
+//	mockgen --build_flags=--mod=mod -destination=./mock/healthcheck.go -package=mock -mock_names=HealthDatabase=HealthDatabase,HealthAuthorizations=HealthAuthorizations github.com/cloudtrust/common-service/v2/healthcheck HealthDatabase,HealthAuthorizations
 //
 
 // Package mock is a generated GoMock package.
@@ -12,6 +12,7 @@ package mock
 import (
 	reflect "reflect"
 
+	security "github.com/cloudtrust/common-service/v2/security"
 	gomock "go.uber.org/mock/gomock"
 )
 
@@ -52,3 +53,41 @@ func (mr *HealthDatabaseMockRecorder) Ping() *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Ping", reflect.TypeOf((*HealthDatabase)(nil).Ping))
 }
+
+// HealthAuthorizations is a mock of HealthAuthorizations interface.
+type HealthAuthorizations struct {
+	ctrl     *gomock.Controller
+	recorder *HealthAuthorizationsMockRecorder
+	isgomock struct{}
+}
+
+// HealthAuthorizationsMockRecorder is the mock recorder for HealthAuthorizations.
+type HealthAuthorizationsMockRecorder struct {
+	mock *HealthAuthorizations
+}
+
+// NewHealthAuthorizations creates a new mock instance.
+func NewHealthAuthorizations(ctrl *gomock.Controller) *HealthAuthorizations {
+	mock := &HealthAuthorizations{ctrl: ctrl}
+	mock.recorder = &HealthAuthorizationsMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *HealthAuthorizations) EXPECT() *HealthAuthorizationsMockRecorder {
+	return m.recorder
+}
+
+// GetReloadStatus mocks base method.
+func (m *HealthAuthorizations) GetReloadStatus() security.AuthorizationsReloadStatus {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetReloadStatus")
+	ret0, _ := ret[0].(security.AuthorizationsReloadStatus)
+	return ret0
+}
+
+// GetReloadStatus indicates an expected call of GetReloadStatus.
+func (mr *HealthAuthorizationsMockRecorder) GetReloadStatus() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetReloadStatus", reflect.TypeOf((*HealthAuthorizations)(nil).GetReloadStatus))
+}
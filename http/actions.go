@@ -0,0 +1,16 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/cloudtrust/common-service/v2/security"
+)
+
+// MakeActionCatalogHandler makes a HTTP handler that returns the catalog of the actions of the index, grouped by service and API
+func MakeActionCatalogHandler(index *security.ActionsIndex) http.HandlerFunc {
+	var catalog = index.Catalog()
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = EncodeReply(r.Context(), w, catalog)
+	})
+}
@@ -0,0 +1,29 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/cloudtrust/common-service/v2/security"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMakeActionCatalogHandler(t *testing.T) {
+	r := mux.NewRouter()
+	r.Handle("/actions", MakeActionCatalogHandler(&security.Actions))
+
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	res, err := http.Get(ts.URL + "/actions")
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+
+	var catalog security.ActionCatalog
+	assert.Nil(t, json.NewDecoder(res.Body).Decode(&catalog))
+	assert.Equal(t, security.Actions.Catalog(), catalog)
+	assert.Equal(t, "bridge", catalog.Services[0].Name)
+}
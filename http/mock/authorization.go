@@ -12,7 +12,10 @@ package mock
 import (
 	context "context"
 	reflect "reflect"
+	time "time"
 
+	configuration "github.com/cloudtrust/common-service/v2/configuration"
+	security "github.com/cloudtrust/common-service/v2/security"
 	gomock "go.uber.org/mock/gomock"
 )
 
@@ -138,6 +141,91 @@ func (mr *AuthorizationManagerMockRecorder) CheckAuthorizationOnTargetUser(ctx,
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckAuthorizationOnTargetUser", reflect.TypeOf((*AuthorizationManager)(nil).CheckAuthorizationOnTargetUser), ctx, action, targetRealm, userID)
 }
 
+// Explain mocks base method.
+func (m *AuthorizationManager) Explain(ctx context.Context, request security.ExplainRequest) security.AuthorizationDecision {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Explain", ctx, request)
+	ret0, _ := ret[0].(security.AuthorizationDecision)
+	return ret0
+}
+
+// Explain indicates an expected call of Explain.
+func (mr *AuthorizationManagerMockRecorder) Explain(ctx, request any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Explain", reflect.TypeOf((*AuthorizationManager)(nil).Explain), ctx, request)
+}
+
+// FilterAuthorizedTargetGroups mocks base method.
+func (m *AuthorizationManager) FilterAuthorizedTargetGroups(ctx context.Context, action, targetRealm string, targetGroups []string) []string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FilterAuthorizedTargetGroups", ctx, action, targetRealm, targetGroups)
+	ret0, _ := ret[0].([]string)
+	return ret0
+}
+
+// FilterAuthorizedTargetGroups indicates an expected call of FilterAuthorizedTargetGroups.
+func (mr *AuthorizationManagerMockRecorder) FilterAuthorizedTargetGroups(ctx, action, targetRealm, targetGroups any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FilterAuthorizedTargetGroups", reflect.TypeOf((*AuthorizationManager)(nil).FilterAuthorizedTargetGroups), ctx, action, targetRealm, targetGroups)
+}
+
+// FilterAuthorizedTargetUsers mocks base method.
+func (m *AuthorizationManager) FilterAuthorizedTargetUsers(ctx context.Context, action, targetRealm string, userIDs []string) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FilterAuthorizedTargetUsers", ctx, action, targetRealm, userIDs)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FilterAuthorizedTargetUsers indicates an expected call of FilterAuthorizedTargetUsers.
+func (mr *AuthorizationManagerMockRecorder) FilterAuthorizedTargetUsers(ctx, action, targetRealm, userIDs any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FilterAuthorizedTargetUsers", reflect.TypeOf((*AuthorizationManager)(nil).FilterAuthorizedTargetUsers), ctx, action, targetRealm, userIDs)
+}
+
+// FindExceedingAuthorizations mocks base method.
+func (m *AuthorizationManager) FindExceedingAuthorizations(ctx context.Context, authorizations []configuration.Authorization) []configuration.Authorization {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindExceedingAuthorizations", ctx, authorizations)
+	ret0, _ := ret[0].([]configuration.Authorization)
+	return ret0
+}
+
+// FindExceedingAuthorizations indicates an expected call of FindExceedingAuthorizations.
+func (mr *AuthorizationManagerMockRecorder) FindExceedingAuthorizations(ctx, authorizations any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindExceedingAuthorizations", reflect.TypeOf((*AuthorizationManager)(nil).FindExceedingAuthorizations), ctx, authorizations)
+}
+
+// GetAllowedTargetGroups mocks base method.
+func (m *AuthorizationManager) GetAllowedTargetGroups(ctx context.Context, action, targetRealm string) security.AllowedTargetGroups {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAllowedTargetGroups", ctx, action, targetRealm)
+	ret0, _ := ret[0].(security.AllowedTargetGroups)
+	return ret0
+}
+
+// GetAllowedTargetGroups indicates an expected call of GetAllowedTargetGroups.
+func (mr *AuthorizationManagerMockRecorder) GetAllowedTargetGroups(ctx, action, targetRealm any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAllowedTargetGroups", reflect.TypeOf((*AuthorizationManager)(nil).GetAllowedTargetGroups), ctx, action, targetRealm)
+}
+
+// GetReloadStatus mocks base method.
+func (m *AuthorizationManager) GetReloadStatus() security.AuthorizationsReloadStatus {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetReloadStatus")
+	ret0, _ := ret[0].(security.AuthorizationsReloadStatus)
+	return ret0
+}
+
+// GetReloadStatus indicates an expected call of GetReloadStatus.
+func (mr *AuthorizationManagerMockRecorder) GetReloadStatus() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetReloadStatus", reflect.TypeOf((*AuthorizationManager)(nil).GetReloadStatus))
+}
+
 // GetRightsOfCurrentUser mocks base method.
 func (m *AuthorizationManager) GetRightsOfCurrentUser(ctx context.Context) map[string]map[string]map[string]map[string]struct{} {
 	m.ctrl.T.Helper()
@@ -165,3 +253,15 @@ func (mr *AuthorizationManagerMockRecorder) ReloadAuthorizations(ctx any) *gomoc
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReloadAuthorizations", reflect.TypeOf((*AuthorizationManager)(nil).ReloadAuthorizations), ctx)
 }
+
+// StartPeriodicReload mocks base method.
+func (m *AuthorizationManager) StartPeriodicReload(ctx context.Context, interval, jitter time.Duration) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "StartPeriodicReload", ctx, interval, jitter)
+}
+
+// StartPeriodicReload indicates an expected call of StartPeriodicReload.
+func (mr *AuthorizationManagerMockRecorder) StartPeriodicReload(ctx, interval, jitter any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StartPeriodicReload", reflect.TypeOf((*AuthorizationManager)(nil).StartPeriodicReload), ctx, interval, jitter)
+}
@@ -2,13 +2,238 @@ package http
 
 import (
 	"net/http"
+	"net/url"
+	"slices"
+	"strings"
 
+	errorhandler "github.com/cloudtrust/common-service/v2/errors"
 	"github.com/cloudtrust/common-service/v2/security"
 )
 
+// Query parameters of the explain authorization handler
+const (
+	ExplainPrmAction        = "action"
+	ExplainPrmTargetRealm   = "realm"
+	ExplainPrmTargetGroup   = "group"
+	ExplainPrmTargetGroupID = "groupId"
+	ExplainPrmTargetUserID  = "userId"
+	ExplainPrmSubjectRealm  = "subjectRealm"
+	ExplainPrmSubjectUserID = "subjectUserId"
+)
+
+// Query parameters of the rights handler
+const (
+	RightsPrmFormat      = "format"
+	RightsPrmService     = "service"
+	RightsPrmAPI         = "api"
+	RightsPrmTargetRealm = "realm"
+	RightsPrmScopes      = "scopes"
+)
+
+// Formats of the rights handler
+const (
+	RightsFormatNested = "nested"
+	RightsFormatFlat   = "flat"
+)
+
+// Permission is an effective permission of the user, merged across its groups
+type Permission struct {
+	Action       string         `json:"action"`
+	Scope        security.Scope `json:"scope,omitempty"`
+	TargetRealm  string         `json:"targetRealm"`
+	TargetGroups []string       `json:"targetGroups,omitempty"`
+	Denied       bool           `json:"denied,omitempty"`
+}
+
 // MakeRightsHandler makes a HTTP handler that returns information about the rights of the user.
+// Query parameters can select the format and filter the rights:
+//   - format: nested (default) returns the rights as group -> action -> target realm -> target groups, flat returns a list of
+//     permissions merged across the groups
+//   - service and api: only keep the actions of the given service and/or API of the actions catalog
+//   - realm: only keep the rights on the given target realm, including the wildcard realms covering it
+//   - scopes: when true, the flat permissions include the scope of their action
 func MakeRightsHandler(authorizationManager security.AuthorizationManager) http.HandlerFunc {
+	var errorHandler = ErrorHandlerNoLog()
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var ctx = r.Context()
+		var query = r.URL.Query()
+
+		var format = query.Get(RightsPrmFormat)
+		if format != "" && format != RightsFormatNested && format != RightsFormatFlat {
+			errorHandler(ctx, errorhandler.CreateInvalidQueryParameterError(RightsPrmFormat), w)
+			return
+		}
+		var actionFilter, err = makeRightsActionFilter(query.Get(RightsPrmService), query.Get(RightsPrmAPI))
+		if err != nil {
+			errorHandler(ctx, err, w)
+			return
+		}
+		var rights = authorizationManager.GetRightsOfCurrentUser(ctx)
+		if targetRealm := query.Get(RightsPrmTargetRealm); actionFilter != nil || targetRealm != "" {
+			rights = filterRights(rights, actionFilter, targetRealm)
+		}
+
+		if format == RightsFormatFlat {
+			_ = EncodeReply(ctx, w, flattenRights(rights, query.Get(RightsPrmScopes) == "true"))
+			return
+		}
+		_ = EncodeReply(ctx, w, rights)
+	})
+}
+
+// makeRightsActionFilter returns the actions of the catalog matching the service and API names, or nil without filter
+func makeRightsActionFilter(serviceName, apiName string) (map[string]security.Action, error) {
+	if serviceName == "" && apiName == "" {
+		return nil, nil
+	}
+	var res = map[string]security.Action{}
+	var serviceFound, apiFound = serviceName == "", apiName == ""
+	for _, service := range security.Actions.Catalog().Services {
+		if serviceName != "" && service.Name != serviceName {
+			continue
+		}
+		serviceFound = true
+		for _, api := range service.APIs {
+			if apiName != "" && api.Name != apiName {
+				continue
+			}
+			apiFound = true
+			for _, action := range api.Actions {
+				res[action.Name] = security.Action(action)
+			}
+		}
+	}
+	if !serviceFound {
+		return nil, errorhandler.CreateInvalidQueryParameterError(RightsPrmService)
+	}
+	if !apiFound {
+		return nil, errorhandler.CreateInvalidQueryParameterError(RightsPrmAPI)
+	}
+	return res, nil
+}
+
+// filterRights returns the rights on the filtered actions and target realm. Rights are copied as they are shared with the
+// authorization manager
+func filterRights(rights map[string]map[string]map[string]map[string]struct{}, actions map[string]security.Action,
+	targetRealm string) map[string]map[string]map[string]map[string]struct{} {
+	var res = map[string]map[string]map[string]map[string]struct{}{}
+	for group, groupRights := range rights {
+		var filteredGroupRights = map[string]map[string]map[string]struct{}{}
+		for action, targetRealms := range groupRights {
+			if _, ok := actions[action]; actions != nil && !ok {
+				continue
+			}
+			var filteredTargetRealms = map[string]map[string]struct{}{}
+			for realm, targetGroups := range targetRealms {
+				if targetRealm == "" || realmCovers(strings.TrimPrefix(realm, security.DenyPrefix), targetRealm) {
+					filteredTargetRealms[realm] = targetGroups
+				}
+			}
+			if targetRealm == "" || len(filteredTargetRealms) > 0 {
+				filteredGroupRights[action] = filteredTargetRealms
+			}
+		}
+		if len(filteredGroupRights) > 0 {
+			res[group] = filteredGroupRights
+		}
+	}
+	return res
+}
+
+func realmCovers(entryRealm, targetRealm string) bool {
+	return entryRealm == targetRealm || entryRealm == "*" || (entryRealm == "/" && targetRealm != "master")
+}
+
+// flattenRights merges the rights of the groups into a list of permissions sorted by action, target realm and denial
+func flattenRights(rights map[string]map[string]map[string]map[string]struct{}, withScopes bool) []Permission {
+	type permissionKey struct {
+		action      string
+		targetRealm string
+		denied      bool
+	}
+	var targetGroups = map[permissionKey]map[string]struct{}{}
+	for _, groupRights := range rights {
+		for action, targetRealms := range groupRights {
+			for realm, groups := range targetRealms {
+				var key = permissionKey{action: action, targetRealm: strings.TrimPrefix(realm, security.DenyPrefix), denied: strings.HasPrefix(realm, security.DenyPrefix)}
+				if _, ok := targetGroups[key]; !ok {
+					targetGroups[key] = map[string]struct{}{}
+				}
+				for group := range groups {
+					targetGroups[key][group] = struct{}{}
+				}
+			}
+		}
+	}
+
+	var scopes = map[string]security.Scope{}
+	if withScopes {
+		for _, action := range security.Actions.GetAllActions() {
+			scopes[action.Name] = action.Scope
+		}
+	}
+	var res = []Permission{}
+	for key, groups := range targetGroups {
+		var permission = Permission{Action: key.action, Scope: scopes[key.action], TargetRealm: key.targetRealm, Denied: key.denied}
+		for group := range groups {
+			permission.TargetGroups = append(permission.TargetGroups, group)
+		}
+		slices.Sort(permission.TargetGroups)
+		res = append(res, permission)
+	}
+	slices.SortFunc(res, func(a, b Permission) int {
+		if c := strings.Compare(a.Action, b.Action); c != 0 {
+			return c
+		}
+		if c := strings.Compare(a.TargetRealm, b.TargetRealm); c != 0 {
+			return c
+		}
+		if a.Denied == b.Denied {
+			return 0
+		}
+		if a.Denied {
+			return 1
+		}
+		return -1
+	})
+	return res
+}
+
+// MakeExplainAuthorizationHandler makes a HTTP handler that explains the authorization decision of the current user, or of
+// the subject user given as query parameter, for the action given as query parameter on a target realm, optionally on a target
+// group, group ID or user ID.
+// Groups of subjects and target users or groups looked up in Keycloak are only exposed to callers allowed MGMT_ExplainAuthorizations on them
+func MakeExplainAuthorizationHandler(authorizationManager security.AuthorizationManager) http.HandlerFunc {
+	var errorHandler = ErrorHandlerNoLog()
+
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		_ = EncodeReply(r.Context(), w, authorizationManager.GetRightsOfCurrentUser(r.Context()))
+		var ctx = r.Context()
+		var query = r.URL.Query()
+		var request = security.ExplainRequest{
+			Action:        query.Get(ExplainPrmAction),
+			TargetRealm:   query.Get(ExplainPrmTargetRealm),
+			TargetGroup:   optionalQueryParam(query, ExplainPrmTargetGroup),
+			TargetGroupID: optionalQueryParam(query, ExplainPrmTargetGroupID),
+			TargetUserID:  optionalQueryParam(query, ExplainPrmTargetUserID),
+			SubjectRealm:  optionalQueryParam(query, ExplainPrmSubjectRealm),
+			SubjectUserID: optionalQueryParam(query, ExplainPrmSubjectUserID),
+		}
+		if request.Action == "" {
+			errorHandler(ctx, errorhandler.CreateMissingParameterError(ExplainPrmAction), w)
+			return
+		}
+		if request.TargetRealm == "" {
+			errorHandler(ctx, errorhandler.CreateMissingParameterError(ExplainPrmTargetRealm), w)
+			return
+		}
+		_ = EncodeReply(ctx, w, authorizationManager.Explain(ctx, request))
 	})
 }
+
+func optionalQueryParam(query url.Values, name string) *string {
+	if value := query.Get(name); value != "" {
+		return &value
+	}
+	return nil
+}
@@ -8,6 +8,7 @@ import (
 	"testing"
 
 	"github.com/cloudtrust/common-service/v2/http/mock"
+	"github.com/cloudtrust/common-service/v2/security"
 	"github.com/gorilla/mux"
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/mock/gomock"
@@ -50,3 +51,135 @@ func TestMakeRightsHandler(t *testing.T) {
 	assert.Equal(t, response, rights)
 	assert.Nil(t, err)
 }
+
+func TestMakeRightsHandlerFormats(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	mockAuthManager := mock.NewAuthorizationManager(mockCtrl)
+
+	var getUser = security.MGMTGetUser.String()
+	var sendSMS = security.COMSendSMS.String()
+	var rights = map[string]map[string]map[string]map[string]struct{}{
+		"toe": {
+			getUser: {
+				"/":         {"support": {}},
+				"!customer": {"vip": {}},
+			},
+			sendSMS: {
+				"customer": {},
+			},
+		},
+		"svc": {
+			getUser: {
+				"/":       {"sales": {}},
+				"partner": {"*": {}},
+			},
+		},
+	}
+	mockAuthManager.EXPECT().GetRightsOfCurrentUser(gomock.Any()).Return(rights).AnyTimes()
+
+	r := mux.NewRouter()
+	r.Handle("/rights", MakeRightsHandler(mockAuthManager))
+
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	var get = func(t *testing.T, query string, response any) int {
+		res, err := http.Get(ts.URL + "/rights?" + query)
+		assert.Nil(t, err)
+		if response != nil && res.StatusCode == http.StatusOK {
+			assert.Nil(t, json.NewDecoder(res.Body).Decode(response))
+		}
+		return res.StatusCode
+	}
+
+	t.Run("Invalid parameters", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, get(t, "format=xml", nil))
+		assert.Equal(t, http.StatusBadRequest, get(t, "service=unknown", nil))
+		assert.Equal(t, http.StatusBadRequest, get(t, "service=bridge&api=unknown", nil))
+	})
+	t.Run("Flat format", func(t *testing.T) {
+		var response []Permission
+		assert.Equal(t, http.StatusOK, get(t, "format=flat&scopes=true", &response))
+		assert.Equal(t, []Permission{
+			{Action: sendSMS, Scope: security.ScopeRealm, TargetRealm: "customer"},
+			{Action: getUser, Scope: security.ScopeGroup, TargetRealm: "/", TargetGroups: []string{"sales", "support"}},
+			{Action: getUser, Scope: security.ScopeGroup, TargetRealm: "customer", TargetGroups: []string{"vip"}, Denied: true},
+			{Action: getUser, Scope: security.ScopeGroup, TargetRealm: "partner", TargetGroups: []string{"*"}},
+		}, response)
+	})
+	t.Run("Filter by API", func(t *testing.T) {
+		var response []Permission
+		assert.Equal(t, http.StatusOK, get(t, "format=flat&service=bridge&api=communication", &response))
+		assert.Equal(t, []Permission{{Action: sendSMS, TargetRealm: "customer"}}, response)
+	})
+	t.Run("Filter by target realm", func(t *testing.T) {
+		var response map[string]map[string]map[string]map[string]struct{}
+		assert.Equal(t, http.StatusOK, get(t, "realm=partner", &response))
+		assert.Equal(t, map[string]map[string]map[string]map[string]struct{}{
+			"toe": {getUser: {"/": {"support": {}}}},
+			"svc": {getUser: {"/": {"sales": {}}, "partner": {"*": {}}}},
+		}, response)
+		var masterResponse map[string]map[string]map[string]map[string]struct{}
+		assert.Equal(t, http.StatusOK, get(t, "realm=master", &masterResponse))
+		assert.Len(t, masterResponse, 0)
+	})
+}
+
+func TestMakeExplainAuthorizationHandler(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	mockAuthManager := mock.NewAuthorizationManager(mockCtrl)
+
+	r := mux.NewRouter()
+	r.Handle("/explain", MakeExplainAuthorizationHandler(mockAuthManager))
+
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	t.Run("Missing action", func(t *testing.T) {
+		res, err := http.Get(ts.URL + "/explain?realm=master")
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+	})
+	t.Run("Missing realm", func(t *testing.T) {
+		res, err := http.Get(ts.URL + "/explain?action=GetUser")
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+	})
+	t.Run("Success", func(t *testing.T) {
+		var userID = "user-id"
+		var decision = security.AuthorizationDecision{
+			Allowed:         true,
+			Reason:          security.ReasonAllowed,
+			Action:          "GetUser",
+			CurrentRealm:    "master",
+			CurrentGroups:   []string{"toe"},
+			TargetRealm:     "customer",
+			TargetGroups:    []string{"svc"},
+			MatchingEntries: []security.AuthorizationMatch{{Group: "toe", TargetRealm: "*", TargetGroup: "svc", RealmWildcard: true}},
+			KeycloakLookups: []security.KeycloakLookup{{Operation: security.LookupGroupNamesOfUser, Realm: "customer", ID: userID, Result: []string{"svc"}}},
+		}
+		mockAuthManager.EXPECT().Explain(gomock.Any(), security.ExplainRequest{Action: "GetUser", TargetRealm: "customer", TargetUserID: &userID}).Return(decision)
+
+		res, err := http.Get(ts.URL + "/explain?action=GetUser&realm=customer&userId=user-id")
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+
+		var response security.AuthorizationDecision
+		assert.Nil(t, json.NewDecoder(res.Body).Decode(&response))
+		assert.Equal(t, decision, response)
+	})
+	t.Run("Subject", func(t *testing.T) {
+		var subjectRealm = "customer"
+		var subjectUserID = "subject-id"
+		mockAuthManager.EXPECT().Explain(gomock.Any(), security.ExplainRequest{Action: "GetRealm", TargetRealm: "customer", SubjectRealm: &subjectRealm,
+			SubjectUserID: &subjectUserID}).Return(security.AuthorizationDecision{Reason: security.ReasonSubjectNotAllowed})
+
+		res, err := http.Get(ts.URL + "/explain?action=GetRealm&realm=customer&subjectRealm=customer&subjectUserId=subject-id")
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+	})
+}
@@ -193,6 +193,7 @@ func MakeHTTPOIDCTokenValidationMW(keycloakClient KeycloakClient, audienceRequir
 			ctx = context.WithValue(ctx, cs.CtContextGroups, ExtractGroups(jot.GetGroups()))
 			ctx = context.WithValue(ctx, cs.CtContextRoles, jot.GetRoles())
 			ctx = context.WithValue(ctx, cs.CtContextIssuerDomain, issuerDomain)
+			ctx = context.WithValue(ctx, cs.CtContextClaims, extractClaims(accessToken))
 
 			next.ServeHTTP(w, req.WithContext(ctx))
 		})
@@ -238,6 +239,15 @@ func ParseAndValidateOIDCToken(ctx context.Context, accessToken string, keycloak
 	return jot, nil
 }
 
+// extractClaims returns the claims of an access token already validated
+func extractClaims(accessToken string) map[string]any {
+	var claims = jwt.MapClaims{}
+	if _, _, err := jwt.NewParser().ParseUnverified(accessToken, claims); err != nil {
+		return map[string]any{}
+	}
+	return claims
+}
+
 // AssertMatchingAudience checks if the required audience is in the jwt list of audiences
 func AssertMatchingAudience(jwtAudiences []string, requiredAudience string) bool {
 	for _, jwtAudience := range jwtAudiences {
@@ -177,7 +177,9 @@ func checkContextEndpoint(ctx context.Context, request any) (response any, err e
 	var realm = ctx.Value(cs.CtContextRealm).(string)
 	var user = ctx.Value(cs.CtContextUsername).(string)
 	var groups = ctx.Value(cs.CtContextGroups).([]string)
-	if (tokenAudString == accessToken || tokenAudArray == accessToken) && "master" == realm && "admin" == user && len(groups) == 1 && "toe_administrator" == groups[0] {
+	var claims = ctx.Value(cs.CtContextClaims).(map[string]any)
+	if (tokenAudString == accessToken || tokenAudArray == accessToken) && "master" == realm && "admin" == user && len(groups) == 1 && "toe_administrator" == groups[0] &&
+		claims["preferred_username"] == user {
 		return "", nil
 	}
 
@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+
+	cs "github.com/cloudtrust/common-service/v2"
+)
+
+const (
+	hdrForwardedFor = "X-Forwarded-For"
+)
+
+// MakeHTTPClientIPMW stores the IP address of the client in the context. The X-Forwarded-For header is only considered when
+// the request is received from one of the trusted proxies, given as IP addresses or CIDR ranges (invalid values are ignored).
+// The client is then the last address of the header which is not a trusted proxy
+func MakeHTTPClientIPMW(trustedProxies ...string) func(http.Handler) http.Handler {
+	var proxies []netip.Prefix
+	for _, proxy := range trustedProxies {
+		if prefix, err := netip.ParsePrefix(proxy); err == nil {
+			proxies = append(proxies, prefix.Masked())
+		} else if addr, err := netip.ParseAddr(proxy); err == nil {
+			proxies = append(proxies, netip.PrefixFrom(addr, addr.BitLen()))
+		}
+	}
+	var isTrusted = func(addr netip.Addr) bool {
+		for _, proxy := range proxies {
+			if proxy.Contains(addr.Unmap()) {
+				return true
+			}
+		}
+		return false
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			var host, _, err = net.SplitHostPort(req.RemoteAddr)
+			if err != nil {
+				host = req.RemoteAddr
+			}
+			var clientIP, errAddr = netip.ParseAddr(host)
+			if errAddr != nil {
+				next.ServeHTTP(w, req)
+				return
+			}
+
+			if isTrusted(clientIP) {
+				var forwarded = strings.Split(strings.Join(req.Header.Values(hdrForwardedFor), ","), ",")
+				for i := len(forwarded) - 1; i >= 0; i-- {
+					var addr, err = netip.ParseAddr(strings.TrimSpace(forwarded[i]))
+					if err != nil {
+						break
+					}
+					clientIP = addr
+					if !isTrusted(addr) {
+						break
+					}
+				}
+			}
+
+			var ctx = context.WithValue(req.Context(), cs.CtContextClientIP, clientIP.Unmap().String())
+			next.ServeHTTP(w, req.WithContext(ctx))
+		})
+	}
+}
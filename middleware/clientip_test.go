@@ -0,0 +1,45 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	cs "github.com/cloudtrust/common-service/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMakeHTTPClientIPMW(t *testing.T) {
+	var clientIP any
+	var m = MakeHTTPClientIPMW("10.0.0.0/8", "192.168.1.1", "invalid")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		clientIP = r.Context().Value(cs.CtContextClientIP)
+	}))
+
+	var testCases = []struct {
+		name       string
+		remoteAddr string
+		forwarded  []string
+		expected   any
+	}{
+		{name: "Direct client", remoteAddr: "1.2.3.4:1234", expected: "1.2.3.4"},
+		{name: "Forwarded header of untrusted client is ignored", remoteAddr: "1.2.3.4:1234", forwarded: []string{"5.6.7.8"}, expected: "1.2.3.4"},
+		{name: "Trusted proxy", remoteAddr: "10.1.1.1:1234", forwarded: []string{"5.6.7.8"}, expected: "5.6.7.8"},
+		{name: "Chain of trusted proxies", remoteAddr: "192.168.1.1:1234", forwarded: []string{"9.9.9.9, 5.6.7.8", "10.2.2.2"}, expected: "5.6.7.8"},
+		{name: "Only trusted proxies", remoteAddr: "10.1.1.1:1234", forwarded: []string{"10.2.2.2"}, expected: "10.2.2.2"},
+		{name: "Invalid forwarded address", remoteAddr: "10.1.1.1:1234", forwarded: []string{"unknown"}, expected: "10.1.1.1"},
+		{name: "IPv6 client", remoteAddr: "[fd00::1]:1234", expected: "fd00::1"},
+		{name: "Invalid remote address", remoteAddr: "pipe", expected: nil},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			clientIP = nil
+			var req = httptest.NewRequest(http.MethodGet, "http://cloudtrust.io/test", nil)
+			req.RemoteAddr = tc.remoteAddr
+			for _, value := range tc.forwarded {
+				req.Header.Add(hdrForwardedFor, value)
+			}
+			m.ServeHTTP(httptest.NewRecorder(), req)
+			assert.Equal(t, tc.expected, clientIP)
+		})
+	}
+}
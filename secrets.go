@@ -0,0 +1,216 @@
+package commonservice
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Prefixes of the configuration values which reference a secret
+const (
+	SecretPrefixFile   = "file://"
+	SecretPrefixEnv    = "env://"
+	SecretPrefixBase64 = "base64:"
+)
+
+// SecretMask replaces secret values in dumped configurations
+const SecretMask = "********"
+
+// SecretResolvingConfiguration wraps a Configuration to transparently resolve values referencing secrets:
+// file:///run/secrets/x is replaced by the content of the file (without trailing line feed), env://NAME by the value of the
+// environment variable and base64:... by the decoded value. Other values are returned unchanged.
+// A reference which can't be resolved is returned as an empty value: use CheckSecrets at startup to detect them
+type SecretResolvingConfiguration struct {
+	Configuration
+	mutex      sync.RWMutex
+	secretKeys map[string]bool
+	readFile   func(string) ([]byte, error)
+	lookupEnv  func(string) (string, bool)
+}
+
+// NewSecretResolvingConfiguration creates a SecretResolvingConfiguration. The given keys are considered as secret even when
+// their values are not references
+func NewSecretResolvingConfiguration(c Configuration, secretKeys ...string) *SecretResolvingConfiguration {
+	var res = &SecretResolvingConfiguration{
+		Configuration: c,
+		secretKeys:    map[string]bool{},
+		readFile:      os.ReadFile,
+		lookupEnv:     os.LookupEnv,
+	}
+	res.MarkSecret(secretKeys...)
+	return res
+}
+
+// MarkSecret declares keys as secret: their values are masked by Dump
+func (c *SecretResolvingConfiguration) MarkSecret(keys ...string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for _, key := range keys {
+		c.secretKeys[strings.ToLower(key)] = true
+	}
+}
+
+// IsSecret tells if the value of a key is a reference to a secret or has been declared as secret
+func (c *SecretResolvingConfiguration) IsSecret(key string) bool {
+	c.mutex.RLock()
+	var declared = c.secretKeys[strings.ToLower(key)]
+	c.mutex.RUnlock()
+	if declared {
+		return true
+	}
+	var _, isReference = c.reference(key)
+	return isReference
+}
+
+// CheckSecrets resolves the references of the given keys and reports all the failures
+func (c *SecretResolvingConfiguration) CheckSecrets(keys ...string) error {
+	var errs []error
+	for _, key := range keys {
+		if ref, ok := c.reference(key); ok {
+			if _, err := c.resolve(ref); err != nil {
+				errs = append(errs, fmt.Errorf("can't resolve secret of configuration key %s: %w", key, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Dump returns the values of the given keys as key/value pairs which can be provided to a logger. Secrets are masked
+func (c *SecretResolvingConfiguration) Dump(keys ...string) []any {
+	var res = make([]any, 0, 2*len(keys))
+	for _, key := range keys {
+		if c.IsSecret(key) {
+			res = append(res, key, SecretMask)
+		} else {
+			res = append(res, key, c.Configuration.Get(key))
+		}
+	}
+	return res
+}
+
+func (c *SecretResolvingConfiguration) reference(key string) (string, bool) {
+	var value, ok = c.Configuration.Get(key).(string)
+	if !ok {
+		return "", false
+	}
+	for _, prefix := range []string{SecretPrefixFile, SecretPrefixEnv, SecretPrefixBase64} {
+		if strings.HasPrefix(value, prefix) {
+			return value, true
+		}
+	}
+	return "", false
+}
+
+func (c *SecretResolvingConfiguration) resolve(ref string) (string, error) {
+	switch {
+	case strings.HasPrefix(ref, SecretPrefixFile):
+		var content, err = c.readFile(strings.TrimPrefix(ref, SecretPrefixFile))
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimRight(string(content), "\r\n"), nil
+	case strings.HasPrefix(ref, SecretPrefixEnv):
+		var name = strings.TrimPrefix(ref, SecretPrefixEnv)
+		if value, ok := c.lookupEnv(name); ok {
+			return value, nil
+		}
+		return "", fmt.Errorf("environment variable %s is not set", name)
+	default:
+		var decoded, err = base64.StdEncoding.DecodeString(strings.TrimPrefix(ref, SecretPrefixBase64))
+		return string(decoded), err
+	}
+}
+
+// resolved returns the resolved value of a key when it is a reference
+func (c *SecretResolvingConfiguration) resolved(key string) (string, bool) {
+	var ref, ok = c.reference(key)
+	if !ok {
+		return "", false
+	}
+	var value, _ = c.resolve(ref)
+	return value, true
+}
+
+// Get returns the value of a key, resolving secret references
+func (c *SecretResolvingConfiguration) Get(key string) any {
+	if value, ok := c.resolved(key); ok {
+		return value
+	}
+	return c.Configuration.Get(key)
+}
+
+// GetString returns the value of a key as a string, resolving secret references
+func (c *SecretResolvingConfiguration) GetString(key string) string {
+	if value, ok := c.resolved(key); ok {
+		return value
+	}
+	return c.Configuration.GetString(key)
+}
+
+// GetStringSlice returns the value of a key as a slice of strings, resolving secret references
+func (c *SecretResolvingConfiguration) GetStringSlice(key string) []string {
+	if value, ok := c.resolved(key); ok {
+		return configurationStringSlice(value)
+	}
+	return c.Configuration.GetStringSlice(key)
+}
+
+// GetBool returns the value of a key as a boolean, resolving secret references
+func (c *SecretResolvingConfiguration) GetBool(key string) bool {
+	if value, ok := c.resolved(key); ok {
+		return configurationBool(value)
+	}
+	return c.Configuration.GetBool(key)
+}
+
+// GetInt returns the value of a key as an int, resolving secret references
+func (c *SecretResolvingConfiguration) GetInt(key string) int {
+	if value, ok := c.resolved(key); ok {
+		return int(configurationInt64(value))
+	}
+	return c.Configuration.GetInt(key)
+}
+
+// GetInt32 returns the value of a key as an int32, resolving secret references
+func (c *SecretResolvingConfiguration) GetInt32(key string) int32 {
+	if value, ok := c.resolved(key); ok {
+		return int32(configurationInt64(value))
+	}
+	return c.Configuration.GetInt32(key)
+}
+
+// GetInt64 returns the value of a key as an int64, resolving secret references
+func (c *SecretResolvingConfiguration) GetInt64(key string) int64 {
+	if value, ok := c.resolved(key); ok {
+		return configurationInt64(value)
+	}
+	return c.Configuration.GetInt64(key)
+}
+
+// GetFloat64 returns the value of a key as a float64, resolving secret references
+func (c *SecretResolvingConfiguration) GetFloat64(key string) float64 {
+	if value, ok := c.resolved(key); ok {
+		return configurationFloat64(value)
+	}
+	return c.Configuration.GetFloat64(key)
+}
+
+// GetTime returns the value of a key as a time, resolving secret references
+func (c *SecretResolvingConfiguration) GetTime(key string) time.Time {
+	if value, ok := c.resolved(key); ok {
+		return configurationTime(value)
+	}
+	return c.Configuration.GetTime(key)
+}
+
+// GetDuration returns the value of a key as a duration, resolving secret references
+func (c *SecretResolvingConfiguration) GetDuration(key string) time.Duration {
+	if value, ok := c.resolved(key); ok {
+		return configurationDuration(value)
+	}
+	return c.Configuration.GetDuration(key)
+}
@@ -0,0 +1,88 @@
+package commonservice
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSecretResolvingConfiguration(t *testing.T) {
+	var base = NewBasicConfiguration()
+	base.Set("db-host-port", "localhost:3306")
+	base.Set("db-username", "user")
+	base.Set("db-password", "file:///run/secrets/db-password")
+	base.Set("kafka-client-secret", "env://KAFKA_SECRET")
+	base.Set("api-key", "base64:c2VjcmV0LWtleQ==")
+	base.Set("max-conns", "env://MAX_CONNS")
+	base.Set("timeout", "base64:NXM=")
+	base.Set("enabled", "env://ENABLED")
+	base.Set("ratio", "base64:MC41")
+	base.Set("origins", "base64:YSBi")
+	base.Set("not-before", "base64:MjAyNC0wMS0wMg==")
+	base.Set("missing-file", "file:///run/secrets/missing")
+	base.Set("missing-env", "env://MISSING")
+	base.Set("invalid-base64", "base64:???")
+
+	var conf = NewSecretResolvingConfiguration(base, "db-username")
+	conf.readFile = func(path string) ([]byte, error) {
+		if path == "/run/secrets/db-password" {
+			return []byte("db-secret\n"), nil
+		}
+		return nil, errors.New("file not found")
+	}
+	conf.lookupEnv = func(name string) (string, bool) {
+		var envs = map[string]string{"KAFKA_SECRET": "kafka-secret", "MAX_CONNS": "25", "ENABLED": "true"}
+		var value, ok = envs[name]
+		return value, ok
+	}
+
+	t.Run("Resolve references", func(t *testing.T) {
+		assert.Equal(t, "db-secret", conf.GetString("db-password"))
+		assert.Equal(t, "db-secret", conf.Get("db-password"))
+		assert.Equal(t, "kafka-secret", conf.GetString("kafka-client-secret"))
+		assert.Equal(t, "secret-key", conf.GetString("api-key"))
+		assert.Equal(t, 25, conf.GetInt("max-conns"))
+		assert.Equal(t, int32(25), conf.GetInt32("max-conns"))
+		assert.Equal(t, int64(25), conf.GetInt64("max-conns"))
+		assert.Equal(t, 5*time.Second, conf.GetDuration("timeout"))
+		assert.True(t, conf.GetBool("enabled"))
+		assert.Equal(t, 0.5, conf.GetFloat64("ratio"))
+		assert.Equal(t, []string{"a", "b"}, conf.GetStringSlice("origins"))
+		assert.Equal(t, time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC), conf.GetTime("not-before"))
+		assert.Equal(t, "", conf.GetString("missing-file"))
+	})
+	t.Run("Plain values are unchanged", func(t *testing.T) {
+		base.Set("plain-int", 3)
+		assert.Equal(t, "localhost:3306", conf.GetString("db-host-port"))
+		assert.Equal(t, "localhost:3306", conf.Get("db-host-port"))
+		assert.Equal(t, 3, conf.GetInt("plain-int"))
+		assert.Equal(t, int32(3), conf.GetInt32("plain-int"))
+		assert.Equal(t, int64(3), conf.GetInt64("plain-int"))
+		assert.Equal(t, float64(3), conf.GetFloat64("plain-int"))
+		assert.Equal(t, time.Duration(3), conf.GetDuration("plain-int"))
+		assert.False(t, conf.GetBool("plain-int-missing"))
+		assert.Equal(t, []string{}, conf.GetStringSlice("plain-int-missing"))
+		assert.Equal(t, time.Time{}, conf.GetTime("plain-int-missing"))
+	})
+	t.Run("Check secrets", func(t *testing.T) {
+		assert.Nil(t, conf.CheckSecrets("db-host-port", "db-password", "kafka-client-secret", "api-key"))
+
+		var err = conf.CheckSecrets("missing-file", "missing-env", "invalid-base64")
+		assert.NotNil(t, err)
+		assert.Contains(t, err.Error(), "missing-file")
+		assert.Contains(t, err.Error(), "missing-env")
+		assert.Contains(t, err.Error(), "invalid-base64")
+	})
+	t.Run("Dump masks secrets", func(t *testing.T) {
+		conf.MarkSecret("Max-Conns")
+		assert.Equal(t, []any{
+			"db-host-port", "localhost:3306",
+			"db-username", SecretMask,
+			"db-password", SecretMask,
+			"kafka-client-secret", SecretMask,
+			"max-conns", SecretMask,
+		}, conf.Dump("db-host-port", "db-username", "db-password", "kafka-client-secret", "max-conns"))
+	})
+}
@@ -0,0 +1,138 @@
+package security
+
+import (
+	"context"
+	"maps"
+	"slices"
+
+	"github.com/cloudtrust/common-service/v2/configuration"
+)
+
+// Issues reported when validating authorizations against the actions catalog
+const (
+	IssueUnknownAction         = "unknown action"
+	IssueMissingTargetRealm    = "no target realm on a realm or group scope action"
+	IssueUnexpectedTargetGroup = "target group on a global or realm scope action"
+	IssueMissingTargetGroup    = "no target group on a group scope action"
+)
+
+// ActionCatalog lists the actions of the services, grouped by API
+type ActionCatalog struct {
+	Services []ServiceCatalog `json:"services"`
+}
+
+// ServiceCatalog lists the actions of a service, grouped by API
+type ServiceCatalog struct {
+	Name string       `json:"name"`
+	APIs []APICatalog `json:"apis"`
+}
+
+// APICatalog lists the actions of an API
+type APICatalog struct {
+	Name    string          `json:"name"`
+	Actions []CatalogAction `json:"actions"`
+}
+
+// CatalogAction is an action of the catalog
+type CatalogAction struct {
+	Name  string `json:"name"`
+	Scope Scope  `json:"scope"`
+}
+
+// AuthorizationIssue is an authorization which is not consistent with the actions catalog
+type AuthorizationIssue struct {
+	Authorization configuration.Authorization
+	Issue         string
+}
+
+// Catalog returns the actions of the index. Services and APIs are sorted in the order of their declaration, actions in the
+// order they were added
+func (a *ActionsIndex) Catalog() ActionCatalog {
+	var res = ActionCatalog{Services: []ServiceCatalog{}}
+	for _, service := range slices.Sorted(maps.Keys(a.index)) {
+		var serviceCatalog = ServiceCatalog{Name: service.String(), APIs: []APICatalog{}}
+		for _, api := range slices.Sorted(maps.Keys(a.index[service])) {
+			var apiCatalog = APICatalog{Name: api.String(), Actions: []CatalogAction{}}
+			for _, action := range a.index[service][api] {
+				apiCatalog.Actions = append(apiCatalog.Actions, CatalogAction(action))
+			}
+			serviceCatalog.APIs = append(serviceCatalog.APIs, apiCatalog)
+		}
+		res.Services = append(res.Services, serviceCatalog)
+	}
+	return res
+}
+
+// GetAction returns the action with the given name
+func (a *ActionsIndex) GetAction(name string) (Action, bool) {
+	for _, action := range a.GetAllActions() {
+		if action.Name == name {
+			return action, true
+		}
+	}
+	return Action{}, false
+}
+
+// ValidateAuthorizations checks that the actions of the authorizations are known and that their targets are consistent with
+// the scope of the actions:
+//   - realm and group scope actions need a target realm
+//   - global and realm scope actions can't have a target group
+//   - group scope authorizations need a target group, except deny entries which can deny a whole realm
+func (a *ActionsIndex) ValidateAuthorizations(authorizations []configuration.Authorization) []AuthorizationIssue {
+	var scopes = map[string]Scope{}
+	for _, action := range a.GetAllActions() {
+		scopes[action.Name] = action.Scope
+	}
+
+	var res []AuthorizationIssue
+	for _, authz := range authorizations {
+		if issue := validateAuthorizationScope(authz, scopes); issue != "" {
+			res = append(res, AuthorizationIssue{Authorization: authz, Issue: issue})
+		}
+	}
+	return res
+}
+
+func validateAuthorizationScope(authz configuration.Authorization, scopes map[string]Scope) string {
+	if authz.Action == nil {
+		return IssueUnknownAction
+	}
+	var scope, ok = scopes[*authz.Action]
+	switch {
+	case !ok:
+		return IssueUnknownAction
+	case scope != ScopeGlobal && authz.TargetRealmID == nil:
+		return IssueMissingTargetRealm
+	case scope != ScopeGroup && authz.TargetGroupName != nil:
+		return IssueUnexpectedTargetGroup
+	case scope == ScopeGroup && authz.TargetGroupName == nil && !authz.Deny:
+		return IssueMissingTargetGroup
+	}
+	return ""
+}
+
+// WithActionsValidation makes the manager validate the loaded authorizations against the actions of the index. Inconsistent
+// authorizations are logged as warnings but still loaded
+func WithActionsValidation(index *ActionsIndex) AuthorizationManagerOption {
+	return func(am *authorizationManager) {
+		am.actionsIndex = index
+	}
+}
+
+func (am *authorizationManager) logAuthorizationIssues(ctx context.Context, authorizations []configuration.Authorization) {
+	if am.actionsIndex == nil {
+		return
+	}
+	for _, issue := range am.actionsIndex.ValidateAuthorizations(authorizations) {
+		var authz = issue.Authorization
+		am.logger.Warn(ctx, "msg", "Inconsistent authorization: "+issue.Issue, "realm", valueOf(authz.RealmID), "group", valueOf(authz.GroupName),
+			"action", valueOf(authz.Action), "targetRealm", valueOf(authz.TargetRealmID), "targetGroup", valueOf(authz.TargetGroupName))
+	}
+}
+
+func valueOf(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
@@ -0,0 +1,97 @@
+package security
+
+import (
+	"testing"
+
+	"github.com/cloudtrust/common-service/v2/configuration"
+	"github.com/cloudtrust/common-service/v2/security/mock"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+func TestActionsCatalog(t *testing.T) {
+	var index = ActionsIndex{index: map[Service]map[API][]Action{}}
+	var getUser = index.addAction(BridgeService, ManagementAPI, "MGMT_GetUser", ScopeGroup)
+	var getRealms = index.addAction(BridgeService, ManagementAPI, "MGMT_GetRealms", ScopeGlobal)
+	var sendSMS = index.addAction(BridgeService, CommunicationAPI, "COM_SendSMS", ScopeRealm)
+	var getEvents = index.addAction(EventService, EventsAPI, "EV_GetEvents", ScopeRealm)
+
+	t.Run("Catalog", func(t *testing.T) {
+		assert.Equal(t, ActionCatalog{Services: []ServiceCatalog{
+			{Name: "bridge", APIs: []APICatalog{
+				{Name: "communication", Actions: []CatalogAction{CatalogAction(sendSMS)}},
+				{Name: "management", Actions: []CatalogAction{CatalogAction(getUser), CatalogAction(getRealms)}},
+			}},
+			{Name: "event", APIs: []APICatalog{
+				{Name: "events", Actions: []CatalogAction{CatalogAction(getEvents)}},
+			}},
+		}}, index.Catalog())
+		assert.Len(t, flattenCatalog(Actions.Catalog()), len(Actions.GetAllActions()))
+	})
+	t.Run("GetAction", func(t *testing.T) {
+		var action, ok = index.GetAction("MGMT_GetUser")
+		assert.True(t, ok)
+		assert.Equal(t, getUser, action)
+		_, ok = index.GetAction("unknown")
+		assert.False(t, ok)
+	})
+	t.Run("Names of services and APIs", func(t *testing.T) {
+		assert.Equal(t, "externalidp", ExternalIDPService.String())
+		assert.Equal(t, "service-99", Service(99).String())
+		assert.Equal(t, "idp", IdpAPI.String())
+		assert.Equal(t, "api-99", API(99).String())
+	})
+	t.Run("Validate authorizations", func(t *testing.T) {
+		var realm = "master"
+		var group = "toe"
+		var targetRealm = "customer"
+		var targetGroup = "support"
+		var unknown = "unknown"
+		var authorizations = []configuration.Authorization{
+			{RealmID: &realm, GroupName: &group, Action: &getUser.Name, TargetRealmID: &targetRealm, TargetGroupName: &targetGroup},
+			{RealmID: &realm, GroupName: &group, Action: &getUser.Name, TargetRealmID: &targetRealm, Deny: true},
+			{RealmID: &realm, GroupName: &group, Action: &getRealms.Name},
+			{RealmID: &realm, GroupName: &group, Action: &sendSMS.Name, TargetRealmID: &targetRealm},
+			{RealmID: &realm, GroupName: &group, Action: &unknown, TargetRealmID: &targetRealm},
+			{RealmID: &realm, GroupName: &group, Action: &sendSMS.Name},
+			{RealmID: &realm, GroupName: &group, Action: &sendSMS.Name, TargetRealmID: &targetRealm, TargetGroupName: &targetGroup},
+			{RealmID: &realm, GroupName: &group, Action: &getUser.Name, TargetRealmID: &targetRealm},
+		}
+		assert.Equal(t, []AuthorizationIssue{
+			{Authorization: authorizations[4], Issue: IssueUnknownAction},
+			{Authorization: authorizations[5], Issue: IssueMissingTargetRealm},
+			{Authorization: authorizations[6], Issue: IssueUnexpectedTargetGroup},
+			{Authorization: authorizations[7], Issue: IssueMissingTargetGroup},
+		}, index.ValidateAuthorizations(authorizations))
+	})
+}
+
+func TestActionsValidationOnLoad(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockKeycloakClient = mock.NewKeycloakClient(mockCtrl)
+	var mockAuthorizationDBReader = mock.NewAuthorizationDBReader(mockCtrl)
+	var mockLogger = mock.NewLogger(mockCtrl)
+
+	var realm = "master"
+	var group = "toe"
+	var unknown = "unknown"
+	mockAuthorizationDBReader.EXPECT().GetAuthorizations(gomock.Any()).Return([]configuration.Authorization{
+		{RealmID: &realm, GroupName: &group, Action: &unknown},
+	}, nil)
+	mockLogger.EXPECT().Info(gomock.Any(), gomock.Any()).AnyTimes()
+	mockLogger.EXPECT().Warn(gomock.Any(), "msg", "Inconsistent authorization: "+IssueUnknownAction, "realm", realm, "group", group,
+		"action", unknown, "targetRealm", "", "targetGroup", "")
+	var _, err = NewAuthorizationManager(mockAuthorizationDBReader, mockKeycloakClient, mockLogger, WithActionsValidation(&Actions))
+	assert.Nil(t, err)
+}
+
+func flattenCatalog(catalog ActionCatalog) []CatalogAction {
+	var res []CatalogAction
+	for _, service := range catalog.Services {
+		for _, api := range service.APIs {
+			res = append(res, api.Actions...)
+		}
+	}
+	return res
+}
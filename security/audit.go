@@ -0,0 +1,70 @@
+package security
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	cs "github.com/cloudtrust/common-service/v2"
+	"github.com/cloudtrust/common-service/v2/events"
+)
+
+// Audit events reported by the authorization manager
+const (
+	AuthorizationDeniedEventType  = "AUTHORIZATION_DENIED"
+	AuthorizationGrantedEventType = "AUTHORIZATION_GRANTED"
+
+	AuditEventAction      = "action"
+	AuditEventAgentGroups = "agent_groups"
+	AuditEventError       = "error"
+)
+
+type auditEvents struct {
+	reporter         events.AuditEventsReporterModule
+	origin           string
+	sensitiveActions map[string]struct{}
+}
+
+// WithAuditEvents makes the manager report an audit event for each denied authorization check performed with a request context
+// (Check*On* methods). Allowed checks are also reported for the given sensitive actions.
+// Events are reported with the given origin and include the action, the target and the caller
+func WithAuditEvents(reporter events.AuditEventsReporterModule, origin string, sensitiveActions ...string) AuthorizationManagerOption {
+	return func(am *authorizationManager) {
+		var audit = &auditEvents{
+			reporter:         reporter,
+			origin:           origin,
+			sensitiveActions: map[string]struct{}{},
+		}
+		for _, action := range sensitiveActions {
+			audit.sensitiveActions[action] = struct{}{}
+		}
+		am.audit = audit
+	}
+}
+
+// reportAuthorization reports the result of an authorization check when audit events are enabled
+func (am *authorizationManager) reportAuthorization(ctx context.Context, err error, action, targetRealm string, details map[string]string) {
+	if am.audit == nil {
+		return
+	}
+	var eventType = AuthorizationDeniedEventType
+	if err == nil {
+		if _, ok := am.audit.sensitiveActions[action]; !ok {
+			return
+		}
+		eventType = AuthorizationGrantedEventType
+	}
+
+	if details == nil {
+		details = map[string]string{}
+	}
+	details[AuditEventAction] = action
+	if groups, ok := ctx.Value(cs.CtContextGroups).([]string); ok {
+		details[AuditEventAgentGroups] = strings.Join(groups, "|")
+	}
+	// Forbidden errors carry no information, other ones (such as Keycloak failures) are reported
+	if err != nil && !errors.As(err, &ForbiddenError{}) {
+		details[AuditEventError] = err.Error()
+	}
+	am.audit.reporter.ReportEvent(ctx, events.NewEventFromContext(ctx, am.logger, am.audit.origin, eventType, targetRealm, details))
+}
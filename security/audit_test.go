@@ -0,0 +1,73 @@
+package security
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	cs "github.com/cloudtrust/common-service/v2"
+	"github.com/cloudtrust/common-service/v2/configuration"
+	"github.com/cloudtrust/common-service/v2/log"
+	"github.com/cloudtrust/common-service/v2/security/mock"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+func TestAuditEvents(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockKeycloakClient = mock.NewKeycloakClient(mockCtrl)
+	var mockAuthorizationDBReader = mock.NewAuthorizationDBReader(mockCtrl)
+	var mockReporter = mock.NewAuditEventsReporterModule(mockCtrl)
+
+	var accessToken = "TOKEN=="
+	var master = "master"
+	var customer = "customer"
+	var toe = "toe"
+	var support = "support"
+	var vip = "vip"
+	var getUser = "GetUser"
+	var deleteUser = "DeleteUser"
+	var userID = "user-id"
+
+	mockAuthorizationDBReader.EXPECT().GetAuthorizations(gomock.Any()).Return([]configuration.Authorization{
+		{RealmID: &master, GroupName: &toe, Action: &getUser, TargetRealmID: &customer, TargetGroupName: &support},
+		{RealmID: &master, GroupName: &toe, Action: &deleteUser, TargetRealmID: &customer, TargetGroupName: &support},
+	}, nil)
+	var manager, err = NewAuthorizationManager(mockAuthorizationDBReader, mockKeycloakClient, log.NewNopLogger(),
+		WithAuditEvents(mockReporter, "back-office", deleteUser))
+	assert.Nil(t, err)
+
+	var ctx = context.WithValue(context.Background(), cs.CtContextAccessToken, accessToken)
+	ctx = context.WithValue(ctx, cs.CtContextGroups, []string{toe})
+	ctx = context.WithValue(ctx, cs.CtContextRealm, master)
+	ctx = context.WithValue(ctx, cs.CtContextUserID, "agent-id")
+	ctx = context.WithValue(ctx, cs.CtContextUsername, "agent")
+
+	t.Run("Allowed action is not reported", func(t *testing.T) {
+		assert.Nil(t, manager.CheckAuthorizationOnTargetGroup(ctx, getUser, customer, support))
+		assert.Nil(t, manager.CheckAuthorizationOnTargetRealm(ctx, getUser, customer))
+	})
+	t.Run("Allowed sensitive action is reported", func(t *testing.T) {
+		mockReporter.EXPECT().ReportEvent(ctx, gomock.Any())
+		assert.Nil(t, manager.CheckAuthorizationOnTargetGroup(ctx, deleteUser, customer, support))
+	})
+	t.Run("Denials are reported", func(t *testing.T) {
+		mockReporter.EXPECT().ReportEvent(ctx, gomock.Any()).Times(2)
+		assert.NotNil(t, manager.CheckAuthorizationOnTargetGroup(ctx, getUser, customer, vip))
+		assert.NotNil(t, manager.CheckAuthorizationOnTargetRealm(ctx, getUser, master))
+	})
+	t.Run("Denial on target user is reported once", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().GetGroupNamesOfUser(ctx, accessToken, customer, userID).Return([]string{vip, "other"}, nil)
+		mockReporter.EXPECT().ReportEvent(ctx, gomock.Any())
+		assert.NotNil(t, manager.CheckAuthorizationOnTargetUser(ctx, getUser, customer, userID))
+	})
+	t.Run("Keycloak failure is reported", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().GetGroupNamesOfUser(ctx, accessToken, customer, userID).Return(nil, errors.New("keycloak error"))
+		mockReporter.EXPECT().ReportEvent(ctx, gomock.Any())
+		assert.NotNil(t, manager.CheckAuthorizationOnTargetUser(ctx, getUser, customer, userID))
+	})
+	t.Run("Checks without request context are not reported", func(t *testing.T) {
+		assert.NotNil(t, manager.CheckAuthorizationForGroupsOnTargetGroup(master, []string{toe}, getUser, customer, vip))
+	})
+}
@@ -1,5 +1,7 @@
 package security
 
+import "fmt"
+
 // Scope type
 type Scope string
 
@@ -36,6 +38,25 @@ const (
 	ExternalIDPService
 )
 
+var serviceNames = map[Service]string{
+	BridgeService:        "bridge",
+	EventService:         "event",
+	IDNowService:         "idnow",
+	PaperCardService:     "papercard",
+	SchedulerService:     "scheduler",
+	VoucherService:       "voucher",
+	AccreditationService: "accreditation",
+	LifecycleService:     "lifecycle",
+	ExternalIDPService:   "externalidp",
+}
+
+func (s Service) String() string {
+	if name, ok := serviceNames[s]; ok {
+		return name
+	}
+	return fmt.Sprintf("service-%d", int(s))
+}
+
 // API type
 type API int
 
@@ -54,6 +75,27 @@ const (
 	IdpAPI
 )
 
+var apiNames = map[API]string{
+	CommunicationAPI:  "communication",
+	EventsAPI:         "events",
+	KycAPI:            "kyc",
+	ManagementAPI:     "management",
+	StatisticAPI:      "statistic",
+	TaskAPI:           "task",
+	IDNowAPI:          "idnow",
+	CardsAPI:          "cards",
+	SchedulerAPI:      "scheduler",
+	EventStatisticAPI: "eventstatistic",
+	IdpAPI:            "idp",
+}
+
+func (a API) String() string {
+	if name, ok := apiNames[a]; ok {
+		return name
+	}
+	return fmt.Sprintf("api-%d", int(a))
+}
+
 // ActionsIndex struct
 type ActionsIndex struct {
 	index map[Service]map[API][]Action
@@ -90,6 +132,15 @@ func (a *ActionsIndex) GetAllActions() []Action {
 	return res
 }
 
+// GetAllActionNames returns the names of all known actions
+func (a *ActionsIndex) GetAllActionNames() []string {
+	var names []string
+	for _, action := range a.GetAllActions() {
+		names = append(names, action.Name)
+	}
+	return names
+}
+
 // GetActionNamesForAPIs returns a list of names
 func (a *ActionsIndex) GetActionNamesForAPIs(service Service, apis ...API) []string {
 	var names []string
@@ -214,6 +265,7 @@ var (
 	MGMTGetThemeConfiguration               = Actions.addAction(BridgeService, ManagementAPI, "MGMT_GetThemeConfiguration", ScopeRealm)
 	MGMTUpdateThemeConfiguration            = Actions.addAction(BridgeService, ManagementAPI, "MGMT_UpdateThemeConfiguration", ScopeRealm)
 	MGMTGetThemeTranslations                = Actions.addAction(BridgeService, ManagementAPI, "MGMT_GetThemeTranslations", ScopeRealm)
+	MGMTExplainAuthorizations               = Actions.addAction(BridgeService, ManagementAPI, "MGMT_ExplainAuthorizations", ScopeGroup)
 
 	STGetActions                   = Actions.addAction(BridgeService, StatisticAPI, "ST_GetActions", ScopeGlobal)
 	STGetStatisticsIdentifications = Actions.addAction(BridgeService, StatisticAPI, "ST_GetStatisticsIdentifications", ScopeRealm)
@@ -3,21 +3,53 @@ package security
 import (
 	"context"
 	"encoding/json"
+	"math/rand/v2"
 	"net/http"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	cs "github.com/cloudtrust/common-service/v2"
 	"github.com/cloudtrust/common-service/v2/configuration"
 	errorhandler "github.com/cloudtrust/common-service/v2/errors"
+	"github.com/cloudtrust/common-service/v2/events"
 	"github.com/cloudtrust/common-service/v2/log"
 	"github.com/pkg/errors"
 )
 
 type authorizationManager struct {
-	authorizations        *AuthorizationsMatrix
+	authorizations        atomic.Pointer[loadedAuthorizations]
 	authorizationDBReader AuthorizationDBReader
 	keycloakClient        KeycloakClient
 	logger                log.Logger
+	now                   func() time.Time
+	statusMutex           sync.Mutex
+	status                AuthorizationsReloadStatus
+	periodicReload        atomic.Bool
+	hierarchicalGroups    bool
+	actionsIndex          *ActionsIndex
+	audit                 *auditEvents
+}
+
+// loadedAuthorizations holds the allow and deny matrices and the conditions of their entries, loaded together so that they are
+// always swapped consistently
+type loadedAuthorizations struct {
+	allowed           AuthorizationsMatrix
+	denied            AuthorizationsMatrix
+	allowedConditions entriesConditions
+	deniedConditions  entriesConditions
+}
+
+// AuthorizationManagerOption configures optional behaviors of an AuthorizationManager
+type AuthorizationManagerOption func(*authorizationManager)
+
+// AuthorizationsReloadStatus describes the result of the authorizations reloads
+type AuthorizationsReloadStatus struct {
+	LastAttempt time.Time
+	LastSuccess time.Time
+	Entries     int
+	LastError   error
 }
 
 // KeycloakClient is the minimum interface required to access Keycloak
@@ -40,8 +72,15 @@ type AuthorizationManager interface {
 	CheckAuthorizationOnTargetGroupID(ctx context.Context, action, targetRealm, targetGroupID string) error
 	CheckAuthorizationOnTargetUser(ctx context.Context, action, targetRealm, userID string) error
 	CheckAuthorizationOnSelfUser(ctx context.Context, action string) error
+	FilterAuthorizedTargetGroups(ctx context.Context, action, targetRealm string, targetGroups []string) []string
+	FilterAuthorizedTargetUsers(ctx context.Context, action, targetRealm string, userIDs []string) ([]string, error)
+	GetAllowedTargetGroups(ctx context.Context, action, targetRealm string) AllowedTargetGroups
+	FindExceedingAuthorizations(ctx context.Context, authorizations []configuration.Authorization) []configuration.Authorization
 	GetRightsOfCurrentUser(ctx context.Context) map[string]map[string]map[string]map[string]struct{}
+	Explain(ctx context.Context, request ExplainRequest) AuthorizationDecision
 	ReloadAuthorizations(ctx context.Context) error
+	StartPeriodicReload(ctx context.Context, interval, jitter time.Duration)
+	GetReloadStatus() AuthorizationsReloadStatus
 }
 
 // Authorizations data structure
@@ -60,11 +99,20 @@ type AuthorizationManager interface {
 //	'*' can be used to express all target realms
 //	'/' can be used to express all non master realms
 //	'*' can be used to express all target groups are allowed
-func NewAuthorizationManager(authorizationDBReader AuthorizationDBReader, keycloakClient KeycloakClient, logger log.Logger) (AuthorizationManager, error) {
+//
+// Deny entries are loaded in a distinct matrix and take precedence over the allowed ones (see DenyPrefix)
+//
+// Options can enable optional behaviors such as hierarchical groups (see WithHierarchicalGroups) or the validation of the
+// loaded authorizations (see WithActionsValidation) and the audit of the authorization checks (see WithAuditEvents)
+func NewAuthorizationManager(authorizationDBReader AuthorizationDBReader, keycloakClient KeycloakClient, logger log.Logger, options ...AuthorizationManagerOption) (AuthorizationManager, error) {
 	var manager = &authorizationManager{
 		authorizationDBReader: authorizationDBReader,
 		keycloakClient:        keycloakClient,
 		logger:                logger,
+		now:                   time.Now,
+	}
+	for _, option := range options {
+		option(manager)
 	}
 
 	err := manager.ReloadAuthorizations(context.Background())
@@ -76,6 +124,12 @@ func NewAuthorizationManager(authorizationDBReader AuthorizationDBReader, keyclo
 }
 
 func (am *authorizationManager) CheckAuthorizationOnTargetUser(ctx context.Context, action, targetRealm, userID string) error {
+	var err = am.checkAuthorizationOnTargetUser(ctx, action, targetRealm, userID)
+	am.reportAuthorization(ctx, err, action, targetRealm, map[string]string{events.CtEventTargetUserID: userID})
+	return err
+}
+
+func (am *authorizationManager) checkAuthorizationOnTargetUser(ctx context.Context, action, targetRealm, userID string) error {
 	var accessToken = ctx.Value(cs.CtContextAccessToken).(string)
 
 	infos, _ := json.Marshal(map[string]string{
@@ -98,6 +152,14 @@ func (am *authorizationManager) CheckAuthorizationOnTargetUser(ctx context.Conte
 }
 
 func (am *authorizationManager) CheckAuthorizationOnSelfUser(ctx context.Context, action string) error {
+	var err = am.checkAuthorizationOnSelfUser(ctx, action)
+	var targetRealm, _ = ctx.Value(cs.CtContextRealm).(string)
+	var userID, _ = ctx.Value(cs.CtContextUserID).(string)
+	am.reportAuthorization(ctx, err, action, targetRealm, map[string]string{events.CtEventTargetUserID: userID})
+	return err
+}
+
+func (am *authorizationManager) checkAuthorizationOnSelfUser(ctx context.Context, action string) error {
 	var targetRealm = ctx.Value(cs.CtContextRealm).(string)
 	var userID = ctx.Value(cs.CtContextUserID).(string)
 
@@ -120,8 +182,19 @@ func (am *authorizationManager) checkAuthorizationOnUserGroups(ctx context.Conte
 		return ForbiddenError{}
 	}
 
+	var currentRealm = ctx.Value(cs.CtContextRealm).(string)
+	var currentGroups = ctx.Value(cs.CtContextGroups).([]string)
+	var loaded = am.getLoadedAuthorizations()
+	var attributes = am.getRequestAttributes(ctx)
+	for _, targetGroup := range groupsRep {
+		if _, denied := am.findGroupDenial(loaded, attributes, currentRealm, currentGroups, action, targetRealm, targetGroup); denied {
+			am.logger.Info(ctx, "msg", "ForbiddenError: Action denied on a group of the user", "infos", string(infos))
+			return ForbiddenError{}
+		}
+	}
+
 	for _, targetGroup := range groupsRep {
-		if am.CheckAuthorizationOnTargetGroup(ctx, action, targetRealm, targetGroup) == nil {
+		if am.checkAuthorizationOnTargetGroup(ctx, action, targetRealm, targetGroup) == nil {
 			return nil
 		}
 	}
@@ -131,6 +204,12 @@ func (am *authorizationManager) checkAuthorizationOnUserGroups(ctx context.Conte
 }
 
 func (am *authorizationManager) CheckAuthorizationOnTargetGroupID(ctx context.Context, action, targetRealm, targetGroupID string) error {
+	var err = am.checkAuthorizationOnTargetGroupID(ctx, action, targetRealm, targetGroupID)
+	am.reportAuthorization(ctx, err, action, targetRealm, map[string]string{events.CtEventGroupID: targetGroupID})
+	return err
+}
+
+func (am *authorizationManager) checkAuthorizationOnTargetGroupID(ctx context.Context, action, targetRealm, targetGroupID string) error {
 	var accessToken = ctx.Value(cs.CtContextAccessToken).(string)
 	var currentRealm = ctx.Value(cs.CtContextRealm).(string)
 	var currentGroups = ctx.Value(cs.CtContextGroups).([]string)
@@ -157,23 +236,37 @@ func (am *authorizationManager) CheckAuthorizationOnTargetGroupID(ctx context.Co
 		return ForbiddenError{}
 	}
 
-	return am.CheckAuthorizationOnTargetGroup(ctx, action, targetRealm, targetGroup)
+	return am.checkAuthorizationOnTargetGroup(ctx, action, targetRealm, targetGroup)
 }
 func (am *authorizationManager) CheckAuthorizationForGroupsOnTargetGroup(realm string, groups []string, action, targetRealm, targetGroup string) error {
-	for _, group := range groups {
-		if authz, ok := (*am.authorizations)[realm][group][action]; ok && am.currentGroupAllowedForTargetGroup(authz, targetRealm, targetGroup) {
-			return nil
-		}
+	return am.checkForGroupsOnTargetGroup(am.getLoadedAuthorizations(), nil, realm, groups, action, targetRealm, targetGroup)
+}
+
+// checkForGroupsOnTargetGroup checks the authorization of the groups on a target group. Without request attributes, the
+// authorizations with conditions don't allow the action while the deny entries with conditions forbid it
+func (am *authorizationManager) checkForGroupsOnTargetGroup(loaded *loadedAuthorizations, attributes *requestAttributes, realm string, groups []string,
+	action, targetRealm, targetGroup string) error {
+	if _, denied := am.findGroupDenial(loaded, attributes, realm, groups, action, targetRealm, targetGroup); denied {
+		return ForbiddenError{}
+	}
+	if matches, _ := am.findAllowances(loaded, attributes, realm, groups, action, targetRealm, &targetGroup, true); len(matches) > 0 {
+		return nil
 	}
 
 	return ForbiddenError{}
 }
 
 func (am *authorizationManager) CheckAuthorizationOnTargetGroup(ctx context.Context, action, targetRealm, targetGroup string) error {
+	var err = am.checkAuthorizationOnTargetGroup(ctx, action, targetRealm, targetGroup)
+	am.reportAuthorization(ctx, err, action, targetRealm, map[string]string{events.CtEventGroupName: targetGroup})
+	return err
+}
+
+func (am *authorizationManager) checkAuthorizationOnTargetGroup(ctx context.Context, action, targetRealm, targetGroup string) error {
 	var currentRealm = ctx.Value(cs.CtContextRealm).(string)
 	var currentGroups = ctx.Value(cs.CtContextGroups).([]string)
 
-	err := am.CheckAuthorizationForGroupsOnTargetGroup(currentRealm, currentGroups, action, targetRealm, targetGroup)
+	err := am.checkForGroupsOnTargetGroup(am.getLoadedAuthorizations(), am.getRequestAttributes(ctx), currentRealm, currentGroups, action, targetRealm, targetGroup)
 
 	if err != nil {
 		infos, _ := json.Marshal(map[string]string{
@@ -189,56 +282,78 @@ func (am *authorizationManager) CheckAuthorizationOnTargetGroup(ctx context.Cont
 	return err
 }
 
-func (am *authorizationManager) currentGroupAllowedForTargetGroup(authz map[string]map[string]struct{}, targetRealm, targetGroup string) bool {
-	if targetGroupAllowed, wildcard := authz["*"]; wildcard {
-		_, allGroupsAllowed := targetGroupAllowed["*"]
-		_, groupAllowed := targetGroupAllowed[targetGroup]
-
-		if allGroupsAllowed || groupAllowed {
-			return true
-		}
-	}
-
-	if targetGroupAllowed, nonMasterRealmAllowed := authz["/"]; targetRealm != "master" && nonMasterRealmAllowed {
-		_, allGroupsAllowed := targetGroupAllowed["*"]
-		_, groupAllowed := targetGroupAllowed[targetGroup]
-
-		if allGroupsAllowed || groupAllowed {
-			return true
+// findAllowances returns the allow entries of the groups which apply to the action on the target realm or, when targetGroup is
+// not nil, on the target group, and the matching entries whose conditions are not fulfilled. With firstOnly, it returns as soon
+// as an entry applies
+func (am *authorizationManager) findAllowances(loaded *loadedAuthorizations, attributes *requestAttributes, realm string, groups []string,
+	action, targetRealm string, targetGroup *string, firstOnly bool) ([]AuthorizationMatch, []AuthorizationMatch) {
+	var matches, conditionsNotMet []AuthorizationMatch
+	for _, group := range am.expandGroups(groups) {
+		var authz, ok = loaded.allowed[realm][group][action]
+		if !ok {
+			continue
 		}
-	}
-
-	if targetGroupAllowed, realmAllowed := authz[targetRealm]; realmAllowed {
-		_, allGroupsAllowed := targetGroupAllowed["*"]
-		_, groupAllowed := targetGroupAllowed[targetGroup]
-
-		if allGroupsAllowed || groupAllowed {
-			return true
+		for _, allowedRealm := range []string{"*", "/", targetRealm} {
+			var targetGroups, ok = authz[allowedRealm]
+			if !ok || (allowedRealm == "/" && targetRealm == "master") {
+				continue
+			}
+			var match = AuthorizationMatch{Group: group, TargetRealm: allowedRealm, RealmWildcard: allowedRealm == "*" || allowedRealm == "/"}
+			var entry = authorizationEntry{realm: realm, group: group, action: action, targetRealm: allowedRealm}
+			if targetGroup == nil {
+				if loaded.realmEntryAllowed(entry, targetGroups, attributes) {
+					matches = append(matches, match)
+				} else {
+					conditionsNotMet = append(conditionsNotMet, match)
+				}
+			} else {
+				for _, matchingEntry := range am.matchingTargetGroups(targetGroups, *targetGroup) {
+					match.TargetGroup, match.GroupWildcard = matchingEntry, strings.HasSuffix(matchingEntry, "*")
+					entry.targetGroup = matchingEntry
+					if loaded.allowedConditions.applies(entry, attributes, false) {
+						matches = append(matches, match)
+						break
+					}
+					conditionsNotMet = append(conditionsNotMet, match)
+				}
+			}
+			if firstOnly && len(matches) > 0 {
+				return matches, conditionsNotMet
+			}
 		}
 	}
-
-	return false
+	return matches, conditionsNotMet
 }
 
 func (am *authorizationManager) CheckAuthorizationForGroupsOnTargetRealm(realm string, groups []string, action, targetRealm string) error {
-	for _, group := range groups {
-		_, wildcard := (*am.authorizations)[realm][group][action]["*"]
-		_, nonMasterRealmAllowed := (*am.authorizations)[realm][group][action]["/"]
-		_, realmAllowed := (*am.authorizations)[realm][group][action][targetRealm]
+	return am.checkForGroupsOnTargetRealm(nil, realm, groups, action, targetRealm)
+}
 
-		if wildcard || realmAllowed || (targetRealm != "master" && nonMasterRealmAllowed) {
-			return nil
-		}
+// checkForGroupsOnTargetRealm checks the authorization of the groups on a target realm. Without request attributes, the
+// authorizations with conditions don't allow the action while the deny entries with conditions forbid it
+func (am *authorizationManager) checkForGroupsOnTargetRealm(attributes *requestAttributes, realm string, groups []string, action, targetRealm string) error {
+	var loaded = am.getLoadedAuthorizations()
+	if _, denied := am.findRealmDenial(loaded, attributes, realm, groups, action, targetRealm); denied {
+		return ForbiddenError{}
+	}
+	if matches, _ := am.findAllowances(loaded, attributes, realm, groups, action, targetRealm, nil, true); len(matches) > 0 {
+		return nil
 	}
 
 	return ForbiddenError{}
 }
 
 func (am *authorizationManager) CheckAuthorizationOnTargetRealm(ctx context.Context, action, targetRealm string) error {
+	var err = am.checkAuthorizationOnTargetRealm(ctx, action, targetRealm)
+	am.reportAuthorization(ctx, err, action, targetRealm, nil)
+	return err
+}
+
+func (am *authorizationManager) checkAuthorizationOnTargetRealm(ctx context.Context, action, targetRealm string) error {
 	var currentRealm = ctx.Value(cs.CtContextRealm).(string)
 	var currentGroups = ctx.Value(cs.CtContextGroups).([]string)
 
-	err := am.CheckAuthorizationForGroupsOnTargetRealm(currentRealm, currentGroups, action, targetRealm)
+	err := am.checkForGroupsOnTargetRealm(am.getRequestAttributes(ctx), currentRealm, currentGroups, action, targetRealm)
 
 	if err != nil {
 		infos, _ := json.Marshal(map[string]string{
@@ -253,7 +368,8 @@ func (am *authorizationManager) CheckAuthorizationOnTargetRealm(ctx context.Cont
 	return err
 }
 
-// GetRightsOfCurrentUser returns the matrix rights of the current user
+// GetRightsOfCurrentUser returns the matrix rights of the current user. Deny entries are returned under their target realm
+// prefixed with DenyPrefix
 func (am *authorizationManager) GetRightsOfCurrentUser(ctx context.Context) map[string]map[string]map[string]map[string]struct{} {
 	var currentRealm string
 	var currentGroups = []string{}
@@ -271,18 +387,30 @@ func (am *authorizationManager) GetRightsOfCurrentUser(ctx context.Context) map[
 	//3 dimensions table to express authorizations (group_of_user, action, target_realm) -> target_group for which the action is allowed
 	// We keep group_of_user as a user may be part of multiple groups
 	var rights = map[string]map[string]map[string]map[string]struct{}{}
+	// A single snapshot of the matrix is used
+	var loaded = am.getLoadedAuthorizations()
 
-	for _, group := range currentGroups {
-		rightsForGroup, exist := (*am.authorizations)[currentRealm][group]
+	for _, group := range am.expandGroups(currentGroups) {
+		rightsForGroup, exist := loaded.allowed[currentRealm][group]
 
 		if exist {
 			rights[group] = rightsForGroup
 		}
 	}
+	am.addDenialsToRights(loaded, rights, currentRealm, currentGroups)
 
 	return rights
 }
 
+// MakeAuthorizationsReloadCallback creates a configuration change callback which reloads the authorizations of the given manager.
+// It is intended to be registered on a configuration.ConfigurationWatcher for configuration.ChangeSourceAuthorizations
+func MakeAuthorizationsReloadCallback(am AuthorizationManager) configuration.ChangeCallback {
+	return func(ctx context.Context, _ configuration.ChangeSource, _ []string) {
+		// Errors are already logged by ReloadAuthorizations: last loaded authorizations are kept
+		_ = am.ReloadAuthorizations(ctx)
+	}
+}
+
 func suggestForbiddenError(err error) error {
 	// Caller is suggesting to return a forbidden error except if err is an Unauthorized one
 	switch e := errors.Cause(err).(type) {
@@ -305,6 +433,39 @@ func (e ForbiddenError) Error() string {
 // 4 dimensions table to express authorizations (realm_of_user, group_of_user, action, target_realm) -> target_group for which the action is allowed
 type AuthorizationsMatrix map[string]map[string]map[string]map[string]map[string]struct{}
 
+func (matrix AuthorizationsMatrix) add(authz configuration.Authorization) {
+	// Realm of user
+	if _, ok := matrix[*authz.RealmID]; !ok {
+		matrix[*authz.RealmID] = make(map[string]map[string]map[string]map[string]struct{})
+	}
+
+	// Group of user
+	if _, ok := matrix[*authz.RealmID][*authz.GroupName]; !ok {
+		matrix[*authz.RealmID][*authz.GroupName] = make(map[string]map[string]map[string]struct{})
+	}
+
+	// Action
+	if _, ok := matrix[*authz.RealmID][*authz.GroupName][*authz.Action]; !ok {
+		matrix[*authz.RealmID][*authz.GroupName][*authz.Action] = make(map[string]map[string]struct{})
+	}
+
+	// Target Realm
+	if authz.TargetRealmID == nil {
+		return
+	}
+
+	if _, ok := matrix[*authz.RealmID][*authz.GroupName][*authz.Action][*authz.TargetRealmID]; !ok {
+		matrix[*authz.RealmID][*authz.GroupName][*authz.Action][*authz.TargetRealmID] = make(map[string]struct{})
+	}
+
+	// Target Group
+	if authz.TargetGroupName == nil {
+		return
+	}
+
+	matrix[*authz.RealmID][*authz.GroupName][*authz.Action][*authz.TargetRealmID][*authz.TargetGroupName] = struct{}{}
+}
+
 // LoadAuthorizations loads the authorization JSON into the data structure
 // Authorization matrix is a 4 dimensions table :
 //   - realm_of_user
@@ -321,49 +482,100 @@ type AuthorizationsMatrix map[string]map[string]map[string]map[string]map[string
 //	'*' can be used to express all target groups are allowed
 func (am *authorizationManager) ReloadAuthorizations(ctx context.Context) error {
 	am.logger.Info(ctx, "msg", "Reload authorizations triggered")
-	authorizations, err := am.authorizationDBReader.GetAuthorizations(context.Background())
+	var attempt = time.Now()
+	authorizations, err := am.authorizationDBReader.GetAuthorizations(ctx)
 	if err != nil {
 		am.logger.Warn(ctx, "msg", "Failed to get authorizations from DB", "err", err)
+		// Last loaded authorizations are kept
+		am.statusMutex.Lock()
+		am.status.LastAttempt = attempt
+		am.status.LastError = err
+		am.statusMutex.Unlock()
 		return err
 	}
 
-	var matrix = make(AuthorizationsMatrix)
+	am.logAuthorizationIssues(ctx, authorizations)
 
+	var loaded = loadedAuthorizations{
+		allowed:           make(AuthorizationsMatrix),
+		denied:            make(AuthorizationsMatrix),
+		allowedConditions: make(entriesConditions),
+		deniedConditions:  make(entriesConditions),
+	}
 	for _, authz := range authorizations {
-		// Realm of user
-		if _, ok := matrix[*authz.RealmID]; !ok {
-			matrix[*authz.RealmID] = make(map[string]map[string]map[string]map[string]struct{})
-		}
-
-		// Group of user
-		if _, ok := matrix[*authz.RealmID][*authz.GroupName]; !ok {
-			matrix[*authz.RealmID][*authz.GroupName] = make(map[string]map[string]map[string]struct{})
+		if authz.Deny {
+			loaded.denied.add(authz)
+			loaded.deniedConditions.add(authz)
+		} else {
+			loaded.allowed.add(authz)
+			loaded.allowedConditions.add(authz)
 		}
+	}
 
-		// Action
-		if _, ok := matrix[*authz.RealmID][*authz.GroupName][*authz.Action]; !ok {
-			matrix[*authz.RealmID][*authz.GroupName][*authz.Action] = make(map[string]map[string]struct{})
-		}
+	am.authorizations.Store(&loaded)
+	am.statusMutex.Lock()
+	am.status = AuthorizationsReloadStatus{
+		LastAttempt: attempt,
+		LastSuccess: attempt,
+		Entries:     len(authorizations),
+	}
+	am.statusMutex.Unlock()
+	am.logger.Info(ctx, "msg", "Authorizations reloaded", "entries", len(authorizations))
 
-		// Target Realm
-		if authz.TargetRealmID == nil {
-			continue
-		}
+	return nil
+}
 
-		if _, ok := matrix[*authz.RealmID][*authz.GroupName][*authz.Action][*authz.TargetRealmID]; !ok {
-			matrix[*authz.RealmID][*authz.GroupName][*authz.Action][*authz.TargetRealmID] = make(map[string]struct{})
-		}
+func (am *authorizationManager) getLoadedAuthorizations() *loadedAuthorizations {
+	if loaded := am.authorizations.Load(); loaded != nil {
+		return loaded
+	}
+	return &loadedAuthorizations{}
+}
 
-		// Target Group
-		if authz.TargetGroupName == nil {
-			continue
+// realmEntryAllowed tells whether one of the authorizations on a target realm, with or without target group, applies
+func (loaded *loadedAuthorizations) realmEntryAllowed(entry authorizationEntry, targetGroups map[string]struct{}, attributes *requestAttributes) bool {
+	if loaded.allowedConditions.applies(entry, attributes, false) {
+		return true
+	}
+	for targetGroup := range targetGroups {
+		entry.targetGroup = targetGroup
+		if loaded.allowedConditions.applies(entry, attributes, false) {
+			return true
 		}
-
-		matrix[*authz.RealmID][*authz.GroupName][*authz.Action][*authz.TargetRealmID][*authz.TargetGroupName] = struct{}{}
 	}
+	return false
+}
 
-	am.authorizations = &matrix
-	am.logger.Info(ctx, "msg", "Authorizations reloaded")
+// StartPeriodicReload reloads the authorizations in background every interval plus a random duration up to jitter, until the
+// context is done. Jitter avoids all the instances of a service to query the database at the same time.
+// Calling it while a periodic reload is already running has no effect
+func (am *authorizationManager) StartPeriodicReload(ctx context.Context, interval, jitter time.Duration) {
+	if interval <= 0 || !am.periodicReload.CompareAndSwap(false, true) {
+		return
+	}
+	go func() {
+		defer am.periodicReload.Store(false)
+		for {
+			var delay = interval
+			if jitter > 0 {
+				delay += rand.N(jitter)
+			}
+			var timer = time.NewTimer(delay)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-timer.C:
+				// Errors are logged by ReloadAuthorizations and reported in the reload status
+				_ = am.ReloadAuthorizations(ctx)
+			}
+		}
+	}()
+}
 
-	return nil
+// GetReloadStatus returns the status of the authorizations reloads
+func (am *authorizationManager) GetReloadStatus() AuthorizationsReloadStatus {
+	am.statusMutex.Lock()
+	defer am.statusMutex.Unlock()
+	return am.status
 }
@@ -4,7 +4,9 @@ import (
 	"context"
 	"errors"
 	"net/http"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/cloudtrust/common-service/v2/configuration"
 
@@ -651,3 +653,107 @@ func TestReloadAuthorizations(t *testing.T) {
 		assert.Equal(t, true, ok)
 	}
 }
+
+func TestMakeAuthorizationsReloadCallback(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockKeycloakClient = mock.NewKeycloakClient(mockCtrl)
+	var mockAuthorizationDBReader = mock.NewAuthorizationDBReader(mockCtrl)
+
+	mockAuthorizationDBReader.EXPECT().GetAuthorizations(gomock.Any()).Return([]configuration.Authorization{}, nil)
+	authorizationManager, err := NewAuthorizationManager(mockAuthorizationDBReader, mockKeycloakClient, log.NewNopLogger())
+	assert.Nil(t, err)
+
+	var callback = MakeAuthorizationsReloadCallback(authorizationManager)
+
+	// The context of the callback is used to get the authorizations
+	var ctx = context.WithValue(context.Background(), cs.CtContextRealm, "master")
+	mockAuthorizationDBReader.EXPECT().GetAuthorizations(ctx).Return([]configuration.Authorization{}, nil)
+	callback(ctx, configuration.ChangeSourceAuthorizations, []string{"master"})
+}
+
+func TestReloadStatus(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockKeycloakClient = mock.NewKeycloakClient(mockCtrl)
+	var mockAuthorizationDBReader = mock.NewAuthorizationDBReader(mockCtrl)
+
+	var master = "master"
+	var toe = "toe"
+	var getUsers = "GetUsers"
+	var authorizations = []configuration.Authorization{{RealmID: &master, GroupName: &toe, Action: &getUsers, TargetRealmID: &master}}
+	var ctx = context.WithValue(context.Background(), cs.CtContextRealm, master)
+	ctx = context.WithValue(ctx, cs.CtContextGroups, []string{toe})
+
+	mockAuthorizationDBReader.EXPECT().GetAuthorizations(gomock.Any()).Return(authorizations, nil)
+	authorizationManager, err := NewAuthorizationManager(mockAuthorizationDBReader, mockKeycloakClient, log.NewNopLogger())
+	assert.Nil(t, err)
+
+	var status = authorizationManager.GetReloadStatus()
+	assert.Equal(t, 1, status.Entries)
+	assert.False(t, status.LastSuccess.IsZero())
+	assert.Nil(t, status.LastError)
+
+	t.Run("Last good matrix is kept on failure", func(t *testing.T) {
+		var reloadErr = errors.New("db error")
+		mockAuthorizationDBReader.EXPECT().GetAuthorizations(gomock.Any()).Return(nil, reloadErr)
+		assert.Equal(t, reloadErr, authorizationManager.ReloadAuthorizations(ctx))
+
+		var newStatus = authorizationManager.GetReloadStatus()
+		assert.Equal(t, reloadErr, newStatus.LastError)
+		assert.Equal(t, status.LastSuccess, newStatus.LastSuccess)
+		assert.Equal(t, 1, newStatus.Entries)
+		assert.Nil(t, authorizationManager.CheckAuthorizationOnTargetRealm(ctx, getUsers, master))
+	})
+	t.Run("Concurrent reloads and checks", func(t *testing.T) {
+		mockAuthorizationDBReader.EXPECT().GetAuthorizations(gomock.Any()).Return(authorizations, nil).Times(20)
+		var wg sync.WaitGroup
+		for range 20 {
+			wg.Add(2)
+			go func() {
+				defer wg.Done()
+				_ = authorizationManager.ReloadAuthorizations(ctx)
+			}()
+			go func() {
+				defer wg.Done()
+				assert.Nil(t, authorizationManager.CheckAuthorizationOnTargetRealm(ctx, getUsers, master))
+			}()
+		}
+		wg.Wait()
+		assert.Nil(t, authorizationManager.GetReloadStatus().LastError)
+	})
+}
+
+func TestStartPeriodicReload(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockKeycloakClient = mock.NewKeycloakClient(mockCtrl)
+	var mockAuthorizationDBReader = mock.NewAuthorizationDBReader(mockCtrl)
+
+	mockAuthorizationDBReader.EXPECT().GetAuthorizations(gomock.Any()).Return([]configuration.Authorization{}, nil)
+	manager, err := NewAuthorizationManager(mockAuthorizationDBReader, mockKeycloakClient, log.NewNopLogger())
+	assert.Nil(t, err)
+
+	var reloaded = make(chan struct{}, 10)
+	mockAuthorizationDBReader.EXPECT().GetAuthorizations(gomock.Any()).DoAndReturn(func(_ context.Context) ([]configuration.Authorization, error) {
+		reloaded <- struct{}{}
+		return nil, errors.New("db error")
+	}).MinTimes(2)
+
+	var ctx, cancel = context.WithCancel(context.Background())
+	manager.StartPeriodicReload(ctx, time.Millisecond, time.Millisecond)
+	// Already started: ignored
+	manager.StartPeriodicReload(ctx, time.Millisecond, 0)
+
+	for range 2 {
+		select {
+		case <-reloaded:
+		case <-time.After(time.Second):
+			assert.Fail(t, "authorizations not reloaded")
+		}
+	}
+	cancel()
+	assert.Eventually(t, func() bool {
+		return !manager.(*authorizationManager).periodicReload.Load()
+	}, time.Second, time.Millisecond)
+}
@@ -0,0 +1,179 @@
+package security
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+
+	cs "github.com/cloudtrust/common-service/v2"
+)
+
+// BulkKeycloakClient can be implemented by a KeycloakClient able to get the groups of several users at once. When the
+// KeycloakClient of the manager implements it, FilterAuthorizedTargetUsers gets all the groups with a single call
+type BulkKeycloakClient interface {
+	GetGroupNamesOfUsers(ctx context.Context, accessToken string, realmName string, userIDs []string) (map[string][]string, error)
+}
+
+// AllowedTargetGroups describes the target groups of a realm on which the current user is allowed to perform an action.
+// When All is set, all the groups are allowed except the Excluded ones. Otherwise only the Groups are allowed, except the
+// Excluded ones. With hierarchical groups, Groups and Excluded may contain "parent/*" patterns
+type AllowedTargetGroups struct {
+	All      bool     `json:"all"`
+	Groups   []string `json:"groups,omitempty"`
+	Excluded []string `json:"excluded,omitempty"`
+}
+
+// IsEmpty tells whether no target group is allowed
+func (a AllowedTargetGroups) IsEmpty() bool {
+	return !a.All && len(a.Groups) == 0
+}
+
+// FilterAuthorizedTargetGroups returns, in their original order, the target groups on which the current user is allowed to
+// perform the action
+func (am *authorizationManager) FilterAuthorizedTargetGroups(ctx context.Context, action, targetRealm string, targetGroups []string) []string {
+	var currentRealm = ctx.Value(cs.CtContextRealm).(string)
+	var currentGroups = ctx.Value(cs.CtContextGroups).([]string)
+	var loaded = am.getLoadedAuthorizations()
+	var attributes = am.getRequestAttributes(ctx)
+
+	var allowed = map[string]bool{}
+	var res = []string{}
+	for _, targetGroup := range targetGroups {
+		isAllowed, ok := allowed[targetGroup]
+		if !ok {
+			isAllowed = am.checkForGroupsOnTargetGroup(loaded, attributes, currentRealm, currentGroups, action, targetRealm, targetGroup) == nil
+			allowed[targetGroup] = isAllowed
+		}
+		if isAllowed {
+			res = append(res, targetGroup)
+		}
+	}
+	return res
+}
+
+// FilterAuthorizedTargetUsers returns, in their original order, the users on which the current user is allowed to perform the
+// action. As for CheckAuthorizationOnTargetUser, a user is allowed if none of its groups is denied and one of them is allowed.
+// An error is returned if the groups of the users can't be retrieved from Keycloak
+func (am *authorizationManager) FilterAuthorizedTargetUsers(ctx context.Context, action, targetRealm string, userIDs []string) ([]string, error) {
+	var currentRealm = ctx.Value(cs.CtContextRealm).(string)
+	var currentGroups = ctx.Value(cs.CtContextGroups).([]string)
+
+	var groupsOfUsers, err = am.getGroupNamesOfUsers(ctx, targetRealm, userIDs)
+	if err != nil {
+		infos, _ := json.Marshal(map[string]string{
+			"ThrownBy":    "FilterAuthorizedTargetUsers",
+			"Action":      action,
+			"targetRealm": targetRealm,
+		})
+		am.logger.Info(ctx, "msg", "Can't get groups of users: "+err.Error(), "infos", string(infos))
+		return nil, err
+	}
+
+	var loaded = am.getLoadedAuthorizations()
+	var attributes = am.getRequestAttributes(ctx)
+	var decisions = map[string]targetGroupDecision{}
+	var res = []string{}
+	for _, userID := range userIDs {
+		var anyAllowed, anyDenied = false, false
+		for _, targetGroup := range groupsOfUsers[userID] {
+			decision, ok := decisions[targetGroup]
+			if !ok {
+				_, decision.denied = am.findGroupDenial(loaded, attributes, currentRealm, currentGroups, action, targetRealm, targetGroup)
+				decision.allowed = am.checkForGroupsOnTargetGroup(loaded, attributes, currentRealm, currentGroups, action, targetRealm, targetGroup) == nil
+				decisions[targetGroup] = decision
+			}
+			anyAllowed = anyAllowed || decision.allowed
+			anyDenied = anyDenied || decision.denied
+		}
+		if anyAllowed && !anyDenied {
+			res = append(res, userID)
+		}
+	}
+	return res, nil
+}
+
+// targetGroupDecision is the evaluation of the authorizations on a target group of a user
+type targetGroupDecision struct {
+	denied  bool
+	allowed bool
+}
+
+// getGroupNamesOfUsers gets the groups of the users, with a single call when the Keycloak client supports it
+func (am *authorizationManager) getGroupNamesOfUsers(ctx context.Context, targetRealm string, userIDs []string) (map[string][]string, error) {
+	var accessToken = ctx.Value(cs.CtContextAccessToken).(string)
+	if bulkClient, ok := am.keycloakClient.(BulkKeycloakClient); ok {
+		return bulkClient.GetGroupNamesOfUsers(ctx, accessToken, targetRealm, userIDs)
+	}
+
+	var res = map[string][]string{}
+	for _, userID := range userIDs {
+		if _, ok := res[userID]; ok {
+			continue
+		}
+		groups, err := am.keycloakClient.GetGroupNamesOfUser(ctx, accessToken, targetRealm, userID)
+		if err != nil {
+			return nil, err
+		}
+		res[userID] = groups
+	}
+	return res, nil
+}
+
+// GetAllowedTargetGroups returns the target groups of the realm on which the current user is allowed to perform the action. It is
+// intended to filter queries upfront rather than checking their results one by one
+func (am *authorizationManager) GetAllowedTargetGroups(ctx context.Context, action, targetRealm string) AllowedTargetGroups {
+	var currentRealm = ctx.Value(cs.CtContextRealm).(string)
+	var currentGroups = ctx.Value(cs.CtContextGroups).([]string)
+	var loaded = am.getLoadedAuthorizations()
+	var attributes = am.getRequestAttributes(ctx)
+
+	var res AllowedTargetGroups
+	if _, denied := am.findRealmDenial(loaded, attributes, currentRealm, currentGroups, action, targetRealm); denied {
+		return res
+	}
+
+	for _, group := range am.expandGroups(currentGroups) {
+		var authz = loaded.allowed[currentRealm][group][action]
+		for _, allowedRealm := range []string{"*", "/", targetRealm} {
+			var targetGroups, ok = authz[allowedRealm]
+			if !ok || (allowedRealm == "/" && targetRealm == "master") {
+				continue
+			}
+			var entry = authorizationEntry{realm: currentRealm, group: group, action: action, targetRealm: allowedRealm}
+			for targetGroup := range targetGroups {
+				entry.targetGroup = targetGroup
+				if !loaded.allowedConditions.applies(entry, attributes, false) {
+					continue
+				}
+				if targetGroup == "*" {
+					res.All = true
+				} else if !slices.Contains(res.Groups, targetGroup) {
+					res.Groups = append(res.Groups, targetGroup)
+				}
+			}
+		}
+	}
+	if res.All {
+		res.Groups = nil
+	}
+
+	am.forEachDenial(loaded, currentRealm, currentGroups, action, targetRealm, func(_ AuthorizationMatch, entry authorizationEntry, targetGroups map[string]struct{}) {
+		for targetGroup := range targetGroups {
+			entry.targetGroup = targetGroup
+			if loaded.deniedConditions.applies(entry, attributes, true) && !slices.Contains(res.Excluded, targetGroup) {
+				res.Excluded = append(res.Excluded, targetGroup)
+			}
+		}
+	})
+	// Allowed groups which are also denied are not allowed
+	res.Groups = slices.DeleteFunc(res.Groups, func(group string) bool {
+		return slices.Contains(res.Excluded, group)
+	})
+	if len(res.Groups) == 0 {
+		res.Groups = nil
+	}
+	slices.Sort(res.Groups)
+	slices.Sort(res.Excluded)
+
+	return res
+}
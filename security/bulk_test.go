@@ -0,0 +1,92 @@
+package security
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	cs "github.com/cloudtrust/common-service/v2"
+	"github.com/cloudtrust/common-service/v2/configuration"
+	"github.com/cloudtrust/common-service/v2/log"
+	"github.com/cloudtrust/common-service/v2/security/mock"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+type bulkKeycloakClient struct {
+	*mock.KeycloakClient
+	*mock.BulkKeycloakClient
+}
+
+func TestBulkAuthorizations(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockKeycloakClient = mock.NewKeycloakClient(mockCtrl)
+	var mockBulkKeycloakClient = mock.NewBulkKeycloakClient(mockCtrl)
+	var mockAuthorizationDBReader = mock.NewAuthorizationDBReader(mockCtrl)
+
+	var accessToken = "TOKEN=="
+	var master = "master"
+	var customer = "customer"
+	var partner = "partner"
+	var toe = "toe"
+	var support = "support"
+	var sales = "sales"
+	var vip = "vip"
+	var getUser = "GetUser"
+	var deleteUser = "DeleteUser"
+	var any = "*"
+
+	var authorizations = []configuration.Authorization{
+		{RealmID: &master, GroupName: &toe, Action: &getUser, TargetRealmID: &customer, TargetGroupName: &any},
+		{RealmID: &master, GroupName: &toe, Action: &getUser, TargetRealmID: &customer, TargetGroupName: &vip, Deny: true},
+		{RealmID: &master, GroupName: &toe, Action: &deleteUser, TargetRealmID: &customer, TargetGroupName: &support},
+		{RealmID: &master, GroupName: &toe, Action: &deleteUser, TargetRealmID: &customer, TargetGroupName: &sales},
+		{RealmID: &master, GroupName: &toe, Action: &deleteUser, TargetRealmID: &partner, TargetGroupName: &any},
+		{RealmID: &master, GroupName: &toe, Action: &deleteUser, TargetRealmID: &partner, Deny: true},
+	}
+	mockAuthorizationDBReader.EXPECT().GetAuthorizations(gomock.Any()).Return(authorizations, nil).Times(2)
+	var manager, err = NewAuthorizationManager(mockAuthorizationDBReader, mockKeycloakClient, log.NewNopLogger())
+	assert.Nil(t, err)
+
+	var ctx = context.WithValue(context.Background(), cs.CtContextAccessToken, accessToken)
+	ctx = context.WithValue(ctx, cs.CtContextGroups, []string{toe})
+	ctx = context.WithValue(ctx, cs.CtContextRealm, master)
+
+	t.Run("Filter target groups", func(t *testing.T) {
+		assert.Equal(t, []string{support, sales, support}, manager.FilterAuthorizedTargetGroups(ctx, getUser, customer, []string{support, vip, sales, support}))
+		assert.Equal(t, []string{support}, manager.FilterAuthorizedTargetGroups(ctx, deleteUser, customer, []string{vip, support}))
+		assert.Equal(t, []string{}, manager.FilterAuthorizedTargetGroups(ctx, deleteUser, partner, []string{support}))
+		assert.Equal(t, []string{}, manager.FilterAuthorizedTargetGroups(ctx, getUser, customer, nil))
+	})
+	t.Run("Filter target users", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().GetGroupNamesOfUser(ctx, accessToken, customer, "user-1").Return([]string{support}, nil)
+		mockKeycloakClient.EXPECT().GetGroupNamesOfUser(ctx, accessToken, customer, "user-2").Return([]string{support, vip}, nil)
+		mockKeycloakClient.EXPECT().GetGroupNamesOfUser(ctx, accessToken, customer, "user-3").Return([]string{}, nil)
+		var users, err = manager.FilterAuthorizedTargetUsers(ctx, getUser, customer, []string{"user-1", "user-2", "user-3", "user-1"})
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"user-1", "user-1"}, users)
+	})
+	t.Run("Filter target users: Keycloak failure", func(t *testing.T) {
+		var kcErr = errors.New("keycloak error")
+		mockKeycloakClient.EXPECT().GetGroupNamesOfUser(ctx, accessToken, customer, "user-1").Return(nil, kcErr)
+		var _, err = manager.FilterAuthorizedTargetUsers(ctx, getUser, customer, []string{"user-1", "user-2"})
+		assert.Equal(t, kcErr, err)
+	})
+	t.Run("Filter target users with a bulk Keycloak client", func(t *testing.T) {
+		var bulkManager, err = NewAuthorizationManager(mockAuthorizationDBReader, bulkKeycloakClient{mockKeycloakClient, mockBulkKeycloakClient}, log.NewNopLogger())
+		assert.Nil(t, err)
+		mockBulkKeycloakClient.EXPECT().GetGroupNamesOfUsers(ctx, accessToken, customer, []string{"user-1", "user-2"}).
+			Return(map[string][]string{"user-1": {support, sales}, "user-2": {vip}}, nil)
+		users, err := bulkManager.FilterAuthorizedTargetUsers(ctx, deleteUser, customer, []string{"user-1", "user-2"})
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"user-1"}, users)
+	})
+	t.Run("Allowed target groups", func(t *testing.T) {
+		assert.Equal(t, AllowedTargetGroups{All: true, Excluded: []string{vip}}, manager.GetAllowedTargetGroups(ctx, getUser, customer))
+		assert.Equal(t, AllowedTargetGroups{Groups: []string{sales, support}}, manager.GetAllowedTargetGroups(ctx, deleteUser, customer))
+		// Whole realm is denied
+		assert.True(t, manager.GetAllowedTargetGroups(ctx, deleteUser, partner).IsEmpty())
+		assert.True(t, manager.GetAllowedTargetGroups(ctx, getUser, partner).IsEmpty())
+	})
+}
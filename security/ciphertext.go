@@ -0,0 +1,160 @@
+package security
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"slices"
+
+	errorsMsg "github.com/cloudtrust/common-service/v2/errors"
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// Encryption algorithms of the keys
+const (
+	AlgorithmAESGCM            = "AES-GCM"
+	AlgorithmXChaCha20Poly1305 = "XChaCha20-Poly1305"
+)
+
+// CiphertextFormatVersion1 is the current version of the self-describing ciphertext format
+const CiphertextFormatVersion1 = 1
+
+// Self-describing ciphertexts start with a magic prefix followed by the format version, the algorithm, the length of the kid
+// and the kid. The nonce and the ciphertext follow the header
+var ciphertextMagic = []byte{0xC7, 0x5E}
+
+var algorithmIDs = map[string]byte{
+	AlgorithmAESGCM:            1,
+	AlgorithmXChaCha20Poly1305: 2,
+}
+
+// VersionedEncrypterDecrypter encrypts values in a self-describing format embedding the format version, the algorithm and the
+// kid of the key. Values can then be decrypted without knowing their kid
+type VersionedEncrypterDecrypter interface {
+	EncrypterDecrypter
+	DecryptAuto(value []byte, additional []byte) ([]byte, error)
+}
+
+// NewVersionedEncrypterFromBase64 creates a VersionedEncrypterDecrypter from a json structure serialized as string. Besides kid
+// and value, key entries can give their algorithm: AES-GCM (default) or XChaCha20-Poly1305 which requires 32 bytes keys.
+// tagSize only applies to AES-GCM.
+// Values are encrypted in the self-describing format. Legacy values, made of a 12 bytes IV followed by the AES-GCM ciphertext,
+// can still be decrypted with Decrypt and their kid, or with DecryptAuto which tries all the AES-GCM keys
+func NewVersionedEncrypterFromBase64(keys string, tagSize int) (VersionedEncrypterDecrypter, error) {
+	return newKeyMaterial(keys, tagSize, true)
+}
+
+// CiphertextKeyID returns the kid embedded in a self-describing ciphertext
+func CiphertextKeyID(value []byte) (string, bool) {
+	var header, ok = parseCiphertextHeader(value)
+	return header.kid, ok
+}
+
+type ciphertextHeader struct {
+	algorithm string
+	kid       string
+	length    int
+}
+
+func parseCiphertextHeader(value []byte) (ciphertextHeader, bool) {
+	var prefixLength = len(ciphertextMagic) + 3
+	if len(value) < prefixLength || !slices.Equal(value[:len(ciphertextMagic)], ciphertextMagic) ||
+		value[len(ciphertextMagic)] != CiphertextFormatVersion1 {
+		return ciphertextHeader{}, false
+	}
+	var header = ciphertextHeader{length: prefixLength + int(value[prefixLength-1])}
+	for algorithm, id := range algorithmIDs {
+		if value[len(ciphertextMagic)+1] == id {
+			header.algorithm = algorithm
+		}
+	}
+	if header.algorithm == "" || len(value) < header.length {
+		return ciphertextHeader{}, false
+	}
+	header.kid = string(value[prefixLength:header.length])
+	return header, true
+}
+
+func (k aesGcmKey) algorithm() string {
+	if k.Alg == "" {
+		return AlgorithmAESGCM
+	}
+	return k.Alg
+}
+
+func (k aesGcmKey) newAEAD(tagSize int) (cipher.AEAD, error) {
+	switch k.algorithm() {
+	case AlgorithmAESGCM:
+		var block, err = aes.NewCipher(k.Key)
+		if err != nil {
+			return nil, err
+		}
+		return cipher.NewGCMWithTagSize(block, tagSize)
+	case AlgorithmXChaCha20Poly1305:
+		return chacha20poly1305.NewX(k.Key)
+	default:
+		return nil, errors.New(errorsMsg.MsgErrInvalidParam + ".alg")
+	}
+}
+
+// encryptVersioned encrypts a value with the most recent key in the self-describing format. The header is authenticated with
+// the additional data
+func (km *keyMaterial) encryptVersioned(value []byte, additional []byte) ([]byte, error) {
+	var key = km.keys[0]
+	if len(key.Kid) > 0xFF {
+		return nil, errors.New(errorsMsg.MsgErrInvalidLength + ".kid")
+	}
+	var aead, err = key.newAEAD(km.tagSize)
+	if err != nil {
+		return nil, err
+	}
+
+	var header = slices.Concat(ciphertextMagic, []byte{CiphertextFormatVersion1, algorithmIDs[key.algorithm()], byte(len(key.Kid))}, []byte(key.Kid))
+	var nonce = make([]byte, aead.NonceSize())
+	_, _ = rand.Read(nonce)
+
+	var res = slices.Concat(header, nonce)
+	return aead.Seal(res, nonce, value, slices.Concat(header, additional)), nil
+}
+
+// decryptVersioned decrypts a self-describing ciphertext
+func (km *keyMaterial) decryptVersioned(value []byte, additional []byte) ([]byte, error) {
+	var header, ok = parseCiphertextHeader(value)
+	if !ok {
+		return nil, errors.New(errorsMsg.MsgErrInvalidParam + "." + errorsMsg.Ciphertext)
+	}
+	var key, found = km.getKey(header.kid)
+	if !found || key.algorithm() != header.algorithm {
+		return nil, errors.New(errorsMsg.MsgErrDecryptionKeyNotAvailable + "." + errorsMsg.EncryptDecrypt)
+	}
+
+	var aead, err = key.newAEAD(km.tagSize)
+	if err != nil {
+		return nil, err
+	}
+	if len(value) <= header.length+aead.NonceSize() {
+		return nil, errors.New(errorsMsg.MsgErrInvalidLength + "." + errorsMsg.Ciphertext)
+	}
+	var nonce = value[header.length : header.length+aead.NonceSize()]
+	return aead.Open(nil, nonce, value[header.length+aead.NonceSize():], slices.Concat(value[:header.length], additional))
+}
+
+// DecryptAuto decrypts a value without knowing its kid. Self-describing ciphertexts are decrypted with their embedded kid while
+// legacy ones are decrypted by trying all the AES-GCM keys, most recent first
+func (km *keyMaterial) DecryptAuto(value []byte, additional []byte) ([]byte, error) {
+	var res, err = km.decryptVersioned(value, additional)
+	if err == nil {
+		return res, nil
+	}
+	// A legacy ciphertext may start like a self-describing one
+	for _, key := range km.keys {
+		if key.algorithm() != AlgorithmAESGCM {
+			continue
+		}
+		if decrypted, legacyErr := km.decryptLegacy(value, key, additional); legacyErr == nil {
+			return decrypted, nil
+		}
+	}
+	return nil, err
+}
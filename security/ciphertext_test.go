@@ -0,0 +1,100 @@
+package security
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testVersionedKeys = `[
+	{"kid":"DBB_1","value":"ABCDEFGHIJKLMNOPQRSTUVWXYZ012345"},
+	{"kid":"DBB_2","value":"ABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789abcdefg=","alg":"XChaCha20-Poly1305"}
+]`
+
+func TestNewVersionedEncrypter(t *testing.T) {
+	t.Run("Unknown algorithm", func(t *testing.T) {
+		var _, err = NewVersionedEncrypterFromBase64(`[{"kid":"DBB_1","value":"ABCDEFGHIJKLMNOPQRSTUVWXYZ012345","alg":"DES"}]`, 16)
+		assert.NotNil(t, err)
+	})
+	t.Run("Invalid XChaCha20-Poly1305 key size", func(t *testing.T) {
+		var _, err = NewVersionedEncrypterFromBase64(`[{"kid":"DBB_1","value":"ABCDEFGHIJKLMNOPQRSTUVWXYZ012345","alg":"XChaCha20-Poly1305"}]`, 16)
+		assert.NotNil(t, err)
+	})
+	t.Run("Legacy encrypter only supports AES-GCM", func(t *testing.T) {
+		var _, err = NewAesGcmEncrypterFromBase64(testVersionedKeys, 16)
+		assert.NotNil(t, err)
+	})
+}
+
+func TestVersionedEncrypter(t *testing.T) {
+	var value = []byte("Sample value used in an encrypt/decrypt cycle")
+	var additional = []byte("additional")
+
+	t.Run("XChaCha20-Poly1305 cycle", func(t *testing.T) {
+		var encrypter, err = NewVersionedEncrypterFromBase64(testVersionedKeys, 16)
+		assert.Nil(t, err)
+		assert.Equal(t, "DBB_2", encrypter.GetCurrentKeyID())
+		testAesGcm(t, encrypter, value)
+
+		var encrypted, _ = encrypter.Encrypt(value, additional)
+		var kid, ok = CiphertextKeyID(encrypted)
+		assert.True(t, ok)
+		assert.Equal(t, "DBB_2", kid)
+
+		var res []byte
+		res, err = encrypter.DecryptAuto(encrypted, additional)
+		assert.Nil(t, err)
+		assert.Equal(t, value, res)
+	})
+	t.Run("AES-GCM cycle", func(t *testing.T) {
+		var encrypter, err = NewVersionedEncrypterFromBase64(testKeys, 16)
+		assert.Nil(t, err)
+		testAesGcm(t, encrypter, value)
+
+		var encrypted, _ = encrypter.Encrypt(value, additional)
+		var kid, _ = CiphertextKeyID(encrypted)
+		assert.Equal(t, "DBB_2", kid)
+
+		var res []byte
+		res, err = encrypter.DecryptAuto(encrypted, additional)
+		assert.Nil(t, err)
+		assert.Equal(t, value, res)
+	})
+
+	var encrypter, _ = NewVersionedEncrypterFromBase64(testVersionedKeys, 16)
+
+	t.Run("Legacy ciphertexts", func(t *testing.T) {
+		var legacy, _ = NewAesGcmEncrypterFromBase64(testOldKeys, 16)
+		var encrypted, _ = legacy.Encrypt(value, additional)
+		var _, ok = CiphertextKeyID(encrypted)
+		assert.False(t, ok)
+
+		var res, err = encrypter.Decrypt(encrypted, "DBB_1", additional)
+		assert.Nil(t, err)
+		assert.Equal(t, value, res)
+
+		res, err = encrypter.DecryptAuto(encrypted, additional)
+		assert.Nil(t, err)
+		assert.Equal(t, value, res)
+	})
+	t.Run("Invalid inputs", func(t *testing.T) {
+		var encrypted, _ = encrypter.Encrypt(value, additional)
+		var _, err = encrypter.DecryptAuto(encrypted, []byte("other"))
+		assert.NotNil(t, err)
+
+		// Altered kid
+		var tampered = append([]byte{}, encrypted...)
+		tampered[len(ciphertextMagic)+4] = '1'
+		_, err = encrypter.DecryptAuto(tampered, additional)
+		assert.NotNil(t, err)
+
+		var unknownKeys, _ = NewVersionedEncrypterFromBase64(testOldKeys, 16)
+		_, err = unknownKeys.DecryptAuto(encrypted, additional)
+		assert.NotNil(t, err)
+
+		_, err = encrypter.DecryptAuto(encrypted[:10], additional)
+		assert.NotNil(t, err)
+		_, err = encrypter.DecryptAuto([]byte{0}, additional)
+		assert.NotNil(t, err)
+	})
+}
@@ -0,0 +1,157 @@
+package security
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"slices"
+	"time"
+
+	cs "github.com/cloudtrust/common-service/v2"
+	"github.com/cloudtrust/common-service/v2/configuration"
+)
+
+// authorizationEntry identifies an entry of the authorizations matrix. TargetGroup is empty for entries without target group
+type authorizationEntry struct {
+	realm       string
+	group       string
+	action      string
+	targetRealm string
+	targetGroup string
+}
+
+// authorizationConditions are the parsed conditions of an authorization entry
+type authorizationConditions struct {
+	timeWindows    []configuration.TimeWindow
+	sourceCIDRs    []netip.Prefix
+	requiredClaims map[string]string
+	requiredRoles  []string
+	invalid        bool
+}
+
+// requestAttributes are the attributes of a request used to evaluate the conditions of the authorizations
+type requestAttributes struct {
+	now      time.Time
+	clientIP netip.Addr
+	claims   map[string]any
+	roles    []string
+}
+
+// entriesConditions gives, for each entry of an authorizations matrix, the conditions of the authorizations which created it.
+// A nil element is an authorization without condition
+type entriesConditions map[authorizationEntry][]*authorizationConditions
+
+func newAuthorizationEntry(authz configuration.Authorization) authorizationEntry {
+	var entry = authorizationEntry{realm: *authz.RealmID, group: *authz.GroupName, action: *authz.Action}
+	if authz.TargetRealmID != nil {
+		entry.targetRealm = *authz.TargetRealmID
+	}
+	if authz.TargetGroupName != nil {
+		entry.targetGroup = *authz.TargetGroupName
+	}
+	return entry
+}
+
+// add records the conditions of an authorization. It returns true if the authorization has conditions
+func (e entriesConditions) add(authz configuration.Authorization) bool {
+	var entry = newAuthorizationEntry(authz)
+	if authz.Conditions == nil {
+		e[entry] = append(e[entry], nil)
+		return false
+	}
+	e[entry] = append(e[entry], compileConditions(authz.Conditions))
+	return true
+}
+
+// applies tells whether one of the authorizations of the entry applies to the request. Without request attributes, conditional
+// authorizations only apply when failClosed is true (i.e. for deny entries)
+func (e entriesConditions) applies(entry authorizationEntry, attributes *requestAttributes, failClosed bool) bool {
+	for _, conditions := range e[entry] {
+		if conditions == nil {
+			return true
+		}
+		if attributes == nil {
+			if failClosed {
+				return true
+			}
+			continue
+		}
+		if conditions.fulfilled(attributes) {
+			return true
+		}
+	}
+	return false
+}
+
+// compileConditions parses the conditions of an authorization. Invalid conditions are never fulfilled
+func compileConditions(conditions *configuration.AuthorizationConditions) *authorizationConditions {
+	var res = &authorizationConditions{
+		timeWindows:    conditions.TimeWindows,
+		requiredClaims: conditions.RequiredClaims,
+		requiredRoles:  conditions.RequiredRoles,
+	}
+	if conditions.Validate() != nil {
+		res.invalid = true
+		return res
+	}
+	res.sourceCIDRs, _ = conditions.ParseSourceCIDRs()
+	return res
+}
+
+func (c *authorizationConditions) fulfilled(attributes *requestAttributes) bool {
+	if c.invalid {
+		return false
+	}
+	if len(c.timeWindows) > 0 && !slices.ContainsFunc(c.timeWindows, func(window configuration.TimeWindow) bool {
+		return window.Includes(attributes.now)
+	}) {
+		return false
+	}
+	if len(c.sourceCIDRs) > 0 && !slices.ContainsFunc(c.sourceCIDRs, func(prefix netip.Prefix) bool {
+		return attributes.clientIP.IsValid() && prefix.Contains(attributes.clientIP)
+	}) {
+		return false
+	}
+	for claim, expected := range c.requiredClaims {
+		if !claimHasValue(attributes.claims[claim], expected) {
+			return false
+		}
+	}
+	for _, role := range c.requiredRoles {
+		if !slices.Contains(attributes.roles, role) {
+			return false
+		}
+	}
+	return true
+}
+
+// claimHasValue tells whether a claim is the expected value or, for multi-valued claims, contains it
+func claimHasValue(claim any, expected string) bool {
+	switch value := claim.(type) {
+	case nil:
+		return false
+	case string:
+		return value == expected
+	case []any:
+		return slices.ContainsFunc(value, func(item any) bool {
+			return claimHasValue(item, expected)
+		})
+	case []string:
+		return slices.Contains(value, expected)
+	default:
+		return fmt.Sprint(value) == expected
+	}
+}
+
+// getRequestAttributes gets the attributes of the request from the context
+func (am *authorizationManager) getRequestAttributes(ctx context.Context) *requestAttributes {
+	var attributes = &requestAttributes{now: am.now()}
+	if value, ok := ctx.Value(cs.CtContextClientIP).(string); ok {
+		if addr, err := netip.ParseAddr(value); err == nil {
+			attributes.clientIP = addr.Unmap()
+		}
+	}
+	attributes.claims, _ = ctx.Value(cs.CtContextClaims).(map[string]any)
+	attributes.roles, _ = ctx.Value(cs.CtContextRoles).([]string)
+	return attributes
+}
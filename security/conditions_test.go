@@ -0,0 +1,131 @@
+package security
+
+import (
+	"context"
+	"net/netip"
+	"testing"
+	"time"
+
+	cs "github.com/cloudtrust/common-service/v2"
+	"github.com/cloudtrust/common-service/v2/configuration"
+	"github.com/cloudtrust/common-service/v2/log"
+	"github.com/cloudtrust/common-service/v2/security/mock"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+func TestClaimHasValue(t *testing.T) {
+	assert.False(t, claimHasValue(nil, "value"))
+	assert.True(t, claimHasValue("value", "value"))
+	assert.False(t, claimHasValue("other", "value"))
+	assert.True(t, claimHasValue([]any{"a", "value"}, "value"))
+	assert.False(t, claimHasValue([]any{"a", "b"}, "value"))
+	assert.True(t, claimHasValue([]string{"value"}, "value"))
+	assert.True(t, claimHasValue(float64(2), "2"))
+	assert.True(t, claimHasValue(true, "true"))
+}
+
+func TestConditionsFulfilled(t *testing.T) {
+	// 2026-10-19 is a monday
+	var officeHours = time.Date(2026, time.October, 19, 10, 0, 0, 0, time.UTC)
+	var attributes = &requestAttributes{
+		now:    officeHours,
+		claims: map[string]any{"acr": "2", "amr": []any{"pwd", "otp"}},
+		roles:  []string{"support"},
+	}
+	var conditions = compileConditions(&configuration.AuthorizationConditions{
+		TimeWindows:    []configuration.TimeWindow{{Days: []string{"mon", "tue", "wed", "thu", "fri"}, Start: "08:00", End: "18:00"}},
+		SourceCIDRs:    []string{"10.0.0.0/8"},
+		RequiredClaims: map[string]string{"acr": "2", "amr": "otp"},
+		RequiredRoles:  []string{"support"},
+	})
+
+	assert.False(t, conditions.fulfilled(attributes))
+	attributes.clientIP = netip.MustParseAddr("192.168.1.1")
+	assert.False(t, conditions.fulfilled(attributes))
+	attributes.clientIP = netip.MustParseAddr("10.1.2.3")
+	assert.True(t, conditions.fulfilled(attributes))
+
+	attributes.now = officeHours.Add(10 * time.Hour)
+	assert.False(t, conditions.fulfilled(attributes))
+	attributes.now = officeHours
+
+	attributes.roles = []string{"other"}
+	assert.False(t, conditions.fulfilled(attributes))
+	attributes.roles = []string{"support"}
+
+	attributes.claims = map[string]any{"acr": "1", "amr": []any{"otp"}}
+	assert.False(t, conditions.fulfilled(attributes))
+
+	// Invalid conditions are never fulfilled
+	assert.False(t, compileConditions(&configuration.AuthorizationConditions{SourceCIDRs: []string{"invalid"}}).fulfilled(attributes))
+	assert.True(t, compileConditions(&configuration.AuthorizationConditions{}).fulfilled(attributes))
+}
+
+func TestConditionalAuthorizations(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockKeycloakClient = mock.NewKeycloakClient(mockCtrl)
+	var mockAuthorizationDBReader = mock.NewAuthorizationDBReader(mockCtrl)
+
+	var master = "master"
+	var customer = "customer"
+	var support = "support"
+	var vip = "vip"
+	var getRealm = "GetRealm"
+	var getUser = "GetUser"
+	var any = "*"
+	var internalNetwork = &configuration.AuthorizationConditions{SourceCIDRs: []string{"10.0.0.0/8"}}
+	var officeHours = &configuration.AuthorizationConditions{TimeWindows: []configuration.TimeWindow{{Start: "08:00", End: "18:00"}}}
+
+	mockAuthorizationDBReader.EXPECT().GetAuthorizations(gomock.Any()).Return([]configuration.Authorization{
+		{RealmID: &master, GroupName: &support, Action: &getRealm, TargetRealmID: &customer, Conditions: internalNetwork},
+		{RealmID: &master, GroupName: &support, Action: &getUser, TargetRealmID: &customer, TargetGroupName: &any, Conditions: officeHours},
+		{RealmID: &master, GroupName: &support, Action: &getUser, TargetRealmID: &customer, TargetGroupName: &support},
+		{RealmID: &master, GroupName: &support, Action: &getUser, TargetRealmID: &customer, TargetGroupName: &vip, Deny: true, Conditions: internalNetwork},
+	}, nil)
+	var manager, err = NewAuthorizationManager(mockAuthorizationDBReader, mockKeycloakClient, log.NewNopLogger())
+	assert.Nil(t, err)
+	var now = time.Date(2026, time.October, 19, 10, 0, 0, 0, time.UTC)
+	manager.(*authorizationManager).now = func() time.Time { return now }
+
+	var ctx = context.WithValue(context.Background(), cs.CtContextRealm, master)
+	ctx = context.WithValue(ctx, cs.CtContextGroups, []string{support})
+	var internalCtx = context.WithValue(ctx, cs.CtContextClientIP, "10.0.0.1")
+	var externalCtx = context.WithValue(ctx, cs.CtContextClientIP, "1.2.3.4")
+
+	t.Run("Realm level", func(t *testing.T) {
+		assert.Nil(t, manager.CheckAuthorizationOnTargetRealm(internalCtx, getRealm, customer))
+		assert.NotNil(t, manager.CheckAuthorizationOnTargetRealm(externalCtx, getRealm, customer))
+		// Without request context, conditional authorizations don't apply
+		assert.NotNil(t, manager.CheckAuthorizationForGroupsOnTargetRealm(master, []string{support}, getRealm, customer))
+		// Realm is reachable through the GetUser entries
+		assert.Nil(t, manager.CheckAuthorizationOnTargetRealm(externalCtx, getUser, customer))
+	})
+	t.Run("Group level", func(t *testing.T) {
+		assert.Nil(t, manager.CheckAuthorizationOnTargetGroup(externalCtx, getUser, customer, "other"))
+		// Unconditional entry is used even if the conditional "*" entry does not apply
+		now = now.Add(10 * time.Hour)
+		assert.NotNil(t, manager.CheckAuthorizationOnTargetGroup(externalCtx, getUser, customer, "other"))
+		assert.Nil(t, manager.CheckAuthorizationOnTargetGroup(externalCtx, getUser, customer, support))
+		now = now.Add(-10 * time.Hour)
+	})
+	t.Run("Conditional deny entry", func(t *testing.T) {
+		assert.Nil(t, manager.CheckAuthorizationOnTargetGroup(externalCtx, getUser, customer, vip))
+		assert.NotNil(t, manager.CheckAuthorizationOnTargetGroup(internalCtx, getUser, customer, vip))
+		// Without request context, conditional deny entries apply
+		assert.NotNil(t, manager.CheckAuthorizationForGroupsOnTargetGroup(master, []string{support}, getUser, customer, vip))
+	})
+	t.Run("Explain", func(t *testing.T) {
+		var decision = manager.Explain(externalCtx, ExplainRequest{Action: getRealm, TargetRealm: customer})
+		assert.False(t, decision.Allowed)
+		assert.Equal(t, ReasonConditionsNotMet, decision.Reason)
+		assert.Equal(t, []AuthorizationMatch{{Group: support, TargetRealm: customer}}, decision.ConditionsNotMet)
+
+		now = now.Add(10 * time.Hour)
+		decision = manager.Explain(externalCtx, ExplainRequest{Action: getUser, TargetRealm: customer, TargetGroup: &support})
+		assert.True(t, decision.Allowed)
+		assert.Equal(t, []AuthorizationMatch{{Group: support, TargetRealm: customer, TargetGroup: support}}, decision.MatchingEntries)
+		assert.Equal(t, []AuthorizationMatch{{Group: support, TargetRealm: customer, TargetGroup: any, GroupWildcard: true}}, decision.ConditionsNotMet)
+	})
+}
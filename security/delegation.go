@@ -0,0 +1,152 @@
+package security
+
+import (
+	"context"
+	"strings"
+
+	cs "github.com/cloudtrust/common-service/v2"
+	"github.com/cloudtrust/common-service/v2/configuration"
+)
+
+// FindExceedingAuthorizations returns the proposed authorizations which exceed the rights of the current user, i.e. which would
+// let their groups perform an action the current user can't perform. It is intended to prevent administrators from granting
+// rights they don't have.
+// An authorization is covered by the rights of the current user when they are allowed the action on the realm of the grantee
+// and one of their entries has the same action and:
+//   - a target realm "*", the same target realm or "/" if the proposed target realm is neither "*" nor master
+//   - when a target group is proposed, a target group "*", the same target group or, with hierarchical groups, a parent/* pattern
+//
+// Authorizations without target realm, proposed or of the current user, are considered as given on the target realm "*".
+// Proposed deny entries never exceed the rights. Rights of the current user which are denied or whose conditions are not fulfilled
+// by the request are not considered
+func (am *authorizationManager) FindExceedingAuthorizations(ctx context.Context, authorizations []configuration.Authorization) []configuration.Authorization {
+	var currentRealm = ctx.Value(cs.CtContextRealm).(string)
+	var currentGroups = ctx.Value(cs.CtContextGroups).([]string)
+	var loaded = am.getLoadedAuthorizations()
+	var attributes = am.getRequestAttributes(ctx)
+
+	var res []configuration.Authorization
+	for _, authz := range authorizations {
+		if authz.Deny || authz.Action == nil {
+			continue
+		}
+		if !am.delegationAllowed(loaded, attributes, currentRealm, currentGroups, authz) {
+			res = append(res, authz)
+		}
+	}
+	return res
+}
+
+func (am *authorizationManager) delegationAllowed(loaded *loadedAuthorizations, attributes *requestAttributes, realm string, groups []string,
+	authz configuration.Authorization) bool {
+	var targetRealm = wildcardAllTargets
+	if authz.TargetRealmID != nil {
+		targetRealm = *authz.TargetRealmID
+	}
+	// The current user can't grant an action to groups of a realm on which they are not allowed this action
+	if authz.RealmID != nil && !am.actionAllowed(loaded, attributes, realm, groups, *authz.Action, *authz.RealmID, nil) {
+		return false
+	}
+	return am.actionAllowed(loaded, attributes, realm, groups, *authz.Action, targetRealm, authz.TargetGroupName)
+}
+
+// actionAllowed tells whether the rights of the current user cover an action on a target realm and optionally on a target group
+func (am *authorizationManager) actionAllowed(loaded *loadedAuthorizations, attributes *requestAttributes, realm string, groups []string,
+	action, targetRealm string, targetGroup *string) bool {
+	if am.delegationDenied(loaded, attributes, realm, groups, action, targetRealm, targetGroup) {
+		return false
+	}
+
+	for _, group := range am.expandGroups(groups) {
+		var rights, ok = loaded.allowed[realm][group][action]
+		if !ok {
+			continue
+		}
+		var entry = authorizationEntry{realm: realm, group: group, action: action}
+		if len(rights) == 0 {
+			// Entry without target realm gives the action on any target realm
+			if targetGroup == nil && loaded.allowedConditions.applies(entry, attributes, false) {
+				return true
+			}
+			continue
+		}
+		for _, allowedRealm := range coveringTargetRealms(targetRealm) {
+			var targetGroups, ok = rights[allowedRealm]
+			if !ok {
+				continue
+			}
+			entry.targetRealm = allowedRealm
+			if targetGroup == nil {
+				if loaded.realmEntryAllowed(entry, targetGroups, attributes) {
+					return true
+				}
+				continue
+			}
+			for _, matchingEntry := range am.matchingTargetGroups(targetGroups, *targetGroup) {
+				entry.targetGroup = matchingEntry
+				if loaded.allowedConditions.applies(entry, attributes, false) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// delegationDenied tells whether a deny entry of the current user applies to a part of the proposed targets. Wildcard target
+// realms and groups are denied as soon as one of the realms or groups they include is denied
+func (am *authorizationManager) delegationDenied(loaded *loadedAuthorizations, attributes *requestAttributes, realm string, groups []string,
+	action, targetRealm string, targetGroup *string) bool {
+	var targetRealms = []string{targetRealm}
+	if targetRealm == wildcardAllTargets || targetRealm == wildcardNonMasterRealm {
+		for _, group := range am.expandGroups(groups) {
+			for deniedRealm := range loaded.denied[realm][group][action] {
+				if targetRealm == wildcardAllTargets || deniedRealm != masterRealm {
+					targetRealms = append(targetRealms, deniedRealm)
+				}
+			}
+		}
+	}
+
+	for _, targetRealm := range targetRealms {
+		if _, denied := am.findRealmDenial(loaded, attributes, realm, groups, action, targetRealm); denied {
+			return true
+		}
+		if targetGroup == nil {
+			continue
+		}
+		if !strings.HasSuffix(*targetGroup, wildcardAllTargets) {
+			if _, denied := am.findGroupDenial(loaded, attributes, realm, groups, action, targetRealm, *targetGroup); denied {
+				return true
+			}
+			continue
+		}
+		// Wildcard target group: any denied group included in the wildcard denies the delegation
+		var prefix = strings.TrimSuffix(*targetGroup, wildcardAllTargets)
+		var denied = false
+		am.forEachDenial(loaded, realm, groups, action, targetRealm, func(_ AuthorizationMatch, entry authorizationEntry, deniedGroups map[string]struct{}) {
+			for deniedGroup := range deniedGroups {
+				entry.targetGroup = deniedGroup
+				if strings.HasPrefix(strings.TrimPrefix(deniedGroup, groupPathSeparator), prefix) && loaded.deniedConditions.applies(entry, attributes, true) {
+					denied = true
+				}
+			}
+		})
+		if denied {
+			return true
+		}
+	}
+	return false
+}
+
+// coveringTargetRealms returns the target realms of the entries which cover a target realm
+func coveringTargetRealms(targetRealm string) []string {
+	switch targetRealm {
+	case wildcardAllTargets:
+		return []string{wildcardAllTargets}
+	case wildcardNonMasterRealm, masterRealm:
+		return []string{wildcardAllTargets, targetRealm}
+	default:
+		return []string{wildcardAllTargets, wildcardNonMasterRealm, targetRealm}
+	}
+}
@@ -0,0 +1,101 @@
+package security
+
+import (
+	"context"
+	"testing"
+
+	cs "github.com/cloudtrust/common-service/v2"
+	"github.com/cloudtrust/common-service/v2/configuration"
+	"github.com/cloudtrust/common-service/v2/log"
+	"github.com/cloudtrust/common-service/v2/security/mock"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+func TestFindExceedingAuthorizations(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockKeycloakClient = mock.NewKeycloakClient(mockCtrl)
+	var mockAuthorizationDBReader = mock.NewAuthorizationDBReader(mockCtrl)
+
+	var master = "master"
+	var customer = "customer"
+	var partner = "partner"
+	var admin = "admin"
+	var toe = "toe"
+	var support = "support"
+	var vip = "vip"
+	var getUser = "GetUser"
+	var deleteUser = "DeleteUser"
+	var getRealm = "GetRealm"
+	var createUser = "CreateUser"
+	var any = "*"
+	var anyNonMasterRealm = "/"
+
+	mockAuthorizationDBReader.EXPECT().GetAuthorizations(gomock.Any()).Return([]configuration.Authorization{
+		{RealmID: &master, GroupName: &admin, Action: &getUser, TargetRealmID: &anyNonMasterRealm, TargetGroupName: &any},
+		{RealmID: &master, GroupName: &admin, Action: &getUser, TargetRealmID: &partner, TargetGroupName: &vip, Deny: true},
+		{RealmID: &master, GroupName: &admin, Action: &deleteUser, TargetRealmID: &customer, TargetGroupName: &support},
+		{RealmID: &master, GroupName: &admin, Action: &getRealm, TargetRealmID: &customer},
+		{RealmID: &master, GroupName: &admin, Action: &createUser},
+	}, nil)
+	var manager, err = NewAuthorizationManager(mockAuthorizationDBReader, mockKeycloakClient, log.NewNopLogger())
+	assert.Nil(t, err)
+
+	var ctx = context.WithValue(context.Background(), cs.CtContextRealm, master)
+	ctx = context.WithValue(ctx, cs.CtContextGroups, []string{admin})
+
+	var grant = func(action, targetRealm string, targetGroup *string) configuration.Authorization {
+		return configuration.Authorization{RealmID: &customer, GroupName: &toe, Action: &action, TargetRealmID: &targetRealm, TargetGroupName: targetGroup}
+	}
+
+	t.Run("Covered authorizations", func(t *testing.T) {
+		assert.Len(t, manager.FindExceedingAuthorizations(ctx, []configuration.Authorization{
+			grant(getUser, customer, &support),
+			grant(getUser, customer, &any),
+			grant(getUser, customer, nil),
+			grant(deleteUser, customer, &support),
+			grant(getRealm, customer, nil),
+			{RealmID: &master, GroupName: &toe, Action: &createUser},
+			// Deny entries never exceed the rights
+			{RealmID: &master, GroupName: &toe, Action: &getUser, TargetRealmID: &master, Deny: true},
+		}), 0)
+	})
+	t.Run("Exceeding authorizations", func(t *testing.T) {
+		var exceeding = []configuration.Authorization{
+			grant(getUser, master, &support),
+			grant(getUser, any, &any),
+			grant(deleteUser, customer, &any),
+			grant(deleteUser, customer, &vip),
+			grant(deleteUser, partner, &support),
+			grant(getRealm, customer, &support),
+			grant(getRealm, anyNonMasterRealm, nil),
+		}
+		assert.Equal(t, exceeding, manager.FindExceedingAuthorizations(ctx, exceeding))
+	})
+	t.Run("Authorizations including denied targets", func(t *testing.T) {
+		var exceeding = []configuration.Authorization{
+			grant(getUser, partner, &vip),
+			grant(getUser, partner, &any),
+			grant(getUser, anyNonMasterRealm, &any),
+		}
+		assert.Equal(t, exceeding, manager.FindExceedingAuthorizations(ctx, append(exceeding, grant(getUser, partner, &support))))
+	})
+	t.Run("Grantee in a realm on which the action is not allowed", func(t *testing.T) {
+		var exceeding = []configuration.Authorization{
+			{RealmID: &master, GroupName: &toe, Action: &getUser, TargetRealmID: &customer, TargetGroupName: &support},
+			{RealmID: &partner, GroupName: &toe, Action: &deleteUser, TargetRealmID: &customer, TargetGroupName: &support},
+		}
+		assert.Equal(t, exceeding, manager.FindExceedingAuthorizations(ctx, append(exceeding,
+			configuration.Authorization{RealmID: &partner, GroupName: &toe, Action: &getUser, TargetRealmID: &customer, TargetGroupName: &support})))
+	})
+	t.Run("Authorizations without target realm", func(t *testing.T) {
+		var exceeding = []configuration.Authorization{
+			{RealmID: &customer, GroupName: &toe, Action: &getRealm},
+			{RealmID: &customer, GroupName: &toe, Action: &getUser},
+			{RealmID: &customer, GroupName: &toe, Action: &createUser, TargetRealmID: &master, TargetGroupName: &support},
+		}
+		assert.Equal(t, exceeding, manager.FindExceedingAuthorizations(ctx, append(exceeding,
+			configuration.Authorization{RealmID: &customer, GroupName: &toe, Action: &createUser, TargetRealmID: &master})))
+	})
+}
@@ -0,0 +1,88 @@
+package security
+
+import "strings"
+
+// DenyPrefix prefixes the target realms of the deny entries returned by GetRightsOfCurrentUser
+const DenyPrefix = "!"
+
+// findRealmDenial returns the deny entries of the groups which forbid the action on the whole target realm. An entry denies the
+// whole realm when it has no target group or when its target group is "*"
+func (am *authorizationManager) findRealmDenial(loaded *loadedAuthorizations, attributes *requestAttributes, realm string, groups []string, action, targetRealm string) ([]AuthorizationMatch, bool) {
+	var res []AuthorizationMatch
+	am.forEachDenial(loaded, realm, groups, action, targetRealm, func(match AuthorizationMatch, entry authorizationEntry, _ map[string]struct{}) {
+		for _, targetGroup := range []string{"", "*"} {
+			entry.targetGroup = targetGroup
+			if loaded.deniedConditions.applies(entry, attributes, true) {
+				match.TargetGroup, match.GroupWildcard = targetGroup, targetGroup == "*"
+				res = append(res, match)
+			}
+		}
+	})
+	return res, len(res) > 0
+}
+
+// findGroupDenial returns the deny entries of the groups which forbid the action on the target group
+func (am *authorizationManager) findGroupDenial(loaded *loadedAuthorizations, attributes *requestAttributes, realm string, groups []string, action, targetRealm, targetGroup string) ([]AuthorizationMatch, bool) {
+	var res []AuthorizationMatch
+	am.forEachDenial(loaded, realm, groups, action, targetRealm, func(match AuthorizationMatch, entry authorizationEntry, targetGroups map[string]struct{}) {
+		for _, deniedGroup := range append([]string{""}, am.matchingTargetGroups(targetGroups, targetGroup)...) {
+			entry.targetGroup = deniedGroup
+			if loaded.deniedConditions.applies(entry, attributes, true) {
+				match.TargetGroup, match.GroupWildcard = deniedGroup, strings.HasSuffix(deniedGroup, "*")
+				res = append(res, match)
+			}
+		}
+	})
+	return res, len(res) > 0
+}
+
+// forEachDenial calls fn for each deny entry of the groups applying to the action on the target realm
+func (am *authorizationManager) forEachDenial(loaded *loadedAuthorizations, realm string, groups []string, action, targetRealm string,
+	fn func(AuthorizationMatch, authorizationEntry, map[string]struct{})) {
+	var denials = loaded.denied[realm]
+	if len(denials) == 0 {
+		return
+	}
+	for _, group := range am.expandGroups(groups) {
+		var authz, ok = denials[group][action]
+		if !ok {
+			continue
+		}
+		for _, deniedRealm := range []string{"*", "/", targetRealm} {
+			var targetGroups, ok = authz[deniedRealm]
+			if !ok || (deniedRealm == "/" && targetRealm == "master") {
+				continue
+			}
+			var match = AuthorizationMatch{Group: group, TargetRealm: deniedRealm, RealmWildcard: deniedRealm == "*" || deniedRealm == "/"}
+			fn(match, authorizationEntry{realm: realm, group: group, action: action, targetRealm: deniedRealm}, targetGroups)
+		}
+	}
+}
+
+// addDenialsToRights adds the deny entries of the groups to the rights, under their target realm prefixed with DenyPrefix.
+// Rights of a group are copied before being completed as they are shared with the loaded matrix
+func (am *authorizationManager) addDenialsToRights(loaded *loadedAuthorizations, rights map[string]map[string]map[string]map[string]struct{}, realm string,
+	groups []string) {
+	for _, group := range am.expandGroups(groups) {
+		var denials, ok = loaded.denied[realm][group]
+		if !ok {
+			continue
+		}
+		var groupRights = map[string]map[string]map[string]struct{}{}
+		for action, targetRealms := range rights[group] {
+			groupRights[action] = map[string]map[string]struct{}{}
+			for targetRealm, targetGroups := range targetRealms {
+				groupRights[action][targetRealm] = targetGroups
+			}
+		}
+		for action, targetRealms := range denials {
+			if _, ok := groupRights[action]; !ok {
+				groupRights[action] = map[string]map[string]struct{}{}
+			}
+			for targetRealm, targetGroups := range targetRealms {
+				groupRights[action][DenyPrefix+targetRealm] = targetGroups
+			}
+		}
+		rights[group] = groupRights
+	}
+}
@@ -0,0 +1,92 @@
+package security
+
+import (
+	"context"
+	"testing"
+
+	cs "github.com/cloudtrust/common-service/v2"
+	"github.com/cloudtrust/common-service/v2/configuration"
+	"github.com/cloudtrust/common-service/v2/log"
+	"github.com/cloudtrust/common-service/v2/security/mock"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+func TestDenyAuthorizations(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockKeycloakClient = mock.NewKeycloakClient(mockCtrl)
+	var mockAuthorizationDBReader = mock.NewAuthorizationDBReader(mockCtrl)
+
+	var accessToken = "TOKEN=="
+	var master = "master"
+	var customer = "customer"
+	var partner = "partner"
+	var toe = "toe"
+	var vip = "vip"
+	var support = "support"
+	var getRealm = "GetRealm"
+	var getUser = "GetUser"
+	var any = "*"
+	var anyNonMasterRealm = "/"
+	var userID = "user-id"
+
+	mockAuthorizationDBReader.EXPECT().GetAuthorizations(gomock.Any()).Return([]configuration.Authorization{
+		{RealmID: &master, GroupName: &toe, Action: &getRealm, TargetRealmID: &anyNonMasterRealm},
+		{RealmID: &master, GroupName: &toe, Action: &getRealm, TargetRealmID: &partner, Deny: true},
+		{RealmID: &master, GroupName: &toe, Action: &getUser, TargetRealmID: &any, TargetGroupName: &any},
+		{RealmID: &master, GroupName: &toe, Action: &getUser, TargetRealmID: &customer, TargetGroupName: &vip, Deny: true},
+		{RealmID: &master, GroupName: &toe, Action: &getUser, TargetRealmID: &partner, Deny: true},
+	}, nil)
+	var manager, err = NewAuthorizationManager(mockAuthorizationDBReader, mockKeycloakClient, log.NewNopLogger())
+	assert.Nil(t, err)
+
+	var ctx = context.WithValue(context.Background(), cs.CtContextAccessToken, accessToken)
+	ctx = context.WithValue(ctx, cs.CtContextGroups, []string{toe})
+	ctx = context.WithValue(ctx, cs.CtContextRealm, master)
+
+	t.Run("Realm level", func(t *testing.T) {
+		assert.Nil(t, manager.CheckAuthorizationOnTargetRealm(ctx, getRealm, customer))
+		assert.Equal(t, ForbiddenError{}, manager.CheckAuthorizationOnTargetRealm(ctx, getRealm, partner))
+		// A deny entry on some target groups does not deny the whole realm
+		assert.Nil(t, manager.CheckAuthorizationForGroupsOnTargetRealm(master, []string{toe}, getUser, customer))
+		assert.NotNil(t, manager.CheckAuthorizationForGroupsOnTargetRealm(master, []string{toe}, getUser, partner))
+	})
+	t.Run("Group level", func(t *testing.T) {
+		assert.Nil(t, manager.CheckAuthorizationOnTargetGroup(ctx, getUser, customer, support))
+		assert.Equal(t, ForbiddenError{}, manager.CheckAuthorizationOnTargetGroup(ctx, getUser, customer, vip))
+		assert.NotNil(t, manager.CheckAuthorizationOnTargetGroup(ctx, getUser, partner, support))
+	})
+	t.Run("Target user with a denied group", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().GetGroupNamesOfUser(ctx, accessToken, customer, userID).Return([]string{support, vip}, nil)
+		assert.Equal(t, ForbiddenError{}, manager.CheckAuthorizationOnTargetUser(ctx, getUser, customer, userID))
+
+		mockKeycloakClient.EXPECT().GetGroupNamesOfUser(ctx, accessToken, customer, userID).Return([]string{support}, nil)
+		assert.Nil(t, manager.CheckAuthorizationOnTargetUser(ctx, getUser, customer, userID))
+	})
+	t.Run("Rights of current user", func(t *testing.T) {
+		var rights = manager.GetRightsOfCurrentUser(ctx)
+		assert.Contains(t, rights[toe][getRealm], anyNonMasterRealm)
+		assert.Contains(t, rights[toe][getRealm], DenyPrefix+partner)
+		assert.Contains(t, rights[toe][getUser][DenyPrefix+customer], vip)
+		assert.Len(t, rights[toe][getUser][DenyPrefix+partner], 0)
+
+		// Loaded matrix is not altered
+		assert.NotContains(t, manager.(*authorizationManager).getLoadedAuthorizations().allowed[master][toe][getRealm], DenyPrefix+partner)
+	})
+	t.Run("Explain", func(t *testing.T) {
+		var decision = manager.Explain(ctx, ExplainRequest{Action: getUser, TargetRealm: customer, TargetGroup: &vip})
+		assert.False(t, decision.Allowed)
+		assert.Equal(t, ReasonDenied, decision.Reason)
+		assert.Equal(t, []AuthorizationMatch{{Group: toe, TargetRealm: customer, TargetGroup: vip}}, decision.DenyingEntries)
+		assert.Len(t, decision.MatchingEntries, 1)
+
+		decision = manager.Explain(ctx, ExplainRequest{Action: getRealm, TargetRealm: partner})
+		assert.Equal(t, ReasonDenied, decision.Reason)
+		assert.Equal(t, []AuthorizationMatch{{Group: toe, TargetRealm: partner}}, decision.DenyingEntries)
+
+		decision = manager.Explain(ctx, ExplainRequest{Action: getRealm, TargetRealm: customer})
+		assert.True(t, decision.Allowed)
+		assert.Nil(t, decision.DenyingEntries)
+	})
+}
@@ -23,16 +23,22 @@ type EncrypterDecrypter interface {
 type aesGcmKey struct {
 	Kid      string `json:"kid"`
 	Key      []byte `json:"value"`
+	Alg      string `json:"alg,omitempty"`
 	priority int    `json:"-"`
 }
 
 type keyMaterial struct {
-	keys    []aesGcmKey
-	tagSize int
+	keys      []aesGcmKey
+	tagSize   int
+	versioned bool
 }
 
 // NewAesGcmEncrypterFromBase64 creation from json structure serialized as string
 func NewAesGcmEncrypterFromBase64(keys string, tagSize int) (EncrypterDecrypter, error) {
+	return newKeyMaterial(keys, tagSize, false)
+}
+
+func newKeyMaterial(keys string, tagSize int, versioned bool) (*keyMaterial, error) {
 	// parse key array
 	var keyEntries []aesGcmKey
 	err := json.Unmarshal([]byte(keys), &keyEntries)
@@ -44,13 +50,17 @@ func NewAesGcmEncrypterFromBase64(keys string, tagSize int) (EncrypterDecrypter,
 		if err != nil {
 			return nil, err
 		}
+		if !versioned && k.algorithm() != AlgorithmAESGCM {
+			// Legacy format does not tell the algorithm
+			return nil, errors.New(errorsMsg.MsgErrInvalidParam + ".alg")
+		}
 		keyEntries[i] = k
 	}
 	// sort key entries according to priority
 	sort.Slice(keyEntries, func(i, j int) bool {
 		return keyEntries[i].priority > keyEntries[j].priority
 	})
-	km := keyMaterial{keys: keyEntries, tagSize: tagSize}
+	km := keyMaterial{keys: keyEntries, tagSize: tagSize, versioned: versioned}
 	// validate the correctness of the current key
 	err = km.validate()
 	if err != nil {
@@ -70,8 +80,9 @@ func (km *keyMaterial) validate() error {
 	for _, key := range km.keys {
 		// create temporary key material for validation
 		var kmSpecific = keyMaterial{
-			keys:    []aesGcmKey{key},
-			tagSize: km.tagSize,
+			keys:      []aesGcmKey{key},
+			tagSize:   km.tagSize,
+			versioned: km.versioned,
 		}
 		var encrypted []byte
 		kid := kmSpecific.GetCurrentKeyID()
@@ -93,6 +104,9 @@ func (km *keyMaterial) validate() error {
 }
 
 func (km *keyMaterial) Encrypt(value []byte, additional []byte) ([]byte, error) {
+	if km.versioned {
+		return km.encryptVersioned(value, additional)
+	}
 	// select the most recent key
 	key := km.keys[0]
 	var block, err = aes.NewCipher(key.Key)
@@ -116,18 +130,34 @@ func (km *keyMaterial) Encrypt(value []byte, additional []byte) ([]byte, error)
 }
 
 func (km *keyMaterial) Decrypt(encData []byte, kid string, additional []byte) ([]byte, error) {
+	if km.versioned {
+		if res, err := km.decryptVersioned(encData, additional); err == nil {
+			return res, nil
+		}
+		// Legacy ciphertexts are decrypted with the provided kid
+	}
+
 	// select the appropriate key
-	var key aesGcmKey
-	var found bool
+	var key, found = km.getKey(kid)
+	if !found {
+		// key for decryption is not available
+		return nil, errors.New(errorsMsg.MsgErrDecryptionKeyNotAvailable + "." + errorsMsg.EncryptDecrypt)
+	}
+	return km.decryptLegacy(encData, key, additional)
+}
+
+func (km *keyMaterial) getKey(kid string) (aesGcmKey, bool) {
 	for _, k := range km.keys {
 		if k.Kid == kid {
-			key = k
-			found = true
-			break
+			return k, true
 		}
 	}
-	if !found {
-		// key for decryption is not available
+	return aesGcmKey{}, false
+}
+
+// decryptLegacy decrypts a value made of a 12 bytes IV followed by the AES-GCM ciphertext
+func (km *keyMaterial) decryptLegacy(encData []byte, key aesGcmKey, additional []byte) ([]byte, error) {
+	if key.algorithm() != AlgorithmAESGCM {
 		return nil, errors.New(errorsMsg.MsgErrDecryptionKeyNotAvailable + "." + errorsMsg.EncryptDecrypt)
 	}
 
@@ -0,0 +1,155 @@
+package security
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+
+	errorsMsg "github.com/cloudtrust/common-service/v2/errors"
+)
+
+const (
+	envelopeDataKeySize      = 32
+	envelopeWrappedKeyLenLen = 2
+	envelopeIVSize           = 12
+)
+
+type envelopeEncrypter struct {
+	kek     EncrypterDecrypter
+	tagSize int
+}
+
+// NewEnvelopeEncrypter creates an EncrypterDecrypter using envelope encryption: each value is encrypted with its own random
+// AES-256 data key, which is stored with the value once wrapped by the key-encryption key kek (e.g. created with
+// NewAesGcmEncrypterFromBase64).
+// Key ids are the ones of the key-encryption key: re-keying a value only needs to re-wrap its data key (see Rekey).
+// Encrypted values are made of the length of the wrapped data key (2 bytes, big endian), the wrapped data key, the IV and
+// the ciphertext. Additional data is bound to both the data key and the value
+func NewEnvelopeEncrypter(kek EncrypterDecrypter, tagSize int) (EncrypterDecrypter, error) {
+	var encrypter = &envelopeEncrypter{kek: kek, tagSize: tagSize}
+	// Check the tag size
+	if _, err := encrypter.newAEAD(make([]byte, envelopeDataKeySize)); err != nil {
+		return nil, err
+	}
+	return encrypter, nil
+}
+
+func (e *envelopeEncrypter) GetCurrentKeyID() string {
+	return e.kek.GetCurrentKeyID()
+}
+
+func (e *envelopeEncrypter) newAEAD(dataKey []byte) (cipher.AEAD, error) {
+	var block, err = aes.NewCipher(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCMWithTagSize(block, e.tagSize)
+}
+
+func (e *envelopeEncrypter) Encrypt(value []byte, additional []byte) ([]byte, error) {
+	var dataKey = make([]byte, envelopeDataKeySize)
+	_, _ = rand.Read(dataKey)
+
+	var aead, err = e.newAEAD(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	var iv = make([]byte, envelopeIVSize)
+	_, _ = rand.Read(iv)
+
+	return e.wrap(dataKey, iv, aead.Seal(nil, iv, value, additional), additional)
+}
+
+func (e *envelopeEncrypter) Decrypt(encData []byte, kid string, additional []byte) ([]byte, error) {
+	var dataKey, iv, encrypted, err = e.unwrap(encData, kid, additional)
+	if err != nil {
+		return nil, err
+	}
+
+	var aead cipher.AEAD
+	if aead, err = e.newAEAD(dataKey); err != nil {
+		return nil, err
+	}
+	return aead.Open(nil, iv, encrypted, additional)
+}
+
+// rewrap encrypts the data key of an encrypted value with the current key-encryption key. The value is only decrypted to check
+// the data key
+func (e *envelopeEncrypter) rewrap(encData []byte, kid string, additional []byte) ([]byte, error) {
+	var dataKey, iv, encrypted, err = e.unwrap(encData, kid, additional)
+	if err != nil {
+		return nil, err
+	}
+
+	var aead cipher.AEAD
+	if aead, err = e.newAEAD(dataKey); err != nil {
+		return nil, err
+	}
+	if _, err = aead.Open(nil, iv, encrypted, additional); err != nil {
+		return nil, err
+	}
+	return e.wrap(dataKey, iv, encrypted, additional)
+}
+
+// wrap encrypts the data key with the current key-encryption key and assembles the encrypted value
+func (e *envelopeEncrypter) wrap(dataKey []byte, iv []byte, encrypted []byte, additional []byte) ([]byte, error) {
+	var wrappedKey, err = e.kek.Encrypt(dataKey, additional)
+	if err != nil {
+		return nil, err
+	}
+	if len(wrappedKey) > 0xFFFF {
+		return nil, errors.New(errorsMsg.MsgErrInvalidLength + "." + errorsMsg.EncryptDecrypt)
+	}
+
+	var res = make([]byte, envelopeWrappedKeyLenLen, envelopeWrappedKeyLenLen+len(wrappedKey)+len(iv)+len(encrypted))
+	binary.BigEndian.PutUint16(res, uint16(len(wrappedKey)))
+	res = append(res, wrappedKey...)
+	res = append(res, iv...)
+	return append(res, encrypted...), nil
+}
+
+// unwrap splits an encrypted value and decrypts its data key
+func (e *envelopeEncrypter) unwrap(encData []byte, kid string, additional []byte) ([]byte, []byte, []byte, error) {
+	if len(encData) < envelopeWrappedKeyLenLen {
+		return nil, nil, nil, errors.New(errorsMsg.MsgErrInvalidLength + "." + errorsMsg.Ciphertext)
+	}
+	var ivStart = envelopeWrappedKeyLenLen + int(binary.BigEndian.Uint16(encData))
+	if len(encData) <= ivStart+envelopeIVSize {
+		return nil, nil, nil, errors.New(errorsMsg.MsgErrInvalidLength + "." + errorsMsg.Ciphertext)
+	}
+
+	var dataKey, err = e.kek.Decrypt(encData[envelopeWrappedKeyLenLen:ivStart], kid, additional)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return dataKey, encData[ivStart : ivStart+envelopeIVSize], encData[ivStart+envelopeIVSize:], nil
+}
+
+// Rekey decrypts a value encrypted with any key known by the encrypter and encrypts it again with its current key. It returns
+// the new encrypted value and the id of the key used. Values already encrypted with the current key are returned unchanged.
+// With an envelope encrypter, only the data key of the value is encrypted again
+func Rekey(encrypter EncrypterDecrypter, encData []byte, kid string, additional []byte) ([]byte, string, error) {
+	var currentKid = encrypter.GetCurrentKeyID()
+	if kid == currentKid {
+		return encData, kid, nil
+	}
+	if envelope, ok := encrypter.(*envelopeEncrypter); ok {
+		var res, err = envelope.rewrap(encData, kid, additional)
+		if err != nil {
+			return nil, "", err
+		}
+		return res, currentKid, nil
+	}
+
+	var value, err = encrypter.Decrypt(encData, kid, additional)
+	if err != nil {
+		return nil, "", err
+	}
+	var res []byte
+	if res, err = encrypter.Encrypt(value, additional); err != nil {
+		return nil, "", err
+	}
+	return res, currentKid, nil
+}
@@ -0,0 +1,91 @@
+package security
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testKeys = `[
+	{"kid":"DBB_1","value":"ABCDEFGHIJKLMNOPQRSTUVWXYZ012345"},
+	{"kid":"DBB_2","value":"ABCDEFGHIJKLMNOPQRSTUVWXYZ012346"}
+]`
+
+var testOldKeys = `[
+	{"kid":"DBB_1","value":"ABCDEFGHIJKLMNOPQRSTUVWXYZ012345"}
+]`
+
+func TestEnvelopeEncrypter(t *testing.T) {
+	var kek, err = NewAesGcmEncrypterFromBase64(testKeys, 16)
+	assert.Nil(t, err)
+
+	t.Run("Invalid tag size", func(t *testing.T) {
+		var _, err = NewEnvelopeEncrypter(kek, 3)
+		assert.NotNil(t, err)
+	})
+
+	var encrypter, _ = NewEnvelopeEncrypter(kek, 16)
+	assert.Equal(t, "DBB_2", encrypter.GetCurrentKeyID())
+
+	t.Run("Encrypt/decrypt cycle", func(t *testing.T) {
+		testAesGcm(t, encrypter, []byte("Sample value used in an encrypt/decrypt cycle"))
+	})
+	t.Run("Each value has its own data key", func(t *testing.T) {
+		var value = []byte("same value")
+		var encrypted1, _ = encrypter.Encrypt(value, nil)
+		var encrypted2, _ = encrypter.Encrypt(value, nil)
+		assert.NotEqual(t, encrypted1, encrypted2)
+	})
+	t.Run("Invalid inputs", func(t *testing.T) {
+		var encrypted, _ = encrypter.Encrypt([]byte("value"), []byte("additional"))
+		var _, err = encrypter.Decrypt(encrypted, "DBB_2", []byte("other"))
+		assert.NotNil(t, err)
+		_, err = encrypter.Decrypt(encrypted, "DBB_3", []byte("additional"))
+		assert.NotNil(t, err)
+		_, err = encrypter.Decrypt([]byte{0}, "DBB_2", nil)
+		assert.NotNil(t, err)
+		_, err = encrypter.Decrypt(encrypted[:20], "DBB_2", []byte("additional"))
+		assert.NotNil(t, err)
+	})
+}
+
+func TestRekey(t *testing.T) {
+	var oldKeys, _ = NewAesGcmEncrypterFromBase64(testOldKeys, 16)
+	var keys, _ = NewAesGcmEncrypterFromBase64(testKeys, 16)
+	var value = []byte("value to re-key")
+	var additional = []byte("additional")
+
+	t.Run("Raw encryption", func(t *testing.T) {
+		var encrypted, _ = oldKeys.Encrypt(value, additional)
+		var rekeyed, kid, err = Rekey(keys, encrypted, "DBB_1", additional)
+		assert.Nil(t, err)
+		assert.Equal(t, "DBB_2", kid)
+		var decrypted, _ = keys.Decrypt(rekeyed, kid, additional)
+		assert.Equal(t, value, decrypted)
+
+		// Already encrypted with the current key
+		var unchanged []byte
+		unchanged, kid, err = Rekey(keys, rekeyed, kid, additional)
+		assert.Nil(t, err)
+		assert.Equal(t, "DBB_2", kid)
+		assert.Equal(t, rekeyed, unchanged)
+
+		_, _, err = Rekey(keys, encrypted, "DBB_1", []byte("other"))
+		assert.NotNil(t, err)
+	})
+	t.Run("Envelope encryption", func(t *testing.T) {
+		var oldEncrypter, _ = NewEnvelopeEncrypter(oldKeys, 16)
+		var encrypter, _ = NewEnvelopeEncrypter(keys, 16)
+		var encrypted, _ = oldEncrypter.Encrypt(value, additional)
+		var rekeyed, kid, err = Rekey(encrypter, encrypted, "DBB_1", additional)
+		assert.Nil(t, err)
+		assert.Equal(t, "DBB_2", kid)
+		var decrypted, _ = encrypter.Decrypt(rekeyed, kid, additional)
+		assert.Equal(t, value, decrypted)
+		// Only the data key is re-encrypted
+		assert.Equal(t, encrypted[len(encrypted)-len(value)-16:], rekeyed[len(rekeyed)-len(value)-16:])
+
+		_, _, err = Rekey(encrypter, encrypted, "DBB_1", []byte("other"))
+		assert.NotNil(t, err)
+	})
+}
@@ -0,0 +1,242 @@
+package security
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+
+	cs "github.com/cloudtrust/common-service/v2"
+	"github.com/cloudtrust/common-service/v2/events"
+)
+
+// Explained decision reasons
+const (
+	ReasonAllowed             = "allowed by matching authorizations"
+	ReasonDenied              = "denied by deny authorizations"
+	ReasonConditionsNotMet    = "conditions of the matching authorizations are not fulfilled"
+	ReasonNoMatchingEntry     = "no authorization of the current groups matches the action on the target"
+	ReasonNoCurrentGroups     = "current user has no group"
+	ReasonTargetUserLookup    = "can't get groups of the target user"
+	ReasonTargetGroupLookup   = "can't get name of the target group"
+	ReasonTargetGroupNotFound = "target group not found"
+	ReasonTargetNotAllowed    = "current user is not allowed to explain the decisions on the target"
+	ReasonSubjectLookup       = "can't get groups of the subject"
+	ReasonSubjectNotAllowed   = "current user is not allowed to explain the decisions of the subject"
+)
+
+// Keycloak operations reported in explained decisions
+const (
+	LookupGroupNamesOfUser = "GetGroupNamesOfUser"
+	LookupGroupName        = "GetGroupName"
+)
+
+// ExplainRequest describes the check to explain. Without target, the check is done on the target realm.
+// TargetUserID, TargetGroupID and TargetGroup are exclusive and considered in this order.
+// SubjectUserID explains the decision for another user than the current one, from SubjectRealm or from the realm of the current
+// user. The current user must be allowed MGMTExplainAuthorizations on the subject
+type ExplainRequest struct {
+	Action        string  `json:"action"`
+	TargetRealm   string  `json:"targetRealm"`
+	TargetGroup   *string `json:"targetGroup,omitempty"`
+	TargetGroupID *string `json:"targetGroupId,omitempty"`
+	TargetUserID  *string `json:"targetUserId,omitempty"`
+	SubjectRealm  *string `json:"subjectRealm,omitempty"`
+	SubjectUserID *string `json:"subjectUserId,omitempty"`
+}
+
+// AuthorizationMatch is an entry of the authorizations matrix which allows or denies the action. GroupWildcard is set for "*" and
+// hierarchical "parent/*" target group entries
+type AuthorizationMatch struct {
+	Group         string `json:"group"`
+	TargetRealm   string `json:"targetRealm"`
+	TargetGroup   string `json:"targetGroup,omitempty"`
+	RealmWildcard bool   `json:"realmWildcard"`
+	GroupWildcard bool   `json:"groupWildcard"`
+}
+
+// KeycloakLookup is a call to Keycloak performed to evaluate the decision
+type KeycloakLookup struct {
+	Operation string   `json:"operation"`
+	Realm     string   `json:"realm"`
+	ID        string   `json:"id"`
+	Result    []string `json:"result,omitempty"`
+	Error     string   `json:"error,omitempty"`
+}
+
+// AuthorizationDecision is the detailed result of an authorization check. CurrentRealm and CurrentGroups are the ones of the subject
+// when the decision is explained for another user
+type AuthorizationDecision struct {
+	Allowed          bool                 `json:"allowed"`
+	Reason           string               `json:"reason"`
+	Action           string               `json:"action"`
+	CurrentRealm     string               `json:"currentRealm"`
+	CurrentGroups    []string             `json:"currentGroups"`
+	TargetRealm      string               `json:"targetRealm"`
+	TargetGroups     []string             `json:"targetGroups,omitempty"`
+	MatchingEntries  []AuthorizationMatch `json:"matchingEntries"`
+	DenyingEntries   []AuthorizationMatch `json:"denyingEntries,omitempty"`
+	ConditionsNotMet []AuthorizationMatch `json:"conditionsNotMet,omitempty"`
+	KeycloakLookups  []KeycloakLookup     `json:"keycloakLookups,omitempty"`
+}
+
+// Explain evaluates an authorization check for the current user, or for the subject of the request, and details how the
+// decision is taken. It evaluates the same rules than the Check* methods but does not stop on the first matching entry.
+// The conditions of the authorizations of a subject are evaluated without the attributes of their requests: only the time
+// windows can be fulfilled
+func (am *authorizationManager) Explain(ctx context.Context, request ExplainRequest) AuthorizationDecision {
+	var decision = AuthorizationDecision{
+		Action:          request.Action,
+		TargetRealm:     request.TargetRealm,
+		CurrentGroups:   []string{},
+		MatchingEntries: []AuthorizationMatch{},
+	}
+	var attributes *requestAttributes
+	if request.SubjectUserID != nil {
+		if !am.explainSubject(ctx, request, &decision) {
+			return decision
+		}
+		attributes = &requestAttributes{now: am.now()}
+	} else {
+		if value, ok := ctx.Value(cs.CtContextRealm).(string); ok {
+			decision.CurrentRealm = value
+		}
+		if value, ok := ctx.Value(cs.CtContextGroups).([]string); ok {
+			decision.CurrentGroups = value
+		}
+		attributes = am.getRequestAttributes(ctx)
+	}
+
+	if !am.explainTargetGroups(ctx, request, &decision) {
+		return decision
+	}
+	if len(decision.CurrentGroups) == 0 {
+		decision.Reason = ReasonNoCurrentGroups
+		return decision
+	}
+
+	var loaded = am.getLoadedAuthorizations()
+	if decision.TargetGroups == nil {
+		decision.DenyingEntries, _ = am.findRealmDenial(loaded, attributes, decision.CurrentRealm, decision.CurrentGroups, request.Action, request.TargetRealm)
+		var matches, conditionsNotMet = am.findAllowances(loaded, attributes, decision.CurrentRealm, decision.CurrentGroups, request.Action, request.TargetRealm, nil, false)
+		decision.MatchingEntries = append(decision.MatchingEntries, matches...)
+		decision.ConditionsNotMet = conditionsNotMet
+	}
+	for _, targetGroup := range decision.TargetGroups {
+		var denials, _ = am.findGroupDenial(loaded, attributes, decision.CurrentRealm, decision.CurrentGroups, request.Action, request.TargetRealm, targetGroup)
+		decision.DenyingEntries = append(decision.DenyingEntries, denials...)
+		var matches, conditionsNotMet = am.findAllowances(loaded, attributes, decision.CurrentRealm, decision.CurrentGroups, request.Action, request.TargetRealm,
+			&targetGroup, false)
+		for _, match := range matches {
+			decision.MatchingEntries = appendMatch(decision.MatchingEntries, match)
+		}
+		for _, match := range conditionsNotMet {
+			decision.ConditionsNotMet = appendMatch(decision.ConditionsNotMet, match)
+		}
+	}
+
+	decision.Allowed = len(decision.MatchingEntries) > 0 && len(decision.DenyingEntries) == 0
+	if decision.Allowed {
+		decision.Reason = ReasonAllowed
+	} else if len(decision.DenyingEntries) > 0 {
+		decision.Reason = ReasonDenied
+	} else if len(decision.ConditionsNotMet) > 0 {
+		decision.Reason = ReasonConditionsNotMet
+	} else {
+		decision.Reason = ReasonNoMatchingEntry
+	}
+	return decision
+}
+
+// explainSubject gets the groups of the subject of the request once the current user is allowed to explain their decisions.
+// It returns false when the decision can't be evaluated further
+func (am *authorizationManager) explainSubject(ctx context.Context, request ExplainRequest, decision *AuthorizationDecision) bool {
+	var accessToken, _ = ctx.Value(cs.CtContextAccessToken).(string)
+	var subjectRealm, _ = ctx.Value(cs.CtContextRealm).(string)
+	if request.SubjectRealm != nil {
+		subjectRealm = *request.SubjectRealm
+	}
+	decision.CurrentRealm = subjectRealm
+
+	var groups, err = am.keycloakClient.GetGroupNamesOfUser(ctx, accessToken, subjectRealm, *request.SubjectUserID)
+	if am.checkExplainAllowed(ctx, subjectRealm, groups, err, map[string]string{events.CtEventTargetUserID: *request.SubjectUserID}) != nil {
+		// Lookups and groups of the subject are not disclosed
+		decision.Reason = ReasonSubjectNotAllowed
+		if err != nil {
+			decision.Reason = ReasonSubjectLookup
+		}
+		return false
+	}
+
+	var lookup = KeycloakLookup{Operation: LookupGroupNamesOfUser, Realm: subjectRealm, ID: *request.SubjectUserID}
+	lookup.Result = groups
+	decision.KeycloakLookups = append(decision.KeycloakLookups, lookup)
+	decision.CurrentGroups = groups
+	return true
+}
+
+func appendMatch(matches []AuthorizationMatch, match AuthorizationMatch) []AuthorizationMatch {
+	if slices.Contains(matches, match) {
+		return matches
+	}
+	return append(matches, match)
+}
+
+// explainTargetGroups resolves the target groups of the request. Groups looked up in Keycloak are only disclosed when the
+// current user is allowed MGMTExplainAuthorizations on them. It returns false when the decision can't be evaluated further
+func (am *authorizationManager) explainTargetGroups(ctx context.Context, request ExplainRequest, decision *AuthorizationDecision) bool {
+	var accessToken, _ = ctx.Value(cs.CtContextAccessToken).(string)
+
+	switch {
+	case request.TargetUserID != nil:
+		var groups, err = am.keycloakClient.GetGroupNamesOfUser(ctx, accessToken, request.TargetRealm, *request.TargetUserID)
+		if am.checkExplainAllowed(ctx, request.TargetRealm, groups, err, map[string]string{events.CtEventTargetUserID: *request.TargetUserID}) != nil {
+			decision.Reason = ReasonTargetNotAllowed
+			if err != nil {
+				decision.Reason = ReasonTargetUserLookup
+			}
+			return false
+		}
+		decision.KeycloakLookups = append(decision.KeycloakLookups, KeycloakLookup{Operation: LookupGroupNamesOfUser, Realm: request.TargetRealm,
+			ID: *request.TargetUserID, Result: groups})
+		decision.TargetGroups = groups
+	case request.TargetGroupID != nil:
+		var groupName, err = am.keycloakClient.GetGroupName(ctx, accessToken, request.TargetRealm, *request.TargetGroupID)
+		if err == nil && groupName == "" {
+			decision.Reason = ReasonTargetGroupNotFound
+			return false
+		}
+		if am.checkExplainAllowed(ctx, request.TargetRealm, []string{groupName}, err, map[string]string{events.CtEventGroupID: *request.TargetGroupID}) != nil {
+			decision.Reason = ReasonTargetNotAllowed
+			if err != nil {
+				decision.Reason = ReasonTargetGroupLookup
+			}
+			return false
+		}
+		decision.KeycloakLookups = append(decision.KeycloakLookups, KeycloakLookup{Operation: LookupGroupName, Realm: request.TargetRealm,
+			ID: *request.TargetGroupID, Result: []string{groupName}})
+		decision.TargetGroups = []string{groupName}
+	case request.TargetGroup != nil:
+		decision.TargetGroups = []string{*request.TargetGroup}
+	}
+	return true
+}
+
+// checkExplainAllowed checks that the current user is allowed MGMTExplainAuthorizations on groups looked up in Keycloak. A failed
+// lookup is reported as a denied authorization
+func (am *authorizationManager) checkExplainAllowed(ctx context.Context, targetRealm string, groups []string, lookupErr error,
+	details map[string]string) error {
+	infos, _ := json.Marshal(map[string]string{
+		"ThrownBy":    "Explain",
+		"Action":      MGMTExplainAuthorizations.Name,
+		"targetRealm": targetRealm,
+	})
+	var err error
+	if lookupErr != nil {
+		am.logger.Info(ctx, "msg", "ForbiddenError: "+lookupErr.Error(), "infos", string(infos))
+		err = suggestForbiddenError(lookupErr)
+	} else {
+		err = am.checkAuthorizationOnUserGroups(ctx, MGMTExplainAuthorizations.Name, targetRealm, groups, infos)
+	}
+	am.reportAuthorization(ctx, err, MGMTExplainAuthorizations.Name, targetRealm, details)
+	return err
+}
@@ -0,0 +1,239 @@
+package security
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	cs "github.com/cloudtrust/common-service/v2"
+	"github.com/cloudtrust/common-service/v2/configuration"
+	"github.com/cloudtrust/common-service/v2/log"
+	"github.com/cloudtrust/common-service/v2/security/mock"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+func TestExplain(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockKeycloakClient = mock.NewKeycloakClient(mockCtrl)
+	var mockAuthorizationDBReader = mock.NewAuthorizationDBReader(mockCtrl)
+
+	var accessToken = "TOKEN=="
+	var master = "master"
+	var toe = "toe"
+	var svc = "svc"
+	var customer = "customer"
+	var getRealm = "GetRealm"
+	var getUser = "GetUser"
+	var any = "*"
+	var anyNonMasterRealm = "/"
+	var userID = "user-id"
+	var groupID = "group-id"
+	var explain = MGMTExplainAuthorizations.Name
+
+	mockAuthorizationDBReader.EXPECT().GetAuthorizations(gomock.Any()).Return([]configuration.Authorization{
+		{RealmID: &master, GroupName: &toe, Action: &explain, TargetRealmID: &any, TargetGroupName: &any},
+		{RealmID: &master, GroupName: &toe, Action: &getRealm, TargetRealmID: &anyNonMasterRealm},
+		{RealmID: &master, GroupName: &svc, Action: &getRealm, TargetRealmID: &customer},
+		{RealmID: &master, GroupName: &toe, Action: &getUser, TargetRealmID: &any, TargetGroupName: &svc},
+		{RealmID: &master, GroupName: &svc, Action: &getUser, TargetRealmID: &customer, TargetGroupName: &any},
+	}, nil)
+	var authorizationManager, err = NewAuthorizationManager(mockAuthorizationDBReader, mockKeycloakClient, log.NewNopLogger())
+	assert.Nil(t, err)
+
+	var ctx = context.WithValue(context.Background(), cs.CtContextAccessToken, accessToken)
+	ctx = context.WithValue(ctx, cs.CtContextGroups, []string{toe, svc})
+	ctx = context.WithValue(ctx, cs.CtContextRealm, master)
+
+	t.Run("Realm check with wildcard", func(t *testing.T) {
+		var decision = authorizationManager.Explain(ctx, ExplainRequest{Action: getRealm, TargetRealm: customer})
+		assert.True(t, decision.Allowed)
+		assert.Equal(t, ReasonAllowed, decision.Reason)
+		assert.Equal(t, master, decision.CurrentRealm)
+		assert.Equal(t, []AuthorizationMatch{
+			{Group: toe, TargetRealm: anyNonMasterRealm, RealmWildcard: true},
+			{Group: svc, TargetRealm: customer},
+		}, decision.MatchingEntries)
+		assert.Nil(t, decision.KeycloakLookups)
+	})
+	t.Run("Non master wildcard does not apply on master", func(t *testing.T) {
+		var decision = authorizationManager.Explain(ctx, ExplainRequest{Action: getRealm, TargetRealm: master})
+		assert.False(t, decision.Allowed)
+		assert.Equal(t, ReasonNoMatchingEntry, decision.Reason)
+		assert.Len(t, decision.MatchingEntries, 0)
+	})
+	t.Run("No current groups", func(t *testing.T) {
+		var decision = authorizationManager.Explain(context.WithValue(ctx, cs.CtContextGroups, []string{}), ExplainRequest{Action: getRealm, TargetRealm: customer})
+		assert.False(t, decision.Allowed)
+		assert.Equal(t, ReasonNoCurrentGroups, decision.Reason)
+	})
+	t.Run("Target group", func(t *testing.T) {
+		var decision = authorizationManager.Explain(ctx, ExplainRequest{Action: getUser, TargetRealm: customer, TargetGroup: &svc})
+		assert.True(t, decision.Allowed)
+		assert.Equal(t, []string{svc}, decision.TargetGroups)
+		assert.Equal(t, []AuthorizationMatch{
+			{Group: toe, TargetRealm: any, TargetGroup: svc, RealmWildcard: true},
+			{Group: svc, TargetRealm: customer, TargetGroup: any, GroupWildcard: true},
+		}, decision.MatchingEntries)
+	})
+	t.Run("Target group ID", func(t *testing.T) {
+		t.Run("Keycloak fails", func(t *testing.T) {
+			mockKeycloakClient.EXPECT().GetGroupName(ctx, accessToken, master, groupID).Return("", errors.New("kc error"))
+			var decision = authorizationManager.Explain(ctx, ExplainRequest{Action: getUser, TargetRealm: master, TargetGroupID: &groupID})
+			assert.False(t, decision.Allowed)
+			assert.Equal(t, ReasonTargetGroupLookup, decision.Reason)
+			assert.Nil(t, decision.KeycloakLookups)
+		})
+		t.Run("Group not found", func(t *testing.T) {
+			mockKeycloakClient.EXPECT().GetGroupName(ctx, accessToken, master, groupID).Return("", nil)
+			var decision = authorizationManager.Explain(ctx, ExplainRequest{Action: getUser, TargetRealm: master, TargetGroupID: &groupID})
+			assert.Equal(t, ReasonTargetGroupNotFound, decision.Reason)
+		})
+		t.Run("Current user is not allowed to explain decisions on the target group", func(t *testing.T) {
+			var svcCtx = context.WithValue(ctx, cs.CtContextGroups, []string{svc})
+			mockKeycloakClient.EXPECT().GetGroupName(svcCtx, accessToken, master, groupID).Return(toe, nil)
+			var decision = authorizationManager.Explain(svcCtx, ExplainRequest{Action: getUser, TargetRealm: master, TargetGroupID: &groupID})
+			assert.Equal(t, ReasonTargetNotAllowed, decision.Reason)
+			assert.Nil(t, decision.KeycloakLookups)
+		})
+		t.Run("Not allowed", func(t *testing.T) {
+			mockKeycloakClient.EXPECT().GetGroupName(ctx, accessToken, master, groupID).Return(toe, nil)
+			var decision = authorizationManager.Explain(ctx, ExplainRequest{Action: getUser, TargetRealm: master, TargetGroupID: &groupID})
+			assert.False(t, decision.Allowed)
+			assert.Equal(t, ReasonNoMatchingEntry, decision.Reason)
+			assert.Equal(t, []KeycloakLookup{{Operation: LookupGroupName, Realm: master, ID: groupID, Result: []string{toe}}}, decision.KeycloakLookups)
+		})
+	})
+	t.Run("Target user", func(t *testing.T) {
+		t.Run("Keycloak fails", func(t *testing.T) {
+			mockKeycloakClient.EXPECT().GetGroupNamesOfUser(ctx, accessToken, master, userID).Return(nil, errors.New("kc error"))
+			var decision = authorizationManager.Explain(ctx, ExplainRequest{Action: getUser, TargetRealm: master, TargetUserID: &userID})
+			assert.Equal(t, ReasonTargetUserLookup, decision.Reason)
+		})
+		t.Run("User without groups", func(t *testing.T) {
+			mockKeycloakClient.EXPECT().GetGroupNamesOfUser(ctx, accessToken, master, userID).Return([]string{}, nil)
+			var decision = authorizationManager.Explain(ctx, ExplainRequest{Action: getUser, TargetRealm: master, TargetUserID: &userID})
+			assert.Equal(t, ReasonTargetNotAllowed, decision.Reason)
+		})
+		t.Run("Current user is not allowed to explain decisions on the target user", func(t *testing.T) {
+			var svcCtx = context.WithValue(ctx, cs.CtContextGroups, []string{svc})
+			mockKeycloakClient.EXPECT().GetGroupNamesOfUser(svcCtx, accessToken, customer, userID).Return([]string{toe}, nil)
+			var decision = authorizationManager.Explain(svcCtx, ExplainRequest{Action: getUser, TargetRealm: customer, TargetUserID: &userID})
+			assert.False(t, decision.Allowed)
+			assert.Equal(t, ReasonTargetNotAllowed, decision.Reason)
+			assert.Nil(t, decision.KeycloakLookups)
+			assert.Nil(t, decision.TargetGroups)
+		})
+		t.Run("Allowed", func(t *testing.T) {
+			mockKeycloakClient.EXPECT().GetGroupNamesOfUser(ctx, accessToken, master, userID).Return([]string{toe, svc}, nil)
+			var decision = authorizationManager.Explain(ctx, ExplainRequest{Action: getUser, TargetRealm: master, TargetUserID: &userID})
+			assert.True(t, decision.Allowed)
+			assert.Equal(t, []string{toe, svc}, decision.TargetGroups)
+			assert.Equal(t, []AuthorizationMatch{{Group: toe, TargetRealm: any, TargetGroup: svc, RealmWildcard: true}}, decision.MatchingEntries)
+		})
+	})
+}
+
+func TestExplainSubject(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockKeycloakClient = mock.NewKeycloakClient(mockCtrl)
+	var mockAuthorizationDBReader = mock.NewAuthorizationDBReader(mockCtrl)
+
+	var accessToken = "TOKEN=="
+	var master = "master"
+	var customer = "customer"
+	var toe = "toe"
+	var support = "support"
+	var vip = "vip"
+	var getRealm = "GetRealm"
+	var getUser = "GetUser"
+	var explain = MGMTExplainAuthorizations.Name
+	var subjectID = "subject-id"
+
+	mockAuthorizationDBReader.EXPECT().GetAuthorizations(gomock.Any()).Return([]configuration.Authorization{
+		{RealmID: &master, GroupName: &toe, Action: &explain, TargetRealmID: &customer, TargetGroupName: &support},
+		{RealmID: &customer, GroupName: &support, Action: &getRealm, TargetRealmID: &customer},
+		{RealmID: &customer, GroupName: &support, Action: &getUser, TargetRealmID: &customer, TargetGroupName: &support},
+	}, nil)
+	var manager, err = NewAuthorizationManager(mockAuthorizationDBReader, mockKeycloakClient, log.NewNopLogger())
+	assert.Nil(t, err)
+
+	var ctx = context.WithValue(context.Background(), cs.CtContextAccessToken, accessToken)
+	ctx = context.WithValue(ctx, cs.CtContextGroups, []string{toe})
+	ctx = context.WithValue(ctx, cs.CtContextRealm, master)
+
+	t.Run("Keycloak fails", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().GetGroupNamesOfUser(ctx, accessToken, customer, subjectID).Return(nil, errors.New("kc error"))
+		var decision = manager.Explain(ctx, ExplainRequest{Action: getRealm, TargetRealm: customer, SubjectRealm: &customer, SubjectUserID: &subjectID})
+		assert.False(t, decision.Allowed)
+		assert.Equal(t, ReasonSubjectLookup, decision.Reason)
+		assert.Nil(t, decision.KeycloakLookups)
+	})
+	t.Run("Current user is not allowed to explain the subject", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().GetGroupNamesOfUser(ctx, accessToken, customer, subjectID).Return([]string{vip}, nil)
+		var decision = manager.Explain(ctx, ExplainRequest{Action: getRealm, TargetRealm: customer, SubjectRealm: &customer, SubjectUserID: &subjectID})
+		assert.False(t, decision.Allowed)
+		assert.Equal(t, ReasonSubjectNotAllowed, decision.Reason)
+		assert.Len(t, decision.CurrentGroups, 0)
+		assert.Nil(t, decision.KeycloakLookups)
+	})
+	t.Run("Subject realm defaults to the current realm", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().GetGroupNamesOfUser(ctx, accessToken, master, subjectID).Return([]string{support}, nil)
+		var decision = manager.Explain(ctx, ExplainRequest{Action: getRealm, TargetRealm: customer, SubjectUserID: &subjectID})
+		assert.Equal(t, master, decision.CurrentRealm)
+		assert.Equal(t, ReasonSubjectNotAllowed, decision.Reason)
+	})
+	t.Run("Decision of the subject", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().GetGroupNamesOfUser(ctx, accessToken, customer, subjectID).Return([]string{support}, nil)
+		var decision = manager.Explain(ctx, ExplainRequest{Action: getUser, TargetRealm: customer, TargetGroup: &support, SubjectRealm: &customer, SubjectUserID: &subjectID})
+		assert.True(t, decision.Allowed)
+		assert.Equal(t, customer, decision.CurrentRealm)
+		assert.Equal(t, []string{support}, decision.CurrentGroups)
+		assert.Equal(t, []AuthorizationMatch{{Group: support, TargetRealm: customer, TargetGroup: support}}, decision.MatchingEntries)
+		assert.Equal(t, []KeycloakLookup{{Operation: LookupGroupNamesOfUser, Realm: customer, ID: subjectID, Result: []string{support}}}, decision.KeycloakLookups)
+	})
+}
+
+func TestExplainMatchesChecks(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockKeycloakClient = mock.NewKeycloakClient(mockCtrl)
+	var mockAuthorizationDBReader = mock.NewAuthorizationDBReader(mockCtrl)
+
+	var master = "master"
+	var customer = "customer"
+	var toe = "toe"
+	var support = "support"
+	var team = "team*"
+	var any = "*"
+	var anyNonMasterRealm = "/"
+	var getRealm = "GetRealm"
+	var getUser = "GetUser"
+
+	mockAuthorizationDBReader.EXPECT().GetAuthorizations(gomock.Any()).Return([]configuration.Authorization{
+		{RealmID: &master, GroupName: &toe, Action: &getRealm, TargetRealmID: &anyNonMasterRealm},
+		{RealmID: &master, GroupName: &toe, Action: &getUser, TargetRealmID: &any, TargetGroupName: &team},
+		{RealmID: &master, GroupName: &support, Action: &getUser, TargetRealmID: &customer, TargetGroupName: &any},
+		{RealmID: &master, GroupName: &support, Action: &getUser, TargetRealmID: &customer, TargetGroupName: &toe, Deny: true},
+	}, nil)
+	var manager, err = NewAuthorizationManager(mockAuthorizationDBReader, mockKeycloakClient, log.NewNopLogger())
+	assert.Nil(t, err)
+
+	var ctx = context.WithValue(context.Background(), cs.CtContextAccessToken, "TOKEN==")
+	ctx = context.WithValue(ctx, cs.CtContextRealm, master)
+
+	for _, groups := range [][]string{{toe}, {support}, {toe, support}} {
+		var groupsCtx = context.WithValue(ctx, cs.CtContextGroups, groups)
+		for _, targetRealm := range []string{master, customer} {
+			var decision = manager.Explain(groupsCtx, ExplainRequest{Action: getRealm, TargetRealm: targetRealm})
+			assert.Equal(t, manager.CheckAuthorizationOnTargetRealm(groupsCtx, getRealm, targetRealm) == nil, decision.Allowed, "%v on %s", groups, targetRealm)
+			for _, targetGroup := range []string{toe, support, "team-a"} {
+				decision = manager.Explain(groupsCtx, ExplainRequest{Action: getUser, TargetRealm: targetRealm, TargetGroup: &targetGroup})
+				assert.Equal(t, manager.CheckAuthorizationOnTargetGroup(groupsCtx, getUser, targetRealm, targetGroup) == nil, decision.Allowed,
+					"%v on %s/%s", groups, targetRealm, targetGroup)
+			}
+		}
+	}
+}
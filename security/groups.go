@@ -0,0 +1,73 @@
+package security
+
+import "strings"
+
+const groupPathSeparator = "/"
+
+// WithHierarchicalGroups enables hierarchical matching of Keycloak group paths such as branch/team:
+//   - a caller member of branch/team also gets the rights granted to branch
+//   - a target group entry branch/* allows all the subgroups of branch, at any depth
+//
+// Leading slashes of group paths are ignored. Without this option, group names are matched exactly
+func WithHierarchicalGroups() AuthorizationManagerOption {
+	return func(am *authorizationManager) {
+		am.hierarchicalGroups = true
+	}
+}
+
+// expandGroups returns the groups of a caller completed, when hierarchical groups are enabled, with their parent groups
+func (am *authorizationManager) expandGroups(groups []string) []string {
+	if !am.hierarchicalGroups {
+		return groups
+	}
+	var res []string
+	var known = map[string]bool{}
+	for _, group := range groups {
+		for _, path := range groupAncestors(strings.TrimPrefix(group, groupPathSeparator)) {
+			if !known[path] {
+				known[path] = true
+				res = append(res, path)
+			}
+		}
+	}
+	return res
+}
+
+// matchingTargetGroups returns all the entries of the allowed target groups which match the target group, "*" first
+func (am *authorizationManager) matchingTargetGroups(allowed map[string]struct{}, targetGroup string) []string {
+	var res []string
+	if _, ok := allowed["*"]; ok {
+		res = append(res, "*")
+	}
+	if _, ok := allowed[targetGroup]; ok && targetGroup != "*" {
+		res = append(res, targetGroup)
+	}
+	if !am.hierarchicalGroups {
+		return res
+	}
+	var trimmed = strings.TrimPrefix(targetGroup, groupPathSeparator)
+	if _, ok := allowed[trimmed]; ok && trimmed != targetGroup {
+		res = append(res, trimmed)
+	}
+	// Ancestors of the target group, the target group itself excluded
+	for _, parent := range groupAncestors(trimmed)[1:] {
+		var pattern = parent + groupPathSeparator + "*"
+		if _, ok := allowed[pattern]; ok {
+			res = append(res, pattern)
+		}
+	}
+	return res
+}
+
+// groupAncestors returns a group path followed by the paths of its parents, closest first
+func groupAncestors(path string) []string {
+	var res = []string{path}
+	for {
+		var index = strings.LastIndex(path, groupPathSeparator)
+		if index <= 0 {
+			return res
+		}
+		path = path[:index]
+		res = append(res, path)
+	}
+}
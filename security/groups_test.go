@@ -0,0 +1,75 @@
+package security
+
+import (
+	"context"
+	"testing"
+
+	cs "github.com/cloudtrust/common-service/v2"
+	"github.com/cloudtrust/common-service/v2/configuration"
+	"github.com/cloudtrust/common-service/v2/log"
+	"github.com/cloudtrust/common-service/v2/security/mock"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+func TestGroupAncestors(t *testing.T) {
+	assert.Equal(t, []string{"a"}, groupAncestors("a"))
+	assert.Equal(t, []string{"a/b/c", "a/b", "a"}, groupAncestors("a/b/c"))
+}
+
+func TestHierarchicalGroups(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockKeycloakClient = mock.NewKeycloakClient(mockCtrl)
+	var mockAuthorizationDBReader = mock.NewAuthorizationDBReader(mockCtrl)
+
+	var master = "master"
+	var customer = "customer"
+	var branch = "branch"
+	var getRealm = "GetRealm"
+	var getUser = "GetUser"
+	var branchSubgroups = "branch/*"
+	var support = "support"
+	var authorizations = []configuration.Authorization{
+		{RealmID: &master, GroupName: &branch, Action: &getRealm, TargetRealmID: &customer},
+		{RealmID: &master, GroupName: &branch, Action: &getUser, TargetRealmID: &customer, TargetGroupName: &branchSubgroups},
+		{RealmID: &master, GroupName: &branch, Action: &getUser, TargetRealmID: &customer, TargetGroupName: &support},
+	}
+
+	var ctx = context.WithValue(context.Background(), cs.CtContextRealm, master)
+	ctx = context.WithValue(ctx, cs.CtContextGroups, []string{"branch/team"})
+
+	t.Run("Flat groups by default", func(t *testing.T) {
+		mockAuthorizationDBReader.EXPECT().GetAuthorizations(gomock.Any()).Return(authorizations, nil)
+		var am, err = NewAuthorizationManager(mockAuthorizationDBReader, mockKeycloakClient, log.NewNopLogger())
+		assert.Nil(t, err)
+
+		assert.NotNil(t, am.CheckAuthorizationOnTargetRealm(ctx, getRealm, customer))
+		assert.Nil(t, am.CheckAuthorizationForGroupsOnTargetGroup(master, []string{branch}, getUser, customer, branchSubgroups))
+		assert.NotNil(t, am.CheckAuthorizationForGroupsOnTargetGroup(master, []string{branch}, getUser, customer, "branch/team"))
+		assert.Len(t, am.GetRightsOfCurrentUser(ctx), 0)
+	})
+	t.Run("Hierarchical groups", func(t *testing.T) {
+		mockAuthorizationDBReader.EXPECT().GetAuthorizations(gomock.Any()).Return(authorizations, nil)
+		var am, err = NewAuthorizationManager(mockAuthorizationDBReader, mockKeycloakClient, log.NewNopLogger(), WithHierarchicalGroups())
+		assert.Nil(t, err)
+
+		// Caller inherits rights of its parent groups
+		assert.Nil(t, am.CheckAuthorizationOnTargetRealm(ctx, getRealm, customer))
+		assert.Nil(t, am.CheckAuthorizationForGroupsOnTargetRealm(master, []string{"/branch/team/sub"}, getRealm, customer))
+		assert.NotNil(t, am.CheckAuthorizationForGroupsOnTargetRealm(master, []string{"branches/team"}, getRealm, customer))
+		assert.Contains(t, am.GetRightsOfCurrentUser(ctx), branch)
+
+		// Target subgroups are matched by the parent/* pattern
+		assert.Nil(t, am.CheckAuthorizationOnTargetGroup(ctx, getUser, customer, "branch/team"))
+		assert.Nil(t, am.CheckAuthorizationOnTargetGroup(ctx, getUser, customer, "/branch/team/sub"))
+		assert.Nil(t, am.CheckAuthorizationOnTargetGroup(ctx, getUser, customer, "/support"))
+		assert.NotNil(t, am.CheckAuthorizationOnTargetGroup(ctx, getUser, customer, branch))
+		assert.NotNil(t, am.CheckAuthorizationOnTargetGroup(ctx, getUser, customer, "other/team"))
+
+		var team = "branch/team"
+		var decision = am.Explain(ctx, ExplainRequest{Action: getUser, TargetRealm: customer, TargetGroup: &team})
+		assert.True(t, decision.Allowed)
+		assert.Equal(t, []AuthorizationMatch{{Group: branch, TargetRealm: customer, TargetGroup: branchSubgroups, GroupWildcard: true}}, decision.MatchingEntries)
+	})
+}
@@ -0,0 +1,277 @@
+package security
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"time"
+)
+
+// UserLookupsInvalidatingActions are the actions after which the cached Keycloak lookups of the target user are obsolete
+// (see InvalidateOnAction)
+var UserLookupsInvalidatingActions = []Action{MGMTSetGroupsToUser, MGMTSetTrustIDGroups, MGMTAddRoleToUser, MGMTDeleteRoleForUser, MGMTDeleteUser}
+
+type lookupKey struct {
+	kind  string
+	realm string
+	id    string
+}
+
+const (
+	lookupGroupsOfUser = "groupsOfUser"
+	lookupRolesOfUser  = "rolesOfUser"
+	lookupGroupName    = "groupName"
+)
+
+type lookupEntry struct {
+	key     lookupKey
+	value   any
+	expires time.Time
+}
+
+type lookupCall struct {
+	done  chan struct{}
+	value any
+	err   error
+}
+
+// lookupCache is a bounded LRU cache of Keycloak lookups with a TTL. Concurrent lookups of the same key share a single call
+// to Keycloak. Errors are never cached
+type lookupCache struct {
+	ttl        time.Duration
+	maxEntries int
+	mutex      sync.Mutex
+	entries    map[lookupKey]*list.Element
+	lru        *list.List
+	calls      map[lookupKey]*lookupCall
+	now        func() time.Time
+}
+
+func newLookupCache(ttl time.Duration, maxEntries int) *lookupCache {
+	return &lookupCache{
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		entries:    map[lookupKey]*list.Element{},
+		lru:        list.New(),
+		calls:      map[lookupKey]*lookupCall{},
+		now:        time.Now,
+	}
+}
+
+// get returns the cached value of a key or looks it up. Callers waiting for the lookup of another caller stop waiting when their
+// context ends
+func (c *lookupCache) get(ctx context.Context, key lookupKey, lookup func() (any, error)) (any, error) {
+	for {
+		c.mutex.Lock()
+		if elem, ok := c.entries[key]; ok {
+			var entry = elem.Value.(*lookupEntry)
+			if c.now().Before(entry.expires) {
+				c.lru.MoveToFront(elem)
+				c.mutex.Unlock()
+				return entry.value, nil
+			}
+			c.removeElement(elem)
+		}
+		if call, ok := c.calls[key]; ok {
+			c.mutex.Unlock()
+			select {
+			case <-call.done:
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+			if errors.Is(call.err, context.Canceled) || errors.Is(call.err, context.DeadlineExceeded) {
+				// The context of the caller which made the call ended: the lookup is made again with the context of this caller
+				continue
+			}
+			return call.value, call.err
+		}
+		var call = &lookupCall{done: make(chan struct{})}
+		c.calls[key] = call
+		c.mutex.Unlock()
+
+		call.value, call.err = lookup()
+
+		c.mutex.Lock()
+		// The call may have been invalidated while running: its result is then returned but not cached
+		if c.calls[key] == call {
+			delete(c.calls, key)
+			if call.err == nil {
+				c.store(key, call.value)
+			}
+		}
+		c.mutex.Unlock()
+		close(call.done)
+
+		return call.value, call.err
+	}
+}
+
+func (c *lookupCache) store(key lookupKey, value any) {
+	if c.maxEntries <= 0 {
+		return
+	}
+	for c.lru.Len() >= c.maxEntries {
+		c.removeElement(c.lru.Back())
+	}
+	c.entries[key] = c.lru.PushFront(&lookupEntry{key: key, value: value, expires: c.now().Add(c.ttl)})
+}
+
+func (c *lookupCache) removeElement(elem *list.Element) {
+	c.lru.Remove(elem)
+	delete(c.entries, elem.Value.(*lookupEntry).key)
+}
+
+// invalidate removes the entries matching the filter and detaches the matching running calls
+func (c *lookupCache) invalidate(filter func(lookupKey) bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for key, elem := range c.entries {
+		if filter(key) {
+			c.removeElement(elem)
+		}
+	}
+	for key := range c.calls {
+		if filter(key) {
+			delete(c.calls, key)
+		}
+	}
+}
+
+func (c *lookupCache) len() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.lru.Len()
+}
+
+// cachedLookups provides the invalidation of the cached lookups of the caching decorators
+type cachedLookups struct {
+	cache         *lookupCache
+	tokenProvider TokenProvider
+}
+
+// TokenProvider provides the access token of a service account
+type TokenProvider interface {
+	ProvideToken(ctx context.Context) (string, error)
+}
+
+// CachingKeycloakClient is a KeycloakClient decorator which caches the lookups of groups.
+// Entries are shared by all the callers: lookups are made with the access token of a service account and the access token of
+// the callers is ignored. Concurrent lookups of the same entry share the call made with the context of the first caller
+type CachingKeycloakClient struct {
+	cachedLookups
+	keycloakClient KeycloakClient
+}
+
+// CachingRoleBasedKeycloakClient is a RoleBasedKeycloakClient decorator which caches the lookups of roles. Entries are shared as
+// with CachingKeycloakClient
+type CachingRoleBasedKeycloakClient struct {
+	cachedLookups
+	keycloakClient RoleBasedKeycloakClient
+}
+
+// NewCachingKeycloakClient creates a caching decorator of a KeycloakClient. Lookups are made with the access token provided by
+// tokenProvider. Entries expire after ttl and at most maxEntries entries are kept, the least recently used ones being evicted first
+func NewCachingKeycloakClient(keycloakClient KeycloakClient, tokenProvider TokenProvider, ttl time.Duration, maxEntries int) *CachingKeycloakClient {
+	return &CachingKeycloakClient{
+		cachedLookups:  cachedLookups{cache: newLookupCache(ttl, maxEntries), tokenProvider: tokenProvider},
+		keycloakClient: keycloakClient,
+	}
+}
+
+// NewCachingRoleBasedKeycloakClient creates a caching decorator of a RoleBasedKeycloakClient. Lookups are made with the access
+// token provided by tokenProvider. Entries expire after ttl and at most maxEntries entries are kept, the least recently used ones
+// being evicted first
+func NewCachingRoleBasedKeycloakClient(keycloakClient RoleBasedKeycloakClient, tokenProvider TokenProvider, ttl time.Duration,
+	maxEntries int) *CachingRoleBasedKeycloakClient {
+	return &CachingRoleBasedKeycloakClient{
+		cachedLookups:  cachedLookups{cache: newLookupCache(ttl, maxEntries), tokenProvider: tokenProvider},
+		keycloakClient: keycloakClient,
+	}
+}
+
+// GetGroupNamesOfUser returns the cached groups of a user or gets them from the decorated KeycloakClient. accessToken is ignored
+func (c *CachingKeycloakClient) GetGroupNamesOfUser(ctx context.Context, _ string, realmName, userID string) ([]string, error) {
+	var res, err = c.cache.get(ctx, lookupKey{kind: lookupGroupsOfUser, realm: realmName, id: userID}, func() (any, error) {
+		var accessToken, err = c.tokenProvider.ProvideToken(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return c.keycloakClient.GetGroupNamesOfUser(ctx, accessToken, realmName, userID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return slices.Clone(res.([]string)), nil
+}
+
+// GetGroupName returns the cached name of a group or gets it from the decorated KeycloakClient. accessToken is ignored
+func (c *CachingKeycloakClient) GetGroupName(ctx context.Context, _ string, realmName, groupID string) (string, error) {
+	var res, err = c.cache.get(ctx, lookupKey{kind: lookupGroupName, realm: realmName, id: groupID}, func() (any, error) {
+		var accessToken, err = c.tokenProvider.ProvideToken(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return c.keycloakClient.GetGroupName(ctx, accessToken, realmName, groupID)
+	})
+	if err != nil {
+		return "", err
+	}
+	return res.(string), nil
+}
+
+// GetRoleNamesOfUser returns the cached roles of a user or gets them from the decorated RoleBasedKeycloakClient. accessToken is
+// ignored
+func (c *CachingRoleBasedKeycloakClient) GetRoleNamesOfUser(ctx context.Context, _ string, realmName, userID string) ([]string, error) {
+	var res, err = c.cache.get(ctx, lookupKey{kind: lookupRolesOfUser, realm: realmName, id: userID}, func() (any, error) {
+		var accessToken, err = c.tokenProvider.ProvideToken(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return c.keycloakClient.GetRoleNamesOfUser(ctx, accessToken, realmName, userID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return slices.Clone(res.([]string)), nil
+}
+
+// InvalidateUser removes the cached groups and roles of a user
+func (c *cachedLookups) InvalidateUser(realmName, userID string) {
+	c.cache.invalidate(func(key lookupKey) bool {
+		return key.kind != lookupGroupName && key.realm == realmName && key.id == userID
+	})
+}
+
+// InvalidateGroup removes the cached name of a group
+func (c *cachedLookups) InvalidateGroup(realmName, groupID string) {
+	c.cache.invalidate(func(key lookupKey) bool {
+		return key.kind == lookupGroupName && key.realm == realmName && key.id == groupID
+	})
+}
+
+// InvalidateRealm removes all the cached entries of a realm
+func (c *cachedLookups) InvalidateRealm(realmName string) {
+	c.cache.invalidate(func(key lookupKey) bool {
+		return key.realm == realmName
+	})
+}
+
+// InvalidateAll removes all the cached entries
+func (c *cachedLookups) InvalidateAll() {
+	c.cache.invalidate(func(lookupKey) bool {
+		return true
+	})
+}
+
+// InvalidateOnAction is a hook to call once an action has been performed on a target user. It invalidates the cached entries of
+// the user when the action is one of UserLookupsInvalidatingActions
+func (c *cachedLookups) InvalidateOnAction(action string, realmName, userID string) {
+	for _, invalidatingAction := range UserLookupsInvalidatingActions {
+		if invalidatingAction.Name == action {
+			c.InvalidateUser(realmName, userID)
+			return
+		}
+	}
+}
@@ -0,0 +1,212 @@
+package security
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cloudtrust/common-service/v2/security/mock"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+func TestCachingKeycloakClient(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockKeycloakClient = mock.NewKeycloakClient(mockCtrl)
+	var mockTokenProvider = mock.NewTokenProvider(mockCtrl)
+
+	var ctx = context.TODO()
+	var accessToken = "SERVICE-ACCOUNT-TOKEN=="
+	var callerToken = "TOKEN=="
+	var realm = "realm"
+	var userID = "user-id"
+	var groupID = "group-id"
+	var groups = []string{"group1", "group2"}
+	var now = time.Now()
+
+	var client = NewCachingKeycloakClient(mockKeycloakClient, mockTokenProvider, time.Minute, 2)
+	client.cache.now = func() time.Time { return now }
+	mockTokenProvider.EXPECT().ProvideToken(gomock.Any()).Return(accessToken, nil).AnyTimes()
+
+	t.Run("Errors are not cached", func(t *testing.T) {
+		var kcErr = errors.New("kc error")
+		mockKeycloakClient.EXPECT().GetGroupNamesOfUser(ctx, accessToken, realm, userID).Return(nil, kcErr)
+		var _, err = client.GetGroupNamesOfUser(ctx, callerToken, realm, userID)
+		assert.Equal(t, kcErr, err)
+
+		mockKeycloakClient.EXPECT().GetGroupName(ctx, accessToken, realm, groupID).Return("", kcErr)
+		_, err = client.GetGroupName(ctx, callerToken, realm, groupID)
+		assert.Equal(t, kcErr, err)
+		assert.Equal(t, 0, client.cache.len())
+	})
+	t.Run("Lookups are cached until they expire", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().GetGroupNamesOfUser(ctx, accessToken, realm, userID).Return(groups, nil)
+		for range 3 {
+			var res, err = client.GetGroupNamesOfUser(ctx, callerToken, realm, userID)
+			assert.Nil(t, err)
+			assert.Equal(t, groups, res)
+		}
+		mockKeycloakClient.EXPECT().GetGroupName(ctx, accessToken, realm, groupID).Return("group1", nil)
+		for range 2 {
+			var res, err = client.GetGroupName(ctx, callerToken, realm, groupID)
+			assert.Nil(t, err)
+			assert.Equal(t, "group1", res)
+		}
+
+		now = now.Add(2 * time.Minute)
+		mockKeycloakClient.EXPECT().GetGroupName(ctx, accessToken, realm, groupID).Return("group2", nil)
+		var res, _ = client.GetGroupName(ctx, callerToken, realm, groupID)
+		assert.Equal(t, "group2", res)
+	})
+	t.Run("Cached values can't be altered by callers", func(t *testing.T) {
+		client.InvalidateAll()
+		mockKeycloakClient.EXPECT().GetGroupNamesOfUser(ctx, accessToken, realm, userID).Return([]string{"group1"}, nil)
+		var res, _ = client.GetGroupNamesOfUser(ctx, callerToken, realm, userID)
+		res[0] = "altered"
+		res, _ = client.GetGroupNamesOfUser(ctx, callerToken, realm, userID)
+		assert.Equal(t, []string{"group1"}, res)
+	})
+	t.Run("Size is bounded", func(t *testing.T) {
+		client.InvalidateAll()
+		for _, id := range []string{"a", "b", "c"} {
+			mockKeycloakClient.EXPECT().GetGroupName(ctx, accessToken, realm, id).Return(id, nil)
+			_, _ = client.GetGroupName(ctx, callerToken, realm, id)
+		}
+		assert.Equal(t, 2, client.cache.len())
+
+		// Least recently used entry has been evicted
+		mockKeycloakClient.EXPECT().GetGroupName(ctx, accessToken, realm, "a").Return("a", nil)
+		_, _ = client.GetGroupName(ctx, callerToken, realm, "a")
+		_, _ = client.GetGroupName(ctx, callerToken, realm, "c")
+	})
+	t.Run("Invalidation", func(t *testing.T) {
+		client.InvalidateAll()
+		mockKeycloakClient.EXPECT().GetGroupNamesOfUser(ctx, accessToken, realm, userID).Return(groups, nil).Times(3)
+		mockKeycloakClient.EXPECT().GetGroupName(ctx, accessToken, realm, groupID).Return("group1", nil).Times(2)
+
+		_, _ = client.GetGroupNamesOfUser(ctx, callerToken, realm, userID)
+		_, _ = client.GetGroupName(ctx, callerToken, realm, groupID)
+
+		// Action without effect on groups
+		client.InvalidateOnAction(MGMTGetUser.Name, realm, userID)
+		// Group name is not invalidated with the user
+		client.InvalidateOnAction(MGMTSetGroupsToUser.Name, realm, userID)
+		_, _ = client.GetGroupNamesOfUser(ctx, callerToken, realm, userID)
+		_, _ = client.GetGroupName(ctx, callerToken, realm, groupID)
+
+		client.InvalidateGroup(realm, groupID)
+		client.InvalidateUser("other", userID)
+		_, _ = client.GetGroupName(ctx, callerToken, realm, groupID)
+		_, _ = client.GetGroupNamesOfUser(ctx, callerToken, realm, userID)
+
+		client.InvalidateRealm(realm)
+		_, _ = client.GetGroupNamesOfUser(ctx, callerToken, realm, userID)
+	})
+	t.Run("Concurrent lookups share a single call", func(t *testing.T) {
+		client.InvalidateAll()
+		var release = make(chan struct{})
+		mockKeycloakClient.EXPECT().GetGroupNamesOfUser(ctx, accessToken, realm, userID).DoAndReturn(func(context.Context, string, string, string) ([]string, error) {
+			<-release
+			return groups, nil
+		})
+
+		var wg sync.WaitGroup
+		for range 5 {
+			wg.Go(func() {
+				var res, err = client.GetGroupNamesOfUser(ctx, callerToken, realm, userID)
+				assert.Nil(t, err)
+				assert.Equal(t, groups, res)
+			})
+		}
+		time.Sleep(20 * time.Millisecond)
+		close(release)
+		wg.Wait()
+	})
+	t.Run("Context errors of the first caller are not shared", func(t *testing.T) {
+		client.InvalidateAll()
+		var release = make(chan struct{})
+		var cancelledCtx, cancel = context.WithCancel(ctx)
+		cancel()
+		mockKeycloakClient.EXPECT().GetGroupNamesOfUser(cancelledCtx, accessToken, realm, userID).DoAndReturn(func(context.Context, string, string, string) ([]string, error) {
+			<-release
+			return nil, context.Canceled
+		})
+		mockKeycloakClient.EXPECT().GetGroupNamesOfUser(ctx, accessToken, realm, userID).Return(groups, nil)
+
+		var wg sync.WaitGroup
+		wg.Go(func() {
+			var _, err = client.GetGroupNamesOfUser(cancelledCtx, callerToken, realm, userID)
+			assert.Equal(t, context.Canceled, err)
+		})
+		time.Sleep(20 * time.Millisecond)
+		wg.Go(func() {
+			var res, err = client.GetGroupNamesOfUser(ctx, callerToken, realm, userID)
+			assert.Nil(t, err)
+			assert.Equal(t, groups, res)
+		})
+		time.Sleep(20 * time.Millisecond)
+		close(release)
+		wg.Wait()
+	})
+	t.Run("Waiters stop waiting when their context ends", func(t *testing.T) {
+		client.InvalidateAll()
+		var release = make(chan struct{})
+		mockKeycloakClient.EXPECT().GetGroupNamesOfUser(ctx, accessToken, realm, userID).DoAndReturn(func(context.Context, string, string, string) ([]string, error) {
+			<-release
+			return groups, nil
+		})
+
+		var wg sync.WaitGroup
+		wg.Go(func() {
+			var res, err = client.GetGroupNamesOfUser(ctx, callerToken, realm, userID)
+			assert.Nil(t, err)
+			assert.Equal(t, groups, res)
+		})
+		time.Sleep(20 * time.Millisecond)
+		var waiterCtx, cancel = context.WithTimeout(ctx, 20*time.Millisecond)
+		defer cancel()
+		var _, err = client.GetGroupNamesOfUser(waiterCtx, callerToken, realm, userID)
+		assert.Equal(t, context.DeadlineExceeded, err)
+		close(release)
+		wg.Wait()
+	})
+}
+
+func TestCachingRoleBasedKeycloakClient(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockKeycloakClient = mock.NewRoleBasedKeycloakClient(mockCtrl)
+	var mockTokenProvider = mock.NewTokenProvider(mockCtrl)
+
+	var ctx = context.TODO()
+	var accessToken = "SERVICE-ACCOUNT-TOKEN=="
+	var callerToken = "TOKEN=="
+	var realm = "realm"
+	var userID = "user-id"
+	var roles = []string{"role1"}
+
+	var client = NewCachingRoleBasedKeycloakClient(mockKeycloakClient, mockTokenProvider, time.Minute, 10)
+
+	var tokenErr = errors.New("token error")
+	mockTokenProvider.EXPECT().ProvideToken(ctx).Return("", tokenErr)
+	var _, err = client.GetRoleNamesOfUser(ctx, callerToken, realm, userID)
+	assert.Equal(t, tokenErr, err)
+
+	mockTokenProvider.EXPECT().ProvideToken(ctx).Return(accessToken, nil).AnyTimes()
+
+	mockKeycloakClient.EXPECT().GetRoleNamesOfUser(ctx, accessToken, realm, userID).Return(nil, errors.New("kc error"))
+	_, err = client.GetRoleNamesOfUser(ctx, callerToken, realm, userID)
+	assert.NotNil(t, err)
+
+	mockKeycloakClient.EXPECT().GetRoleNamesOfUser(ctx, accessToken, realm, userID).Return(roles, nil).Times(2)
+	for range 2 {
+		var res, err = client.GetRoleNamesOfUser(ctx, callerToken, realm, userID)
+		assert.Nil(t, err)
+		assert.Equal(t, roles, res)
+	}
+	client.InvalidateOnAction(MGMTAddRoleToUser.Name, realm, userID)
+	_, _ = client.GetRoleNamesOfUser(ctx, callerToken, realm, userID)
+}
@@ -0,0 +1,310 @@
+package security
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"sort"
+
+	"github.com/cloudtrust/common-service/v2/configuration"
+)
+
+// Escalation reasons
+const (
+	EscalationGlobalScope = "global scope action"
+	EscalationMasterRealm = "access to the master realm"
+	EscalationAllGroups   = "access to all the groups of a realm"
+	EscalationDenyRemoved = "deny entry removed"
+	// EscalationConditionsRelaxed is reported when the conditions of an allow entry are removed or changed
+	EscalationConditionsRelaxed = "conditions removed or changed"
+	// EscalationDenyReduced is reported when conditions are added to a deny entry or changed, which reduces the denial
+	EscalationDenyReduced = "deny entry conditions added or changed"
+)
+
+const (
+	wildcardAllTargets     = "*"
+	wildcardNonMasterRealm = "/"
+	masterRealm            = "master"
+)
+
+// MatrixDiffOptions configures the comparison of authorizations
+type MatrixDiffOptions struct {
+	// Realms are used to expand the '*' and '/' target realm wildcards. Wildcards are compared as is when no realm is given
+	Realms []string
+	// ActionScopes gives the scope of the actions. Defaults to the scopes of the known Actions
+	ActionScopes map[string]Scope
+}
+
+// AuthorizationGrant is an expanded entry of the authorizations matrix of a group. Conditions are the JSON conditions of the entry
+type AuthorizationGrant struct {
+	Action      string `json:"action"`
+	TargetRealm string `json:"targetRealm,omitempty"`
+	TargetGroup string `json:"targetGroup,omitempty"`
+	Conditions  string `json:"conditions,omitempty"`
+}
+
+// GroupRightsDiff lists the changes of the rights of a group. Gained and Lost are the effective rights, once deny entries are
+// applied. DeniesAdded and DeniesRemoved are the changes of the deny entries
+type GroupRightsDiff struct {
+	Realm         string               `json:"realm"`
+	Group         string               `json:"group"`
+	Gained        []AuthorizationGrant `json:"gained,omitempty"`
+	Lost          []AuthorizationGrant `json:"lost,omitempty"`
+	DeniesAdded   []AuthorizationGrant `json:"deniesAdded,omitempty"`
+	DeniesRemoved []AuthorizationGrant `json:"deniesRemoved,omitempty"`
+}
+
+// Escalation is a change which extends the rights of a group in a sensitive way
+type Escalation struct {
+	Realm   string             `json:"realm"`
+	Group   string             `json:"group"`
+	Grant   AuthorizationGrant `json:"grant"`
+	Reasons []string           `json:"reasons"`
+}
+
+// MatrixDiffReport is the result of the comparison of two sets of authorizations
+type MatrixDiffReport struct {
+	Groups      []GroupRightsDiff `json:"groups"`
+	Escalations []Escalation      `json:"escalations"`
+}
+
+// HasEscalations tells whether the report contains escalations
+func (r MatrixDiffReport) HasEscalations() bool {
+	return len(r.Escalations) > 0
+}
+
+type groupKey struct {
+	realm string
+	group string
+}
+
+type groupGrants struct {
+	allowed map[AuthorizationGrant]struct{}
+	denied  map[AuthorizationGrant]struct{}
+}
+
+// DiffAuthorizationReaders compares the authorizations of two sources, for instance two snapshots of the database
+func DiffAuthorizationReaders(ctx context.Context, before, after AuthorizationDBReader, options MatrixDiffOptions) (MatrixDiffReport, error) {
+	beforeAuthorizations, err := before.GetAuthorizations(ctx)
+	if err != nil {
+		return MatrixDiffReport{}, err
+	}
+	afterAuthorizations, err := after.GetAuthorizations(ctx)
+	if err != nil {
+		return MatrixDiffReport{}, err
+	}
+	return DiffAuthorizations(beforeAuthorizations, afterAuthorizations, options), nil
+}
+
+// DiffAuthorizations compares two sets of authorizations and reports, by group, the rights which are gained or lost.
+// Entries are compared with their conditions: a conditional deny entry does not reduce the effective rights.
+// Gained rights on global scope actions, on the master realm or on all the groups of a realm, removed deny entries, removed or
+// changed conditions of allow entries and added or changed conditions of deny entries are reported as escalations
+func DiffAuthorizations(before, after []configuration.Authorization, options MatrixDiffOptions) MatrixDiffReport {
+	var scopes = options.ActionScopes
+	if scopes == nil {
+		scopes = map[string]Scope{}
+		for _, action := range Actions.GetAllActions() {
+			scopes[action.Name] = action.Scope
+		}
+	}
+
+	var beforeGrants = expandAuthorizations(before, options.Realms)
+	var afterGrants = expandAuthorizations(after, options.Realms)
+
+	var keys []groupKey
+	for key := range beforeGrants {
+		keys = append(keys, key)
+	}
+	for key := range afterGrants {
+		if _, ok := beforeGrants[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].realm != keys[j].realm {
+			return keys[i].realm < keys[j].realm
+		}
+		return keys[i].group < keys[j].group
+	})
+
+	var report = MatrixDiffReport{Groups: []GroupRightsDiff{}, Escalations: []Escalation{}}
+	for _, key := range keys {
+		var previous, current = beforeGrants[key], afterGrants[key]
+		var beforeEffective, afterEffective = previous.effective(), current.effective()
+		var diff = GroupRightsDiff{
+			Realm:         key.realm,
+			Group:         key.group,
+			Gained:        grantsDifference(afterEffective, beforeEffective),
+			Lost:          grantsDifference(beforeEffective, afterEffective),
+			DeniesAdded:   grantsDifference(current.denied, previous.denied),
+			DeniesRemoved: grantsDifference(previous.denied, current.denied),
+		}
+		if len(diff.Gained)+len(diff.Lost)+len(diff.DeniesAdded)+len(diff.DeniesRemoved) == 0 {
+			continue
+		}
+		report.Groups = append(report.Groups, diff)
+
+		for _, grant := range diff.Gained {
+			if reasons := gainEscalationReasons(grant, beforeEffective, scopes); len(reasons) > 0 {
+				report.Escalations = append(report.Escalations, Escalation{Realm: key.realm, Group: key.group, Grant: grant, Reasons: reasons})
+			}
+		}
+		for _, grant := range diff.DeniesRemoved {
+			var conditions = sameTargetConditions(grant, current.denied)
+			if slices.Contains(conditions, "") {
+				// The deny entry is now unconditional: the denial is extended
+				continue
+			}
+			var reasons = []string{EscalationDenyRemoved}
+			if len(conditions) > 0 {
+				reasons = []string{EscalationDenyReduced}
+			}
+			report.Escalations = append(report.Escalations, Escalation{Realm: key.realm, Group: key.group, Grant: grant, Reasons: reasons})
+		}
+	}
+	return report
+}
+
+// expandAuthorizations converts authorizations into grants by group. Target realm wildcards are replaced by the given realms
+func expandAuthorizations(authorizations []configuration.Authorization, realms []string) map[groupKey]groupGrants {
+	var res = map[groupKey]groupGrants{}
+	for _, authz := range authorizations {
+		if authz.RealmID == nil || authz.GroupName == nil || authz.Action == nil {
+			continue
+		}
+		var key = groupKey{realm: *authz.RealmID, group: *authz.GroupName}
+		var grants, ok = res[key]
+		if !ok {
+			grants = groupGrants{allowed: map[AuthorizationGrant]struct{}{}, denied: map[AuthorizationGrant]struct{}{}}
+			res[key] = grants
+		}
+		var target = grants.allowed
+		if authz.Deny {
+			target = grants.denied
+		}
+
+		var targetGroup, conditions string
+		if authz.TargetGroupName != nil {
+			targetGroup = *authz.TargetGroupName
+		}
+		if authz.Conditions != nil {
+			var bytes, _ = json.Marshal(authz.Conditions)
+			conditions = string(bytes)
+		}
+		if authz.TargetRealmID == nil {
+			target[AuthorizationGrant{Action: *authz.Action, Conditions: conditions}] = struct{}{}
+			continue
+		}
+		for _, targetRealm := range expandTargetRealm(*authz.TargetRealmID, realms) {
+			target[AuthorizationGrant{Action: *authz.Action, TargetRealm: targetRealm, TargetGroup: targetGroup, Conditions: conditions}] = struct{}{}
+		}
+	}
+	return res
+}
+
+func expandTargetRealm(targetRealm string, realms []string) []string {
+	if len(realms) == 0 || (targetRealm != wildcardAllTargets && targetRealm != wildcardNonMasterRealm) {
+		return []string{targetRealm}
+	}
+	var res []string
+	for _, realm := range realms {
+		if targetRealm == wildcardAllTargets || realm != masterRealm {
+			res = append(res, realm)
+		}
+	}
+	return res
+}
+
+// effective returns the allowed grants which are not denied. A grant on some groups of a realm is denied by an unconditional deny
+// entry on the whole realm or on the same group
+func (g groupGrants) effective() map[AuthorizationGrant]struct{} {
+	var res = map[AuthorizationGrant]struct{}{}
+	for grant := range g.allowed {
+		if !g.isDenied(grant) {
+			res[grant] = struct{}{}
+		}
+	}
+	return res
+}
+
+func (g groupGrants) isDenied(grant AuthorizationGrant) bool {
+	for denied := range g.denied {
+		if denied.Conditions != "" || denied.Action != grant.Action || !deniedRealmMatches(denied.TargetRealm, grant.TargetRealm) {
+			continue
+		}
+		if denied.TargetGroup == "" || denied.TargetGroup == wildcardAllTargets || denied.TargetGroup == grant.TargetGroup {
+			return true
+		}
+	}
+	return false
+}
+
+func deniedRealmMatches(deniedRealm, targetRealm string) bool {
+	if deniedRealm == wildcardAllTargets || deniedRealm == targetRealm {
+		return true
+	}
+	return deniedRealm == wildcardNonMasterRealm && targetRealm != masterRealm && targetRealm != wildcardAllTargets
+}
+
+// grantsDifference returns the grants of a which are not in b, sorted
+func grantsDifference(a, b map[AuthorizationGrant]struct{}) []AuthorizationGrant {
+	var res []AuthorizationGrant
+	for grant := range a {
+		if _, ok := b[grant]; !ok {
+			res = append(res, grant)
+		}
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Action != res[j].Action {
+			return res[i].Action < res[j].Action
+		}
+		if res[i].TargetRealm != res[j].TargetRealm {
+			return res[i].TargetRealm < res[j].TargetRealm
+		}
+		if res[i].TargetGroup != res[j].TargetGroup {
+			return res[i].TargetGroup < res[j].TargetGroup
+		}
+		return res[i].Conditions < res[j].Conditions
+	})
+	return res
+}
+
+// sameTargetConditions returns the conditions of the grants having the same action and targets as grant
+func sameTargetConditions(grant AuthorizationGrant, grants map[AuthorizationGrant]struct{}) []string {
+	var res []string
+	for other := range grants {
+		if other.Action == grant.Action && other.TargetRealm == grant.TargetRealm && other.TargetGroup == grant.TargetGroup {
+			res = append(res, other.Conditions)
+		}
+	}
+	return res
+}
+
+// gainEscalationReasons returns the escalation reasons of a gained grant. A grant which was already held with other conditions
+// is an escalation only if it was always conditional
+func gainEscalationReasons(grant AuthorizationGrant, before map[AuthorizationGrant]struct{}, scopes map[string]Scope) []string {
+	var conditions = sameTargetConditions(grant, before)
+	switch {
+	case len(conditions) == 0:
+		return escalationReasons(grant, scopes)
+	case slices.Contains(conditions, ""):
+		return nil
+	default:
+		return []string{EscalationConditionsRelaxed}
+	}
+}
+
+func escalationReasons(grant AuthorizationGrant, scopes map[string]Scope) []string {
+	var reasons []string
+	if scopes[grant.Action] == ScopeGlobal {
+		reasons = append(reasons, EscalationGlobalScope)
+	}
+	if grant.TargetRealm == masterRealm || grant.TargetRealm == wildcardAllTargets {
+		reasons = append(reasons, EscalationMasterRealm)
+	}
+	if grant.TargetGroup == wildcardAllTargets {
+		reasons = append(reasons, EscalationAllGroups)
+	}
+	return reasons
+}
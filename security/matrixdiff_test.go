@@ -0,0 +1,176 @@
+package security
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/cloudtrust/common-service/v2/configuration"
+	"github.com/cloudtrust/common-service/v2/security/mock"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+func TestDiffAuthorizations(t *testing.T) {
+	var master = "master"
+	var customer = "customer"
+	var partner = "partner"
+	var toe = "toe"
+	var support = "support"
+	var getUser = "GetUser"
+	var getRealm = "GetRealm"
+	var globalAction = "GlobalAction"
+	var any = "*"
+	var anyNonMasterRealm = "/"
+	var options = MatrixDiffOptions{
+		Realms:       []string{master, customer, partner},
+		ActionScopes: map[string]Scope{getUser: ScopeGroup, getRealm: ScopeRealm, globalAction: ScopeGlobal},
+	}
+
+	t.Run("No change", func(t *testing.T) {
+		var authorizations = []configuration.Authorization{
+			{RealmID: &master, GroupName: &toe, Action: &getRealm, TargetRealmID: &customer},
+		}
+		var report = DiffAuthorizations(authorizations, authorizations, options)
+		assert.Len(t, report.Groups, 0)
+		assert.False(t, report.HasEscalations())
+	})
+	t.Run("Wildcards are expanded", func(t *testing.T) {
+		var before = []configuration.Authorization{
+			{RealmID: &master, GroupName: &toe, Action: &getRealm, TargetRealmID: &customer},
+		}
+		var after = []configuration.Authorization{
+			{RealmID: &master, GroupName: &toe, Action: &getRealm, TargetRealmID: &anyNonMasterRealm},
+		}
+		var report = DiffAuthorizations(before, after, options)
+		assert.Equal(t, []GroupRightsDiff{{Realm: master, Group: toe, Gained: []AuthorizationGrant{{Action: getRealm, TargetRealm: partner}}}}, report.Groups)
+		assert.False(t, report.HasEscalations())
+
+		// Without known realms, wildcards are compared as is
+		report = DiffAuthorizations(before, after, MatrixDiffOptions{ActionScopes: options.ActionScopes})
+		assert.Equal(t, []GroupRightsDiff{{
+			Realm:  master,
+			Group:  toe,
+			Gained: []AuthorizationGrant{{Action: getRealm, TargetRealm: anyNonMasterRealm}},
+			Lost:   []AuthorizationGrant{{Action: getRealm, TargetRealm: customer}},
+		}}, report.Groups)
+	})
+	t.Run("Escalations", func(t *testing.T) {
+		var before = []configuration.Authorization{
+			{RealmID: &master, GroupName: &toe, Action: &getUser, TargetRealmID: &customer, TargetGroupName: &support},
+			{RealmID: &master, GroupName: &toe, Action: &getRealm, TargetRealmID: &master, Deny: true},
+		}
+		var after = []configuration.Authorization{
+			{RealmID: &master, GroupName: &toe, Action: &getUser, TargetRealmID: &customer, TargetGroupName: &any},
+			{RealmID: &master, GroupName: &support, Action: &globalAction, TargetRealmID: &any},
+		}
+		var report = DiffAuthorizations(before, after, options)
+		assert.Len(t, report.Groups, 2)
+		assert.Equal(t, GroupRightsDiff{
+			Realm:         master,
+			Group:         toe,
+			Gained:        []AuthorizationGrant{{Action: getUser, TargetRealm: customer, TargetGroup: any}},
+			Lost:          []AuthorizationGrant{{Action: getUser, TargetRealm: customer, TargetGroup: support}},
+			DeniesRemoved: []AuthorizationGrant{{Action: getRealm, TargetRealm: master}},
+		}, report.Groups[1])
+		assert.True(t, report.HasEscalations())
+		assert.Equal(t, []Escalation{
+			{Realm: master, Group: support, Grant: AuthorizationGrant{Action: globalAction, TargetRealm: customer}, Reasons: []string{EscalationGlobalScope}},
+			{Realm: master, Group: support, Grant: AuthorizationGrant{Action: globalAction, TargetRealm: master}, Reasons: []string{EscalationGlobalScope, EscalationMasterRealm}},
+			{Realm: master, Group: support, Grant: AuthorizationGrant{Action: globalAction, TargetRealm: partner}, Reasons: []string{EscalationGlobalScope}},
+			{Realm: master, Group: toe, Grant: AuthorizationGrant{Action: getUser, TargetRealm: customer, TargetGroup: any}, Reasons: []string{EscalationAllGroups}},
+			{Realm: master, Group: toe, Grant: AuthorizationGrant{Action: getRealm, TargetRealm: master}, Reasons: []string{EscalationDenyRemoved}},
+		}, report.Escalations)
+	})
+	t.Run("Deny entries reduce effective rights", func(t *testing.T) {
+		var before = []configuration.Authorization{
+			{RealmID: &master, GroupName: &toe, Action: &getUser, TargetRealmID: &any, TargetGroupName: &support},
+		}
+		var after = []configuration.Authorization{
+			{RealmID: &master, GroupName: &toe, Action: &getUser, TargetRealmID: &any, TargetGroupName: &support},
+			{RealmID: &master, GroupName: &toe, Action: &getUser, TargetRealmID: &anyNonMasterRealm, Deny: true},
+		}
+		var report = DiffAuthorizations(before, after, options)
+		assert.Equal(t, []GroupRightsDiff{{
+			Realm:       master,
+			Group:       toe,
+			Lost:        []AuthorizationGrant{{Action: getUser, TargetRealm: customer, TargetGroup: support}, {Action: getUser, TargetRealm: partner, TargetGroup: support}},
+			DeniesAdded: []AuthorizationGrant{{Action: getUser, TargetRealm: customer}, {Action: getUser, TargetRealm: partner}},
+		}}, report.Groups)
+		assert.False(t, report.HasEscalations())
+	})
+	t.Run("Conditions", func(t *testing.T) {
+		var internalNetwork = &configuration.AuthorizationConditions{SourceCIDRs: []string{"10.0.0.0/8"}}
+		var internalNetworkJSON = `{"source_cidrs":["10.0.0.0/8"]}`
+		var conditional = []configuration.Authorization{
+			{RealmID: &master, GroupName: &toe, Action: &getRealm, TargetRealmID: &customer, Conditions: internalNetwork},
+			{RealmID: &master, GroupName: &toe, Action: &getUser, TargetRealmID: &customer, Deny: true},
+		}
+		var unconditional = []configuration.Authorization{
+			{RealmID: &master, GroupName: &toe, Action: &getRealm, TargetRealmID: &customer},
+			{RealmID: &master, GroupName: &toe, Action: &getUser, TargetRealmID: &customer, Deny: true, Conditions: internalNetwork},
+		}
+
+		var report = DiffAuthorizations(conditional, unconditional, options)
+		assert.Equal(t, []GroupRightsDiff{{
+			Realm:         master,
+			Group:         toe,
+			Gained:        []AuthorizationGrant{{Action: getRealm, TargetRealm: customer}},
+			Lost:          []AuthorizationGrant{{Action: getRealm, TargetRealm: customer, Conditions: internalNetworkJSON}},
+			DeniesAdded:   []AuthorizationGrant{{Action: getUser, TargetRealm: customer, Conditions: internalNetworkJSON}},
+			DeniesRemoved: []AuthorizationGrant{{Action: getUser, TargetRealm: customer}},
+		}}, report.Groups)
+		assert.Equal(t, []Escalation{
+			{Realm: master, Group: toe, Grant: AuthorizationGrant{Action: getRealm, TargetRealm: customer}, Reasons: []string{EscalationConditionsRelaxed}},
+			{Realm: master, Group: toe, Grant: AuthorizationGrant{Action: getUser, TargetRealm: customer}, Reasons: []string{EscalationDenyReduced}},
+		}, report.Escalations)
+
+		// Restricting the allow entry and extending the deny entry are not escalations
+		report = DiffAuthorizations(unconditional, conditional, options)
+		assert.Len(t, report.Groups, 1)
+		assert.False(t, report.HasEscalations())
+
+		// A conditional deny entry does not reduce the effective rights
+		report = DiffAuthorizations(nil, []configuration.Authorization{
+			{RealmID: &master, GroupName: &toe, Action: &getRealm, TargetRealmID: &customer},
+			{RealmID: &master, GroupName: &toe, Action: &getRealm, TargetRealmID: &customer, Deny: true, Conditions: internalNetwork},
+		}, options)
+		assert.Equal(t, []AuthorizationGrant{{Action: getRealm, TargetRealm: customer}}, report.Groups[0].Gained)
+	})
+}
+
+func TestDiffAuthorizationReaders(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockBefore = mock.NewAuthorizationDBReader(mockCtrl)
+	var mockAfter = mock.NewAuthorizationDBReader(mockCtrl)
+
+	var ctx = context.TODO()
+	var master = "master"
+	var toe = "toe"
+	var action = "COM_SendEmail"
+	var anyRealm = "*"
+	var readerErr = errors.New("db error")
+
+	t.Run("Before fails", func(t *testing.T) {
+		mockBefore.EXPECT().GetAuthorizations(ctx).Return(nil, readerErr)
+		var _, err = DiffAuthorizationReaders(ctx, mockBefore, mockAfter, MatrixDiffOptions{})
+		assert.Equal(t, readerErr, err)
+	})
+	t.Run("After fails", func(t *testing.T) {
+		mockBefore.EXPECT().GetAuthorizations(ctx).Return(nil, nil)
+		mockAfter.EXPECT().GetAuthorizations(ctx).Return(nil, readerErr)
+		var _, err = DiffAuthorizationReaders(ctx, mockBefore, mockAfter, MatrixDiffOptions{})
+		assert.Equal(t, readerErr, err)
+	})
+	t.Run("Success using scopes of known actions", func(t *testing.T) {
+		mockBefore.EXPECT().GetAuthorizations(ctx).Return(nil, nil)
+		mockAfter.EXPECT().GetAuthorizations(ctx).Return([]configuration.Authorization{
+			{RealmID: &master, GroupName: &toe, Action: &action, TargetRealmID: &anyRealm},
+		}, nil)
+		var report, err = DiffAuthorizationReaders(ctx, mockBefore, mockAfter, MatrixDiffOptions{})
+		assert.Nil(t, err)
+		assert.Len(t, report.Groups, 1)
+		assert.Equal(t, []Escalation{{Realm: master, Group: toe, Grant: AuthorizationGrant{Action: action, TargetRealm: anyRealm}, Reasons: []string{EscalationMasterRealm}}}, report.Escalations)
+	})
+}
@@ -1,9 +1,9 @@
 // Code generated by MockGen. DO NOT EDIT.
-// Source: github.com/cloudtrust/common-service/v2/security (interfaces: KeycloakClient,RoleBasedKeycloakClient)
+// Source: github.com/cloudtrust/common-service/v2/security (interfaces: KeycloakClient,RoleBasedKeycloakClient,BulkKeycloakClient)
 //
 // Generated by this command:
 //
-//	mockgen --build_flags=--mod=mod -destination=./mock/keycloak_client.go -package=mock -mock_names=KeycloakClient=KeycloakClient,RoleBasedKeycloakClient=RoleBasedKeycloakClient github.com/cloudtrust/common-service/v2/security KeycloakClient,RoleBasedKeycloakClient
+//	mockgen --build_flags=--mod=mod -destination=./mock/keycloak_client.go -package=mock -mock_names=KeycloakClient=KeycloakClient,RoleBasedKeycloakClient=RoleBasedKeycloakClient,BulkKeycloakClient=BulkKeycloakClient github.com/cloudtrust/common-service/v2/security KeycloakClient,RoleBasedKeycloakClient,BulkKeycloakClient
 //
 
 // Package mock is a generated GoMock package.
@@ -108,3 +108,42 @@ func (mr *RoleBasedKeycloakClientMockRecorder) GetRoleNamesOfUser(ctx, accessTok
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRoleNamesOfUser", reflect.TypeOf((*RoleBasedKeycloakClient)(nil).GetRoleNamesOfUser), ctx, accessToken, realmName, userID)
 }
+
+// BulkKeycloakClient is a mock of BulkKeycloakClient interface.
+type BulkKeycloakClient struct {
+	ctrl     *gomock.Controller
+	recorder *BulkKeycloakClientMockRecorder
+	isgomock struct{}
+}
+
+// BulkKeycloakClientMockRecorder is the mock recorder for BulkKeycloakClient.
+type BulkKeycloakClientMockRecorder struct {
+	mock *BulkKeycloakClient
+}
+
+// NewBulkKeycloakClient creates a new mock instance.
+func NewBulkKeycloakClient(ctrl *gomock.Controller) *BulkKeycloakClient {
+	mock := &BulkKeycloakClient{ctrl: ctrl}
+	mock.recorder = &BulkKeycloakClientMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *BulkKeycloakClient) EXPECT() *BulkKeycloakClientMockRecorder {
+	return m.recorder
+}
+
+// GetGroupNamesOfUsers mocks base method.
+func (m *BulkKeycloakClient) GetGroupNamesOfUsers(ctx context.Context, accessToken, realmName string, userIDs []string) (map[string][]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetGroupNamesOfUsers", ctx, accessToken, realmName, userIDs)
+	ret0, _ := ret[0].(map[string][]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetGroupNamesOfUsers indicates an expected call of GetGroupNamesOfUsers.
+func (mr *BulkKeycloakClientMockRecorder) GetGroupNamesOfUsers(ctx, accessToken, realmName, userIDs any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetGroupNamesOfUsers", reflect.TypeOf((*BulkKeycloakClient)(nil).GetGroupNamesOfUsers), ctx, accessToken, realmName, userIDs)
+}
@@ -0,0 +1,56 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/cloudtrust/common-service/v2/security (interfaces: TokenProvider)
+//
+// Generated by this command:
+//
+//	mockgen --build_flags=--mod=mod -destination=./mock/tokenprovider.go -package=mock -mock_names=TokenProvider=TokenProvider github.com/cloudtrust/common-service/v2/security TokenProvider
+//
+
+// Package mock is a generated GoMock package.
+package mock
+
+import (
+	context "context"
+	reflect "reflect"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// TokenProvider is a mock of TokenProvider interface.
+type TokenProvider struct {
+	ctrl     *gomock.Controller
+	recorder *TokenProviderMockRecorder
+	isgomock struct{}
+}
+
+// TokenProviderMockRecorder is the mock recorder for TokenProvider.
+type TokenProviderMockRecorder struct {
+	mock *TokenProvider
+}
+
+// NewTokenProvider creates a new mock instance.
+func NewTokenProvider(ctrl *gomock.Controller) *TokenProvider {
+	mock := &TokenProvider{ctrl: ctrl}
+	mock.recorder = &TokenProviderMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *TokenProvider) EXPECT() *TokenProviderMockRecorder {
+	return m.recorder
+}
+
+// ProvideToken mocks base method.
+func (m *TokenProvider) ProvideToken(ctx context.Context) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ProvideToken", ctx)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ProvideToken indicates an expected call of ProvideToken.
+func (mr *TokenProviderMockRecorder) ProvideToken(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ProvideToken", reflect.TypeOf((*TokenProvider)(nil).ProvideToken), ctx)
+}
@@ -1,5 +1,9 @@
 package security
 
-//go:generate mockgen --build_flags=--mod=mod -destination=./mock/keycloak_client.go -package=mock -mock_names=KeycloakClient=KeycloakClient,RoleBasedKeycloakClient=RoleBasedKeycloakClient github.com/cloudtrust/common-service/v2/security KeycloakClient,RoleBasedKeycloakClient
+//go:generate mockgen --build_flags=--mod=mod -destination=./mock/keycloak_client.go -package=mock -mock_names=KeycloakClient=KeycloakClient,RoleBasedKeycloakClient=RoleBasedKeycloakClient,BulkKeycloakClient=BulkKeycloakClient github.com/cloudtrust/common-service/v2/security KeycloakClient,RoleBasedKeycloakClient,BulkKeycloakClient
+//go:generate mockgen --build_flags=--mod=mod -destination=./mock/tokenprovider.go -package=mock -mock_names=TokenProvider=TokenProvider github.com/cloudtrust/common-service/v2/security TokenProvider
 //go:generate mockgen --build_flags=--mod=mod -destination=./mock/authentication_db_reader.go -package=mock -mock_names=AuthorizationDBReader=AuthorizationDBReader,RoleBasedAuthorizationDBReader=RoleBasedAuthorizationDBReader github.com/cloudtrust/common-service/v2/security AuthorizationDBReader,RoleBasedAuthorizationDBReader
 //go:generate mockgen --build_flags=--mod=mod -destination=./mock/detailederr.go -package=mock -mock_names=DetailedError=DetailedError github.com/cloudtrust/common-service/v2/errors DetailedError
+//go:generate mockgen --build_flags=--mod=mod -destination=./mock/logging.go -package=mock -mock_names=Logger=Logger github.com/cloudtrust/common-service/v2/log Logger
+//go:generate mockgen --build_flags=--mod=mod -destination=./mock/eventsreportermodule.go -package=mock -mock_names=AuditEventsReporterModule=AuditEventsReporterModule github.com/cloudtrust/common-service/v2/events AuditEventsReporterModule
+//go:generate mockgen --build_flags=--mod=mod -destination=./mock/cloudtrustdb.go -package=mock -mock_names=CloudtrustDB=CloudtrustDB,SQLRows=SQLRows,Transaction=Transaction github.com/cloudtrust/common-service/v2/database/sqltypes CloudtrustDB,SQLRows,Transaction
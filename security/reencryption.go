@@ -0,0 +1,191 @@
+package security
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"github.com/cloudtrust/common-service/v2/database/sqltypes"
+	errorsMsg "github.com/cloudtrust/common-service/v2/errors"
+	"github.com/cloudtrust/common-service/v2/log"
+)
+
+const defaultReencryptionBatchSize = 100
+
+var sqlIdentifierRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// ReencryptionTable describes a table holding encrypted values. Values of each row are encrypted with the key identified by
+// the kid column. NumericID must be set when the identifier column is an integer column, so that rows are paginated in the
+// numeric order. AdditionalData, when set, gives the additional data used to encrypt the value of a row from its identifier
+// (in decimal for numeric identifiers)
+type ReencryptionTable struct {
+	Table          string
+	IDColumn       string
+	NumericID      bool
+	ValueColumn    string
+	KidColumn      string
+	AdditionalData func(id string) []byte
+	BatchSize      int
+}
+
+// ReencryptionProgress reports the progress of a re-encryption. Rows which can't be decrypted are counted as failed and left
+// unchanged. Rows whose kid changed meanwhile are counted as skipped
+type ReencryptionProgress struct {
+	Processed   int
+	Reencrypted int
+	Failed      int
+	Skipped     int
+}
+
+func (t ReencryptionTable) validate() error {
+	for _, identifier := range []string{t.Table, t.IDColumn, t.ValueColumn, t.KidColumn} {
+		if !sqlIdentifierRegexp.MatchString(identifier) {
+			return errors.New(errorsMsg.MsgErrInvalidParam + ".reencryptionTable")
+		}
+	}
+	return nil
+}
+
+// ReencryptTable re-encrypts with the current key of the encrypter all the values of the table encrypted with another key (see
+// Rekey). Rows are processed by batches, in the order of their identifier, each batch being updated in its own transaction.
+// Rows without kid are processed too: only values whose ciphertext identifies its key can be decrypted, other ones are counted
+// as failed. A row is only updated if its kid did not change meanwhile. The progress callback, if any, is called after each batch
+func ReencryptTable(ctx context.Context, db sqltypes.CloudtrustDB, encrypter EncrypterDecrypter, table ReencryptionTable, logger log.Logger,
+	progress func(ReencryptionProgress)) (ReencryptionProgress, error) {
+	var res ReencryptionProgress
+	if err := table.validate(); err != nil {
+		return res, err
+	}
+	var batchSize = table.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultReencryptionBatchSize
+	}
+	var selectFirstStmt = fmt.Sprintf("SELECT %s, %s, %s FROM %s WHERE (%s <> ? OR %s IS NULL) ORDER BY %s LIMIT ?;",
+		table.IDColumn, table.ValueColumn, table.KidColumn, table.Table, table.KidColumn, table.KidColumn, table.IDColumn)
+	var selectNextStmt = fmt.Sprintf("SELECT %s, %s, %s FROM %s WHERE (%s <> ? OR %s IS NULL) AND %s > ? ORDER BY %s LIMIT ?;",
+		table.IDColumn, table.ValueColumn, table.KidColumn, table.Table, table.KidColumn, table.KidColumn, table.IDColumn, table.IDColumn)
+	var updateStmt = fmt.Sprintf("UPDATE %s SET %s = ?, %s = ? WHERE %s = ? AND %s <=> ?;",
+		table.Table, table.ValueColumn, table.KidColumn, table.IDColumn, table.KidColumn)
+
+	var currentKid = encrypter.GetCurrentKeyID()
+	// The first batch has no lower bound on the identifier, the next ones start after the last identifier of the previous batch
+	var lastID any
+	for {
+		var rows []reencryptionRow
+		var err error
+		if lastID == nil {
+			rows, err = readReencryptionBatch(db, table.NumericID, selectFirstStmt, currentKid, batchSize)
+		} else {
+			rows, err = readReencryptionBatch(db, table.NumericID, selectNextStmt, currentKid, lastID, batchSize)
+		}
+		if err != nil {
+			logger.Warn(ctx, "msg", "Can't read values to re-encrypt", "table", table.Table, "err", err.Error())
+			return res, err
+		}
+		if len(rows) == 0 {
+			return res, nil
+		}
+		if err = reencryptBatch(ctx, db, encrypter, table, updateStmt, rows, logger, &res); err != nil {
+			return res, err
+		}
+		lastID = rows[len(rows)-1].key
+		if progress != nil {
+			progress(res)
+		}
+		if len(rows) < batchSize {
+			return res, nil
+		}
+	}
+}
+
+type reencryptionRow struct {
+	key   any
+	id    string
+	value []byte
+	kid   sql.NullString
+}
+
+func readReencryptionBatch(db sqltypes.CloudtrustDB, numericID bool, selectStmt string, args ...any) ([]reencryptionRow, error) {
+	var rows, err = db.Query(selectStmt, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var res []reencryptionRow
+	for rows.Next() {
+		var row reencryptionRow
+		if numericID {
+			var id int64
+			if err = rows.Scan(&id, &row.value, &row.kid); err != nil {
+				return nil, err
+			}
+			row.key, row.id = id, strconv.FormatInt(id, 10)
+		} else {
+			if err = rows.Scan(&row.id, &row.value, &row.kid); err != nil {
+				return nil, err
+			}
+			row.key = row.id
+		}
+		res = append(res, row)
+	}
+	return res, rows.Err()
+}
+
+func reencryptBatch(ctx context.Context, db sqltypes.CloudtrustDB, encrypter EncrypterDecrypter, table ReencryptionTable, updateStmt string,
+	rows []reencryptionRow, logger log.Logger, progress *ReencryptionProgress) error {
+	var tx, err = db.BeginTx(ctx, nil)
+	if err != nil {
+		logger.Warn(ctx, "msg", "Can't start transaction", "err", err.Error())
+		return err
+	}
+	defer tx.Close()
+
+	var batchProgress ReencryptionProgress
+	for _, row := range rows {
+		batchProgress.Processed++
+		var additional []byte
+		if table.AdditionalData != nil {
+			additional = table.AdditionalData(row.id)
+		}
+		var value, kid, err = Rekey(encrypter, row.value, row.kid.String, additional)
+		if err != nil {
+			logger.Warn(ctx, "msg", "Can't re-encrypt value", "table", table.Table, "id", row.id, "kid", row.kid.String, "err", err.Error())
+			batchProgress.Failed++
+			continue
+		}
+		var previousKid any
+		if row.kid.Valid {
+			previousKid = row.kid.String
+		}
+		var res sql.Result
+		if res, err = tx.Exec(updateStmt, value, kid, row.key, previousKid); err != nil {
+			logger.Warn(ctx, "msg", "Can't update re-encrypted value", "table", table.Table, "id", row.id, "err", err.Error())
+			return err
+		}
+		var count int64
+		if count, err = res.RowsAffected(); err != nil {
+			logger.Warn(ctx, "msg", "Can't get count of re-encrypted values", "table", table.Table, "id", row.id, "err", err.Error())
+			return err
+		}
+		if count == 0 {
+			logger.Info(ctx, "msg", "Value changed while being re-encrypted", "table", table.Table, "id", row.id)
+			batchProgress.Skipped++
+			continue
+		}
+		batchProgress.Reencrypted++
+	}
+	if err = tx.Commit(); err != nil {
+		logger.Warn(ctx, "msg", "Can't commit transaction", "err", err.Error())
+		return err
+	}
+
+	progress.Processed += batchProgress.Processed
+	progress.Reencrypted += batchProgress.Reencrypted
+	progress.Failed += batchProgress.Failed
+	progress.Skipped += batchProgress.Skipped
+	return nil
+}
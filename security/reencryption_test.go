@@ -0,0 +1,148 @@
+package security
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"testing"
+
+	"github.com/cloudtrust/common-service/v2/log"
+	"github.com/cloudtrust/common-service/v2/security/mock"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+func TestReencryptTable(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockDB = mock.NewCloudtrustDB(mockCtrl)
+	var mockTx = mock.NewTransaction(mockCtrl)
+
+	var oldKeys, _ = NewAesGcmEncrypterFromBase64(testOldKeys, 16)
+	var keys, _ = NewAesGcmEncrypterFromBase64(testKeys, 16)
+	var table = ReencryptionTable{
+		Table:       "user_details",
+		IDColumn:    "user_id",
+		ValueColumn: "details",
+		KidColumn:   "key_id",
+		AdditionalData: func(id string) []byte {
+			return []byte(id)
+		},
+		BatchSize: 2,
+	}
+	var selectFirstStmt = "SELECT user_id, details, key_id FROM user_details WHERE (key_id <> ? OR key_id IS NULL) ORDER BY user_id LIMIT ?;"
+	var selectNextStmt = "SELECT user_id, details, key_id FROM user_details WHERE (key_id <> ? OR key_id IS NULL) AND user_id > ? ORDER BY user_id LIMIT ?;"
+	var updateStmt = "UPDATE user_details SET details = ?, key_id = ? WHERE user_id = ? AND key_id <=> ?;"
+	var ctx = context.TODO()
+
+	var encrypt = func(id string) []byte {
+		var encrypted, _ = oldKeys.Encrypt([]byte("details of "+id), []byte(id))
+		return encrypted
+	}
+	var mockRows = func(values ...[]any) *mock.SQLRows {
+		var rows = mock.NewSQLRows(mockCtrl)
+		for _, value := range values {
+			rows.EXPECT().Next().Return(true)
+			rows.EXPECT().Scan(gomock.Any()).DoAndReturn(func(dest ...any) error {
+				switch id := value[0].(type) {
+				case string:
+					*(dest[0].(*string)) = id
+				case int64:
+					*(dest[0].(*int64)) = id
+				}
+				*(dest[1].(*[]byte)) = value[1].([]byte)
+				if kid, ok := value[2].(string); ok {
+					*(dest[2].(*sql.NullString)) = sql.NullString{String: kid, Valid: true}
+				}
+				return nil
+			})
+		}
+		rows.EXPECT().Next().Return(false)
+		rows.EXPECT().Err().Return(nil)
+		rows.EXPECT().Close()
+		return rows
+	}
+
+	t.Run("Invalid table", func(t *testing.T) {
+		var _, err = ReencryptTable(ctx, mockDB, keys, ReencryptionTable{Table: "user;drop", IDColumn: "id", ValueColumn: "v", KidColumn: "k"}, log.NewNopLogger(), nil)
+		assert.NotNil(t, err)
+	})
+	t.Run("Query fails", func(t *testing.T) {
+		var queryErr = errors.New("query error")
+		mockDB.EXPECT().Query(selectFirstStmt, "DBB_2", 2).Return(nil, queryErr)
+		var _, err = ReencryptTable(ctx, mockDB, keys, table, log.NewNopLogger(), nil)
+		assert.Equal(t, queryErr, err)
+	})
+	t.Run("Update fails", func(t *testing.T) {
+		var updateErr = errors.New("update error")
+		mockDB.EXPECT().Query(selectFirstStmt, "DBB_2", 2).Return(mockRows([]any{"id-1", encrypt("id-1"), "DBB_1"}), nil)
+		mockDB.EXPECT().BeginTx(ctx, nil).Return(mockTx, nil)
+		mockTx.EXPECT().Exec(updateStmt, gomock.Any(), "DBB_2", "id-1", "DBB_1").Return(nil, updateErr)
+		mockTx.EXPECT().Close()
+		var _, err = ReencryptTable(ctx, mockDB, keys, table, log.NewNopLogger(), nil)
+		assert.Equal(t, updateErr, err)
+	})
+	t.Run("Success", func(t *testing.T) {
+		var updated = map[string][]byte{}
+		var reports []ReencryptionProgress
+
+		mockDB.EXPECT().Query(selectFirstStmt, "DBB_2", 2).Return(mockRows(
+			[]any{"id-1", encrypt("id-1"), "DBB_1"},
+			[]any{"id-2", []byte("not a valid value"), "DBB_1"},
+		), nil)
+		mockDB.EXPECT().Query(selectNextStmt, "DBB_2", "id-2", 2).Return(mockRows(
+			[]any{"id-3", encrypt("id-3"), "DBB_1"},
+		), nil)
+		mockDB.EXPECT().BeginTx(ctx, nil).Return(mockTx, nil).Times(2)
+		mockTx.EXPECT().Exec(updateStmt, gomock.Any(), "DBB_2", gomock.Any(), "DBB_1").DoAndReturn(func(_ string, args ...any) (sql.Result, error) {
+			updated[args[2].(string)] = args[0].([]byte)
+			return driver.RowsAffected(1), nil
+		}).Times(2)
+		mockTx.EXPECT().Commit().Return(nil).Times(2)
+		mockTx.EXPECT().Close().Times(2)
+
+		var progress, err = ReencryptTable(ctx, mockDB, keys, table, log.NewNopLogger(), func(progress ReencryptionProgress) {
+			reports = append(reports, progress)
+		})
+		assert.Nil(t, err)
+		assert.Equal(t, ReencryptionProgress{Processed: 3, Reencrypted: 2, Failed: 1}, progress)
+		assert.Equal(t, []ReencryptionProgress{{Processed: 2, Reencrypted: 1, Failed: 1}, progress}, reports)
+
+		for _, id := range []string{"id-1", "id-3"} {
+			var value, err = keys.Decrypt(updated[id], "DBB_2", []byte(id))
+			assert.Nil(t, err)
+			assert.Equal(t, "details of "+id, string(value))
+		}
+	})
+	t.Run("Numeric identifiers and values without kid", func(t *testing.T) {
+		var numericTable = table
+		numericTable.IDColumn = "id"
+		numericTable.NumericID = true
+		var selectFirstStmt = "SELECT id, details, key_id FROM user_details WHERE (key_id <> ? OR key_id IS NULL) ORDER BY id LIMIT ?;"
+		var selectNextStmt = "SELECT id, details, key_id FROM user_details WHERE (key_id <> ? OR key_id IS NULL) AND id > ? ORDER BY id LIMIT ?;"
+		var updateStmt = "UPDATE user_details SET details = ?, key_id = ? WHERE id = ? AND key_id <=> ?;"
+		// Versioned ciphertexts identify their key
+		var versionedOldKeys, _ = NewVersionedEncrypterFromBase64(testOldKeys, 16)
+		var versionedKeys, _ = NewVersionedEncrypterFromBase64(testKeys, 16)
+		var withoutKid, _ = versionedOldKeys.Encrypt([]byte("details of -5"), []byte("-5"))
+
+		mockDB.EXPECT().Query(selectFirstStmt, "DBB_2", 2).Return(mockRows(
+			[]any{int64(-5), withoutKid, nil},
+			[]any{int64(9), []byte("not a valid value"), nil},
+		), nil)
+		mockDB.EXPECT().Query(selectNextStmt, "DBB_2", int64(9), 2).Return(mockRows(
+			[]any{int64(10), encrypt("10"), "DBB_1"},
+		), nil)
+		mockDB.EXPECT().BeginTx(ctx, nil).Return(mockTx, nil).Times(2)
+		mockTx.EXPECT().Exec(updateStmt, gomock.Any(), "DBB_2", int64(-5), nil).Return(driver.RowsAffected(1), nil)
+		// The kid of the row changed meanwhile
+		mockTx.EXPECT().Exec(updateStmt, gomock.Any(), "DBB_2", int64(10), "DBB_1").Return(driver.RowsAffected(0), nil)
+		mockTx.EXPECT().Commit().Return(nil).Times(2)
+		mockTx.EXPECT().Close().Times(2)
+
+		var progress, err = ReencryptTable(ctx, mockDB, versionedKeys, numericTable, log.NewNopLogger(), nil)
+		assert.Nil(t, err)
+		assert.Equal(t, ReencryptionProgress{Processed: 3, Reencrypted: 1, Failed: 1, Skipped: 1}, progress)
+	})
+}
@@ -3,7 +3,6 @@ package security
 import (
 	"context"
 	"encoding/json"
-	"slices"
 	"strings"
 
 	cs "github.com/cloudtrust/common-service/v2"
@@ -31,16 +30,34 @@ type roleBasedAuthorizationManager struct {
 	authorizationDBReader RoleBasedAuthorizationDBReader
 	keycloakClient        RoleBasedKeycloakClient
 	logger                log.Logger
+	rolesRegistry         *ActionRolesRegistry
 }
 
+// RoleBasedAuthorizationManagerOption configures optional behaviors of a RoleBasedAuthorizationManager
+type RoleBasedAuthorizationManagerOption func(*roleBasedAuthorizationManager)
+
+// WithActionRolesRegistry replaces the default bindings of the actions to their allowed roles (see DefaultActionRolesRegistry)
+func WithActionRolesRegistry(registry *ActionRolesRegistry) RoleBasedAuthorizationManagerOption {
+	return func(rbam *roleBasedAuthorizationManager) {
+		rbam.rolesRegistry = registry
+	}
+}
+
+// NewRoleBasedAuthorizationManager creates a RoleBasedAuthorizationManager. Roles allowed to perform the actions are given by
+// DefaultActionRolesRegistry unless another registry is provided with WithActionRolesRegistry
 func NewRoleBasedAuthorizationManager(authorizationDBReader RoleBasedAuthorizationDBReader, keycloakClient RoleBasedKeycloakClient,
-	logger log.Logger) RoleBasedAuthorizationManager {
+	logger log.Logger, options ...RoleBasedAuthorizationManagerOption) RoleBasedAuthorizationManager {
 
-	return &roleBasedAuthorizationManager{
+	var manager = &roleBasedAuthorizationManager{
 		authorizationDBReader: authorizationDBReader,
 		keycloakClient:        keycloakClient,
 		logger:                logger,
+		rolesRegistry:         DefaultActionRolesRegistry(),
+	}
+	for _, option := range options {
+		option(manager)
 	}
+	return manager
 }
 
 // CheckRoleAuthorizationOnTargetUser checks if the target user has the required role to init identification
@@ -53,7 +70,7 @@ func (rbam *roleBasedAuthorizationManager) CheckRoleAuthorizationOnTargetUser(ct
 		return suggestForbiddenError(err)
 	}
 
-	allowedRoles := getAllowedRolesForAction(action, adminConfig)
+	allowedRoles, combination := rbam.rolesRegistry.allowedRoles(action, RoleCheckTargetUser, adminConfig)
 	if len(allowedRoles) == 0 {
 		return nil
 	}
@@ -64,10 +81,8 @@ func (rbam *roleBasedAuthorizationManager) CheckRoleAuthorizationOnTargetUser(ct
 		return suggestForbiddenError(err)
 	}
 
-	for _, userRole := range userRoles {
-		if slices.Contains(allowedRoles, userRole) {
-			return nil
-		}
+	if hasAllowedRoles(userRoles, allowedRoles, combination) {
+		return nil
 	}
 
 	infos, _ := json.Marshal(map[string]string{
@@ -95,15 +110,13 @@ func (rbam *roleBasedAuthorizationManager) CheckRoleAuthorizationOnSelfUser(ctx
 		return suggestForbiddenError(err)
 	}
 
-	allowedRoles := getAllowedRolesForAction(action, adminConfig)
+	allowedRoles, combination := rbam.rolesRegistry.allowedRoles(action, RoleCheckSelfUser, adminConfig)
 	if len(allowedRoles) == 0 {
 		return nil
 	}
 
-	for _, userRole := range currentRoles {
-		if slices.Contains(allowedRoles, userRole) {
-			return nil
-		}
+	if hasAllowedRoles(currentRoles, allowedRoles, combination) {
+		return nil
 	}
 
 	infos, _ := json.Marshal(map[string]string{
@@ -115,21 +128,3 @@ func (rbam *roleBasedAuthorizationManager) CheckRoleAuthorizationOnSelfUser(ctx
 	rbam.logger.Info(ctx, "msg", "ForbiddenError: Not allowed to init identification", "infos", string(infos))
 	return ForbiddenError{}
 }
-
-func getAllowedRolesForAction(action string, adminConfig configuration.RealmAdminConfiguration) []string {
-	allowedRoles := []string{}
-	switch action {
-	case IDNVideoIdentInit.String():
-		allowedRoles = adminConfig.VideoIdentificationAllowedRoles
-	case IDNAuxiliaryVideoIdentInit.String():
-		allowedRoles = adminConfig.AuxiliaryVideoIdentificationAllowedRoles
-	case IDNAutoIdentInit.String():
-		allowedRoles = adminConfig.AutoIdentificationAllowedRoles
-	case KYCGetUser.String(), KYCValidateUser.String():
-		allowedRoles = adminConfig.PhysicalIdentificationAllowedRoles
-	case KYCGetUserAuxiliary.String(), KYCValidateUserAuxiliary.String():
-		allowedRoles = adminConfig.AuxiliaryPhysicalIdentificationAllowedRoles
-	}
-
-	return allowedRoles
-}
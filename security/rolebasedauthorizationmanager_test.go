@@ -155,7 +155,8 @@ func TestCheckRoleAuthorizationOnSelfUser(t *testing.T) {
 	})
 }
 
-func TestGetAllowedRolesForAction(t *testing.T) {
+func TestDefaultActionRolesRegistry(t *testing.T) {
+	registry := DefaultActionRolesRegistry()
 	adminConfig := configuration.RealmAdminConfiguration{
 		VideoIdentificationAllowedRoles:             []string{"end_user_video", "video_user"},
 		AuxiliaryVideoIdentificationAllowedRoles:    []string{"end_user_aux"},
@@ -164,18 +165,18 @@ func TestGetAllowedRolesForAction(t *testing.T) {
 		AuxiliaryPhysicalIdentificationAllowedRoles: []string{"end_user_aux_physical"},
 	}
 
-	videoCheck := getAllowedRolesForAction(IDNVideoIdentInit.String(), adminConfig)
+	videoCheck, _ := registry.allowedRoles(IDNVideoIdentInit.String(), RoleCheckTargetUser, adminConfig)
 	assert.Equal(t, videoCheck, []string{"end_user_video", "video_user"})
 
-	auxiliaryVideoCheck := getAllowedRolesForAction(IDNAuxiliaryVideoIdentInit.String(), adminConfig)
+	auxiliaryVideoCheck, _ := registry.allowedRoles(IDNAuxiliaryVideoIdentInit.String(), RoleCheckTargetUser, adminConfig)
 	assert.Equal(t, auxiliaryVideoCheck, []string{"end_user_aux"})
 
-	autoCheck := getAllowedRolesForAction(IDNAutoIdentInit.String(), adminConfig)
+	autoCheck, _ := registry.allowedRoles(IDNAutoIdentInit.String(), RoleCheckTargetUser, adminConfig)
 	assert.Equal(t, autoCheck, []string{"end_user_auto"})
 
-	check := getAllowedRolesForAction(KYCGetUser.String(), adminConfig)
-	assert.Equal(t, check, []string{})
+	check, _ := registry.allowedRoles(KYCGetUser.String(), RoleCheckTargetUser, adminConfig)
+	assert.Len(t, check, 0)
 
-	auxiliaryPhysicalCheck := getAllowedRolesForAction(KYCGetUserAuxiliary.String(), adminConfig)
+	auxiliaryPhysicalCheck, _ := registry.allowedRoles(KYCGetUserAuxiliary.String(), RoleCheckTargetUser, adminConfig)
 	assert.Equal(t, auxiliaryPhysicalCheck, []string{"end_user_aux_physical"})
 }
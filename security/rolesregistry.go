@@ -0,0 +1,142 @@
+package security
+
+import (
+	"slices"
+
+	"github.com/cloudtrust/common-service/v2/configuration"
+)
+
+// RolesSource gives roles allowed to perform an action according to the admin configuration of the target realm
+type RolesSource func(adminConfig configuration.RealmAdminConfiguration) []string
+
+// RolesCombination tells how the roles of a user are matched against the allowed roles
+type RolesCombination int
+
+// RolesCombination values
+const (
+	// RolesAny requires the user to have one of the allowed roles
+	RolesAny RolesCombination = iota
+	// RolesAll requires the user to have all the allowed roles
+	RolesAll
+)
+
+// RoleCheck tells on which checks an action roles binding applies
+type RoleCheck int
+
+// RoleCheck values
+const (
+	RoleCheckTargetUser RoleCheck = 1 << iota
+	RoleCheckSelfUser
+	RoleCheckAll = RoleCheckTargetUser | RoleCheckSelfUser
+)
+
+// ActionRoles binds an action to the roles allowed to perform it. Roles of all the sources are merged. When no role is
+// allowed, i.e. nothing is configured in the admin configuration, the action is not restricted.
+// Checks default to RoleCheckAll
+type ActionRoles struct {
+	Sources     []RolesSource
+	Combination RolesCombination
+	Checks      RoleCheck
+}
+
+// ActionRolesRegistry holds the roles bindings of the actions. Actions without binding are not restricted
+type ActionRolesRegistry struct {
+	bindings map[string]ActionRoles
+}
+
+// NewActionRolesRegistry creates an empty ActionRolesRegistry
+func NewActionRolesRegistry() *ActionRolesRegistry {
+	return &ActionRolesRegistry{bindings: map[string]ActionRoles{}}
+}
+
+// DefaultActionRolesRegistry creates an ActionRolesRegistry with the bindings of the identification actions to the allowed roles
+// fields of the admin configuration. It is the registry used by default by the RoleBasedAuthorizationManager
+func DefaultActionRolesRegistry() *ActionRolesRegistry {
+	var videoIdentification = AdminConfigurationRoles(func(adminConfig configuration.RealmAdminConfiguration) []string {
+		return adminConfig.VideoIdentificationAllowedRoles
+	})
+	var auxiliaryVideoIdentification = AdminConfigurationRoles(func(adminConfig configuration.RealmAdminConfiguration) []string {
+		return adminConfig.AuxiliaryVideoIdentificationAllowedRoles
+	})
+	var autoIdentification = AdminConfigurationRoles(func(adminConfig configuration.RealmAdminConfiguration) []string {
+		return adminConfig.AutoIdentificationAllowedRoles
+	})
+	var physicalIdentification = AdminConfigurationRoles(func(adminConfig configuration.RealmAdminConfiguration) []string {
+		return adminConfig.PhysicalIdentificationAllowedRoles
+	})
+	var auxiliaryPhysicalIdentification = AdminConfigurationRoles(func(adminConfig configuration.RealmAdminConfiguration) []string {
+		return adminConfig.AuxiliaryPhysicalIdentificationAllowedRoles
+	})
+
+	return NewActionRolesRegistry().
+		Bind(ActionRoles{Sources: []RolesSource{videoIdentification}}, IDNVideoIdentInit).
+		Bind(ActionRoles{Sources: []RolesSource{auxiliaryVideoIdentification}}, IDNAuxiliaryVideoIdentInit).
+		Bind(ActionRoles{Sources: []RolesSource{autoIdentification}}, IDNAutoIdentInit).
+		Bind(ActionRoles{Sources: []RolesSource{physicalIdentification}}, KYCGetUser, KYCValidateUser).
+		Bind(ActionRoles{Sources: []RolesSource{auxiliaryPhysicalIdentification}}, KYCGetUserAuxiliary, KYCValidateUserAuxiliary)
+}
+
+// Bind binds the actions to the roles, replacing their previous binding
+func (r *ActionRolesRegistry) Bind(binding ActionRoles, actions ...Action) *ActionRolesRegistry {
+	if binding.Checks == 0 {
+		binding.Checks = RoleCheckAll
+	}
+	for _, action := range actions {
+		r.bindings[action.Name] = binding
+	}
+	return r
+}
+
+// AdminConfigurationRoles returns a roles source reading a field of the admin configuration
+func AdminConfigurationRoles(field func(adminConfig configuration.RealmAdminConfiguration) []string) RolesSource {
+	return field
+}
+
+// AllowedRolesEntry returns a roles source reading an entry of the generic allowed roles map of the admin configuration
+func AllowedRolesEntry(key string) RolesSource {
+	return func(adminConfig configuration.RealmAdminConfiguration) []string {
+		return adminConfig.AllowedRoles[key]
+	}
+}
+
+// StaticRoles returns a roles source giving always the same roles
+func StaticRoles(roles ...string) RolesSource {
+	return func(configuration.RealmAdminConfiguration) []string {
+		return roles
+	}
+}
+
+// allowedRoles returns the roles allowed to perform the action for the given check, and how they must be combined
+func (r *ActionRolesRegistry) allowedRoles(action string, check RoleCheck, adminConfig configuration.RealmAdminConfiguration) ([]string, RolesCombination) {
+	var binding, ok = r.bindings[action]
+	if !ok || binding.Checks&check == 0 {
+		return nil, RolesAny
+	}
+	var res []string
+	for _, source := range binding.Sources {
+		for _, role := range source(adminConfig) {
+			if !slices.Contains(res, role) {
+				res = append(res, role)
+			}
+		}
+	}
+	return res, binding.Combination
+}
+
+// hasAllowedRoles tells whether the roles of a user match the allowed roles
+func hasAllowedRoles(userRoles []string, allowedRoles []string, combination RolesCombination) bool {
+	if combination == RolesAll {
+		for _, role := range allowedRoles {
+			if !slices.Contains(userRoles, role) {
+				return false
+			}
+		}
+		return true
+	}
+	for _, userRole := range userRoles {
+		if slices.Contains(allowedRoles, userRole) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,96 @@
+package security
+
+import (
+	"context"
+	"testing"
+
+	cs "github.com/cloudtrust/common-service/v2"
+	"github.com/cloudtrust/common-service/v2/configuration"
+	"github.com/cloudtrust/common-service/v2/log"
+	"github.com/cloudtrust/common-service/v2/security/mock"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+func TestActionRolesRegistry(t *testing.T) {
+	var adminConfig = configuration.RealmAdminConfiguration{
+		VideoIdentificationAllowedRoles: []string{"video", "shared"},
+		AllowedRoles:                    map[string][]string{"new_identification": {"new", "shared"}},
+	}
+	var newIdentification = Action{Name: "IDN_NewIdentInit", Scope: ScopeGroup}
+	var registry = NewActionRolesRegistry().
+		Bind(ActionRoles{Sources: []RolesSource{AllowedRolesEntry("new_identification"), StaticRoles("support")}}, newIdentification).
+		Bind(ActionRoles{
+			Sources: []RolesSource{AdminConfigurationRoles(func(adminConfig configuration.RealmAdminConfiguration) []string {
+				return adminConfig.VideoIdentificationAllowedRoles
+			})},
+			Combination: RolesAll,
+			Checks:      RoleCheckSelfUser,
+		}, IDNVideoIdentInit)
+
+	t.Run("Merged sources", func(t *testing.T) {
+		var roles, combination = registry.allowedRoles(newIdentification.Name, RoleCheckTargetUser, adminConfig)
+		assert.Equal(t, []string{"new", "shared", "support"}, roles)
+		assert.Equal(t, RolesAny, combination)
+		roles, _ = registry.allowedRoles(newIdentification.Name, RoleCheckSelfUser, adminConfig)
+		assert.Len(t, roles, 3)
+	})
+	t.Run("Binding restricted to self checks", func(t *testing.T) {
+		var roles, combination = registry.allowedRoles(IDNVideoIdentInit.Name, RoleCheckSelfUser, adminConfig)
+		assert.Equal(t, []string{"video", "shared"}, roles)
+		assert.Equal(t, RolesAll, combination)
+		roles, _ = registry.allowedRoles(IDNVideoIdentInit.Name, RoleCheckTargetUser, adminConfig)
+		assert.Len(t, roles, 0)
+	})
+	t.Run("Unbound action", func(t *testing.T) {
+		var roles, _ = registry.allowedRoles(KYCGetUser.Name, RoleCheckTargetUser, adminConfig)
+		assert.Len(t, roles, 0)
+	})
+	t.Run("Combinations", func(t *testing.T) {
+		assert.True(t, hasAllowedRoles([]string{"a", "c"}, []string{"a", "b"}, RolesAny))
+		assert.False(t, hasAllowedRoles([]string{"c"}, []string{"a", "b"}, RolesAny))
+		assert.True(t, hasAllowedRoles([]string{"a", "b", "c"}, []string{"a", "b"}, RolesAll))
+		assert.False(t, hasAllowedRoles([]string{"a", "c"}, []string{"a", "b"}, RolesAll))
+	})
+}
+
+func TestRoleBasedAuthorizationManagerWithRegistry(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockKeycloakClient := mock.NewRoleBasedKeycloakClient(mockCtrl)
+	mockAuthorizationDBReader := mock.NewRoleBasedAuthorizationDBReader(mockCtrl)
+
+	accessToken := "TOKEN=="
+	realm := "realm"
+	targetUserID := "user-id-123"
+	action := Action{Name: "IDN_NewIdentInit", Scope: ScopeGroup}
+	registry := NewActionRolesRegistry().Bind(ActionRoles{Sources: []RolesSource{AllowedRolesEntry("new")}, Combination: RolesAll}, action)
+	adminConfig := configuration.RealmAdminConfiguration{AllowedRoles: map[string][]string{"new": {"officer", "trained"}}}
+	ctx := context.WithValue(context.Background(), cs.CtContextAccessToken, accessToken)
+	ctx = context.WithValue(ctx, cs.CtContextRealm, realm)
+
+	authorizationManager := NewRoleBasedAuthorizationManager(mockAuthorizationDBReader, mockKeycloakClient, log.NewNopLogger(),
+		WithActionRolesRegistry(registry))
+
+	t.Run("Target user with all the roles", func(t *testing.T) {
+		mockAuthorizationDBReader.EXPECT().GetAdminConfiguration(ctx, realm).Return(adminConfig, nil)
+		mockKeycloakClient.EXPECT().GetRoleNamesOfUser(ctx, accessToken, realm, targetUserID).Return([]string{"trained", "officer"}, nil)
+		assert.Nil(t, authorizationManager.CheckRoleAuthorizationOnTargetUser(ctx, action.Name, realm, targetUserID))
+	})
+	t.Run("Target user missing a role", func(t *testing.T) {
+		mockAuthorizationDBReader.EXPECT().GetAdminConfiguration(ctx, realm).Return(adminConfig, nil)
+		mockKeycloakClient.EXPECT().GetRoleNamesOfUser(ctx, accessToken, realm, targetUserID).Return([]string{"officer"}, nil)
+		assert.Equal(t, ForbiddenError{}, authorizationManager.CheckRoleAuthorizationOnTargetUser(ctx, action.Name, realm, targetUserID))
+	})
+	t.Run("Self user", func(t *testing.T) {
+		var selfCtx = context.WithValue(ctx, cs.CtContextRoles, []string{"officer", "trained"})
+		mockAuthorizationDBReader.EXPECT().GetAdminConfiguration(selfCtx, realm).Return(adminConfig, nil)
+		assert.Nil(t, authorizationManager.CheckRoleAuthorizationOnSelfUser(selfCtx, action.Name))
+	})
+	t.Run("Default bindings are replaced", func(t *testing.T) {
+		mockAuthorizationDBReader.EXPECT().GetAdminConfiguration(ctx, realm).Return(configuration.RealmAdminConfiguration{
+			PhysicalIdentificationAllowedRoles: []string{"kyc_officer"},
+		}, nil)
+		assert.Nil(t, authorizationManager.CheckRoleAuthorizationOnSelfUser(ctx, KYCGetUser.Name))
+	})
+}
@@ -0,0 +1,266 @@
+package security
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"io"
+	"slices"
+
+	errorsMsg "github.com/cloudtrust/common-service/v2/errors"
+)
+
+// DefaultStreamChunkSize is the default size of the plain text chunks of encrypted streams
+const DefaultStreamChunkSize = 64 * 1024
+
+const (
+	maxStreamChunkSize    = 16 * 1024 * 1024
+	streamKeySize         = 32
+	streamChunkSizeLen    = 4
+	streamNoncePrefixSize = 7
+	streamLastChunk       = 1
+)
+
+var errTruncatedStream = errors.New(errorsMsg.MsgErrTruncated + "." + errorsMsg.Ciphertext)
+
+// Streams are encrypted with the STREAM construction: the plain text is split in chunks which are each encrypted with AES-GCM
+// using a random stream key. The nonce of a chunk is made of a random prefix, the chunk counter and a flag set on the last
+// chunk, which lets the decryption detect reordered, dropped or truncated chunks.
+// The stream key is encrypted by the EncrypterDecrypter: encrypted streams are decrypted with the kid of the encrypter, as
+// values encrypted with Encrypt. Encrypted streams start with a header made of the length of the encrypted stream key
+// (2 bytes, big endian), the encrypted stream key, the chunk size (4 bytes, big endian) and the nonce prefix. Each chunk is
+// authenticated with the header and the additional data
+
+type streamCipher struct {
+	aead        cipher.AEAD
+	chunkHeader []byte
+	additional  []byte
+	counter     uint32
+	done        bool
+}
+
+func newStreamCipher(streamKey []byte, chunkHeader []byte, additional []byte) (*streamCipher, error) {
+	var block, err = aes.NewCipher(streamKey)
+	if err != nil {
+		return nil, err
+	}
+	var aead cipher.AEAD
+	if aead, err = cipher.NewGCM(block); err != nil {
+		return nil, err
+	}
+	return &streamCipher{aead: aead, chunkHeader: chunkHeader, additional: slices.Concat(chunkHeader, additional)}, nil
+}
+
+func (s *streamCipher) nextNonce(last bool) ([]byte, error) {
+	if s.done {
+		// Nothing can follow the last chunk and the counter can't wrap
+		return nil, errors.New(errorsMsg.MsgErrInvalidLength + "." + errorsMsg.Ciphertext)
+	}
+	var nonce = s.nonce(s.counter, last)
+	s.counter++
+	s.done = last || s.counter == 0
+	return nonce, nil
+}
+
+func (s *streamCipher) nonce(counter uint32, last bool) []byte {
+	var nonce = make([]byte, s.aead.NonceSize())
+	copy(nonce, s.chunkHeader[streamChunkSizeLen:])
+	binary.BigEndian.PutUint32(nonce[streamNoncePrefixSize:], counter)
+	if last {
+		nonce[len(nonce)-1] = streamLastChunk
+	}
+	return nonce
+}
+
+type encryptingWriter struct {
+	dst       io.Writer
+	cipher    *streamCipher
+	chunkSize int
+	buffer    []byte
+	closed    bool
+}
+
+// NewEncryptingWriter returns a writer encrypting the data written to it in chunks of chunkSize bytes (DefaultStreamChunkSize if
+// chunkSize is 0) and writing the encrypted stream to dst. The encrypted stream can be decrypted with NewDecryptingReader using
+// the current key id of the encrypter. Close must be called to write the last chunk: it does not close dst
+func NewEncryptingWriter(encrypter EncrypterDecrypter, dst io.Writer, chunkSize int, additional []byte) (io.WriteCloser, error) {
+	if chunkSize == 0 {
+		chunkSize = DefaultStreamChunkSize
+	}
+	if chunkSize < 0 || chunkSize > maxStreamChunkSize {
+		return nil, errors.New(errorsMsg.MsgErrInvalidParam + ".chunkSize")
+	}
+
+	var streamKey = make([]byte, streamKeySize)
+	_, _ = rand.Read(streamKey)
+	var encryptedKey, err = encrypter.Encrypt(streamKey, additional)
+	if err != nil {
+		return nil, err
+	}
+	if len(encryptedKey) > 0xFFFF {
+		return nil, errors.New(errorsMsg.MsgErrInvalidLength + "." + errorsMsg.EncryptDecrypt)
+	}
+
+	var chunkHeader = make([]byte, streamChunkSizeLen+streamNoncePrefixSize)
+	binary.BigEndian.PutUint32(chunkHeader, uint32(chunkSize))
+	_, _ = rand.Read(chunkHeader[streamChunkSizeLen:])
+
+	var streamCipher *streamCipher
+	if streamCipher, err = newStreamCipher(streamKey, chunkHeader, additional); err != nil {
+		return nil, err
+	}
+
+	var header = binary.BigEndian.AppendUint16(nil, uint16(len(encryptedKey)))
+	if _, err = dst.Write(slices.Concat(header, encryptedKey, chunkHeader)); err != nil {
+		return nil, err
+	}
+	return &encryptingWriter{
+		dst:       dst,
+		cipher:    streamCipher,
+		chunkSize: chunkSize,
+		buffer:    make([]byte, 0, chunkSize),
+	}, nil
+}
+
+func (w *encryptingWriter) Write(p []byte) (int, error) {
+	if w.closed {
+		return 0, io.ErrClosedPipe
+	}
+	var written = 0
+	for len(p) > 0 {
+		// A full chunk is only flushed once more data comes as the last chunk must be flagged
+		if len(w.buffer) == w.chunkSize {
+			if err := w.flush(false); err != nil {
+				return written, err
+			}
+		}
+		var n = min(len(p), w.chunkSize-len(w.buffer))
+		w.buffer = append(w.buffer, p[:n]...)
+		p = p[n:]
+		written += n
+	}
+	return written, nil
+}
+
+func (w *encryptingWriter) Close() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	return w.flush(true)
+}
+
+func (w *encryptingWriter) flush(last bool) error {
+	var nonce, err = w.cipher.nextNonce(last)
+	if err != nil {
+		return err
+	}
+	if _, err = w.dst.Write(w.cipher.aead.Seal(nil, nonce, w.buffer, w.cipher.additional)); err != nil {
+		return err
+	}
+	w.buffer = w.buffer[:0]
+	return nil
+}
+
+type decryptingReader struct {
+	src    io.Reader
+	cipher *streamCipher
+	// encrypted holds the next encrypted chunk followed by the first byte of the following one, if any
+	encrypted []byte
+	pending   int
+	plain     []byte
+	err       error
+}
+
+// NewDecryptingReader returns a reader decrypting a stream encrypted with NewEncryptingWriter. kid is the id of the key which
+// was the current one of the encrypter when the stream was encrypted. Chunks are only returned once authenticated: the reader
+// fails if the stream was altered or truncated
+func NewDecryptingReader(decrypter EncrypterDecrypter, src io.Reader, kid string, additional []byte) (io.Reader, error) {
+	var keyLength = make([]byte, 2)
+	if _, err := io.ReadFull(src, keyLength); err != nil {
+		return nil, errTruncatedStream
+	}
+	var encryptedKey = make([]byte, int(binary.BigEndian.Uint16(keyLength)))
+	var chunkHeader = make([]byte, streamChunkSizeLen+streamNoncePrefixSize)
+	if _, err := io.ReadFull(src, encryptedKey); err != nil {
+		return nil, errTruncatedStream
+	}
+	if _, err := io.ReadFull(src, chunkHeader); err != nil {
+		return nil, errTruncatedStream
+	}
+	var chunkSize = int(binary.BigEndian.Uint32(chunkHeader))
+	if chunkSize <= 0 || chunkSize > maxStreamChunkSize {
+		return nil, errors.New(errorsMsg.MsgErrInvalidLength + "." + errorsMsg.Ciphertext)
+	}
+
+	var streamKey, err = decrypter.Decrypt(encryptedKey, kid, additional)
+	if err != nil {
+		return nil, err
+	}
+	var streamCipher *streamCipher
+	if streamCipher, err = newStreamCipher(streamKey, chunkHeader, additional); err != nil {
+		return nil, err
+	}
+	return &decryptingReader{
+		src:       src,
+		cipher:    streamCipher,
+		encrypted: make([]byte, chunkSize+streamCipher.aead.Overhead()+1),
+	}, nil
+}
+
+func (r *decryptingReader) Read(p []byte) (int, error) {
+	for len(r.plain) == 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+		r.err = r.nextChunk()
+	}
+	var n = copy(p, r.plain)
+	r.plain = r.plain[n:]
+	return n, nil
+}
+
+// nextChunk decrypts the next chunk. It returns io.EOF once the last chunk is decrypted
+func (r *decryptingReader) nextChunk() error {
+	var n, err = io.ReadFull(r.src, r.encrypted[r.pending:])
+	n += r.pending
+	var last = false
+	switch err {
+	case nil:
+	case io.EOF, io.ErrUnexpectedEOF:
+		last = true
+	default:
+		return err
+	}
+	if last && n == 0 {
+		return errTruncatedStream
+	}
+
+	var chunk = r.encrypted[:n]
+	if !last {
+		chunk = r.encrypted[:n-1]
+	}
+	var nonce []byte
+	if nonce, err = r.cipher.nextNonce(last); err != nil {
+		return err
+	}
+	if r.plain, err = r.cipher.aead.Open(nil, nonce, chunk, r.cipher.additional); err != nil {
+		// The stream was truncated if its final chunk is valid but not flagged as the last one
+		if last {
+			var notLast = r.cipher.nonce(r.cipher.counter-1, false)
+			if _, openErr := r.cipher.aead.Open(nil, notLast, chunk, r.cipher.additional); openErr == nil {
+				return errTruncatedStream
+			}
+		}
+		return err
+	}
+	if last {
+		return io.EOF
+	}
+	// Keep the first byte of the following chunk
+	r.encrypted[0] = r.encrypted[n-1]
+	r.pending = 1
+	return nil
+}
@@ -0,0 +1,116 @@
+package security
+
+import (
+	"bytes"
+	"crypto/rand"
+	"io"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func encryptStream(t *testing.T, encrypter EncrypterDecrypter, value []byte, chunkSize int, additional []byte) []byte {
+	var encrypted bytes.Buffer
+	var writer, err = NewEncryptingWriter(encrypter, &encrypted, chunkSize, additional)
+	assert.Nil(t, err)
+	// Write in small parts which don't match the chunks
+	for part := range slices.Chunk(value, 7) {
+		_, err = writer.Write(part)
+		assert.Nil(t, err)
+	}
+	assert.Nil(t, writer.Close())
+	return encrypted.Bytes()
+}
+
+func decryptStream(decrypter EncrypterDecrypter, encrypted []byte, kid string, additional []byte) ([]byte, error) {
+	var reader, err = NewDecryptingReader(decrypter, bytes.NewReader(encrypted), kid, additional)
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(reader)
+}
+
+func TestStreamEncryption(t *testing.T) {
+	var additional = []byte("additional")
+	var value = make([]byte, 100)
+	_, _ = rand.Read(value)
+
+	var encrypter, _ = NewAesGcmEncrypterFromBase64(testKeys, 16)
+	var versioned, _ = NewVersionedEncrypterFromBase64(testVersionedKeys, 16)
+	var envelope, _ = NewEnvelopeEncrypter(encrypter, 16)
+
+	t.Run("Invalid chunk size", func(t *testing.T) {
+		var _, err = NewEncryptingWriter(encrypter, io.Discard, -1, nil)
+		assert.NotNil(t, err)
+		_, err = NewEncryptingWriter(encrypter, io.Discard, maxStreamChunkSize+1, nil)
+		assert.NotNil(t, err)
+	})
+	for name, encrypter := range map[string]EncrypterDecrypter{"AES-GCM": encrypter, "Versioned": versioned, "Envelope": envelope} {
+		t.Run("Encrypt/decrypt cycle with "+name, func(t *testing.T) {
+			for _, chunkSize := range []int{0, 10, 25, 100, 101} {
+				var encrypted = encryptStream(t, encrypter, value, chunkSize, additional)
+				var res, err = decryptStream(encrypter, encrypted, encrypter.GetCurrentKeyID(), additional)
+				assert.Nil(t, err)
+				assert.Equal(t, value, res)
+			}
+		})
+	}
+	t.Run("Empty stream", func(t *testing.T) {
+		var encrypted = encryptStream(t, encrypter, nil, 10, additional)
+		var res, err = decryptStream(encrypter, encrypted, "DBB_2", additional)
+		assert.Nil(t, err)
+		assert.Len(t, res, 0)
+	})
+	t.Run("Write after close", func(t *testing.T) {
+		var writer, _ = NewEncryptingWriter(encrypter, io.Discard, 10, nil)
+		assert.Nil(t, writer.Close())
+		var _, err = writer.Write([]byte("value"))
+		assert.NotNil(t, err)
+	})
+
+	// 4 chunks of 25 bytes, the header has a 2 bytes length, the 60 bytes encrypted stream key and 11 bytes of chunk size and nonce prefix
+	var encrypted = encryptStream(t, encrypter, value, 25, additional)
+	var headerLength = 2 + 12 + 32 + 16 + 4 + 7
+	var chunkLength = 25 + 16
+	assert.Len(t, encrypted, headerLength+4*chunkLength)
+
+	t.Run("Invalid key or additional data", func(t *testing.T) {
+		var _, err = decryptStream(encrypter, encrypted, "DBB_1", additional)
+		assert.NotNil(t, err)
+		_, err = decryptStream(encrypter, encrypted, "DBB_3", additional)
+		assert.NotNil(t, err)
+		_, err = decryptStream(encrypter, encrypted, "DBB_2", []byte("other"))
+		assert.NotNil(t, err)
+	})
+	t.Run("Truncated stream", func(t *testing.T) {
+		for _, length := range []int{1, headerLength - 1, headerLength, headerLength + chunkLength, headerLength + 3*chunkLength, len(encrypted) - 1} {
+			var _, err = decryptStream(encrypter, encrypted[:length], "DBB_2", additional)
+			assert.NotNil(t, err)
+		}
+		var _, err = decryptStream(encrypter, encrypted[:headerLength+2*chunkLength], "DBB_2", additional)
+		assert.Equal(t, errTruncatedStream, err)
+	})
+	t.Run("Altered stream", func(t *testing.T) {
+		var altered = bytes.Clone(encrypted)
+		altered[headerLength+chunkLength] ^= 1
+		var _, err = decryptStream(encrypter, altered, "DBB_2", additional)
+		assert.NotNil(t, err)
+
+		// Reordered chunks
+		altered = slices.Concat(encrypted[:headerLength], encrypted[headerLength+chunkLength:headerLength+2*chunkLength],
+			encrypted[headerLength:headerLength+chunkLength], encrypted[headerLength+2*chunkLength:])
+		_, err = decryptStream(encrypter, altered, "DBB_2", additional)
+		assert.NotNil(t, err)
+
+		// Altered chunk size
+		altered = bytes.Clone(encrypted)
+		altered[headerLength-8] = 1
+		_, err = decryptStream(encrypter, altered, "DBB_2", additional)
+		assert.NotNil(t, err)
+
+		// Data after the last chunk
+		_, err = decryptStream(encrypter, append(bytes.Clone(encrypted), 0), "DBB_2", additional)
+		assert.NotNil(t, err)
+	})
+}